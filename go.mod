@@ -3,9 +3,12 @@ module github.com/amecky/fin-math
 go 1.24.3
 
 require (
-	github.com/alecthomas/assert/v2 v2.11.0 // indirect
+	github.com/alecthomas/assert/v2 v2.11.0
+	github.com/chobie/go-gaussian v0.0.0-20150107165016-53c09d90eeaf
+)
+
+require (
 	github.com/alecthomas/repr v0.4.0 // indirect
-	github.com/chobie/go-gaussian v0.0.0-20150107165016-53c09d90eeaf // indirect
 	github.com/hexops/gotextdiff v1.0.3 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
 )
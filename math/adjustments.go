@@ -0,0 +1,213 @@
+package math
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Split and dividend adjustment
+//
+// -----------------------------------------------------------------------
+type CorporateActionType int
+
+const (
+	SPLIT CorporateActionType = iota + 1
+	DIVIDEND
+)
+
+// CorporateAction is a split or a cash dividend. Key is the ex-date (only the
+// date part is needed). Ratio is the number of new shares per old share
+// (2 for a 2:1 split, 0.1 for a 1:10 reverse split). Amount is the cash
+// dividend per share in the currency of the prices.
+type CorporateAction struct {
+	Key    string
+	Type   CorporateActionType
+	Ratio  float64
+	Amount float64
+}
+
+type AdjustmentMode int
+
+const (
+	// prices before an action are changed, the most recent prices stay as they are
+	ADJUST_BACKWARD AdjustmentMode = iota
+	// prices after an action are changed, the oldest prices stay as they are
+	ADJUST_FORWARD
+)
+
+type AdjustmentConfig struct {
+	Mode      AdjustmentMode
+	Splits    bool
+	Dividends bool
+	// KeepRaw adds RawOpen, RawHigh, RawLow, RawClose and RawVolume
+	KeepRaw bool
+}
+
+func DefaultAdjustmentConfig() AdjustmentConfig {
+	return AdjustmentConfig{
+		Mode:      ADJUST_BACKWARD,
+		Splits:    true,
+		Dividends: true,
+	}
+}
+
+func rawClose(r *MatrixRow) float64 {
+	if r.Get(CLOSE) != 0.0 {
+		return r.Get(CLOSE)
+	}
+	return r.Get(ADJ_CLOSE)
+}
+
+type actionFactor struct {
+	key    string
+	price  float64
+	volume float64
+}
+
+// actionFactors converts the actions into multipliers for all prices before the ex-date
+func actionFactors(candles *Matrix, actions []CorporateAction, cfg AdjustmentConfig) []actionFactor {
+	ret := make([]actionFactor, 0)
+	for _, a := range actions {
+		af := actionFactor{
+			key:    a.Key,
+			price:  1.0,
+			volume: 1.0,
+		}
+		switch a.Type {
+		case SPLIT:
+			if !cfg.Splits || a.Ratio <= 0.0 {
+				continue
+			}
+			af.price = 1.0 / a.Ratio
+			af.volume = a.Ratio
+		case DIVIDEND:
+			if !cfg.Dividends {
+				continue
+			}
+			// the close of the last bar before the ex-date
+			prev := -1
+			for i := 0; i < candles.Rows; i++ {
+				if candles.DataRows[i].Key < a.Key {
+					prev = i
+				}
+			}
+			if prev == -1 {
+				continue
+			}
+			pc := rawClose(&candles.DataRows[prev])
+			if pc <= a.Amount {
+				continue
+			}
+			af.price = 1.0 - a.Amount/pc
+		}
+		ret = append(ret, af)
+	}
+	return ret
+}
+
+// AdjustCandles rewrites OPEN, HIGH, LOW, CLOSE, ADJ_CLOSE and VOLUME so that
+// splits and dividends do not show up as price gaps. The raw prices are read
+// from OPEN, HIGH, LOW and CLOSE (ADJ_CLOSE if CLOSE is empty). After the
+// adjustment CLOSE and ADJ_CLOSE are identical.
+// Returns the index of RawOpen if KeepRaw is set otherwise -1.
+func AdjustCandles(candles *Matrix, actions []CorporateAction, cfg AdjustmentConfig) int {
+	// 0 = RawOpen 1 = RawHigh 2 = RawLow 3 = RawClose 4 = RawVolume
+	ret := -1
+	if cfg.KeepRaw {
+		ret = candles.AddNamedColumn("RawOpen")
+		candles.AddNamedColumn("RawHigh")
+		candles.AddNamedColumn("RawLow")
+		candles.AddNamedColumn("RawClose")
+		candles.AddNamedColumn("RawVolume")
+		for i := 0; i < candles.Rows; i++ {
+			c := &candles.DataRows[i]
+			c.Set(ret, c.Get(OPEN))
+			c.Set(ret+1, c.Get(HIGH))
+			c.Set(ret+2, c.Get(LOW))
+			c.Set(ret+3, rawClose(c))
+			c.Set(ret+4, c.Get(VOLUME))
+		}
+	}
+	factors := actionFactors(candles, actions, cfg)
+	sort.Slice(factors, func(i, j int) bool {
+		return factors[i].key < factors[j].key
+	})
+	for i := 0; i < candles.Rows; i++ {
+		c := &candles.DataRows[i]
+		pf := 1.0
+		vf := 1.0
+		for _, f := range factors {
+			before := c.Key < f.key
+			if cfg.Mode == ADJUST_BACKWARD && before {
+				pf *= f.price
+				vf *= f.volume
+			}
+			if cfg.Mode == ADJUST_FORWARD && !before {
+				pf /= f.price
+				vf /= f.volume
+			}
+		}
+		cl := rawClose(c)
+		c.Set(OPEN, c.Get(OPEN)*pf)
+		c.Set(HIGH, c.Get(HIGH)*pf)
+		c.Set(LOW, c.Get(LOW)*pf)
+		c.Set(CLOSE, cl*pf)
+		c.Set(ADJ_CLOSE, cl*pf)
+		c.Set(VOLUME, c.Get(VOLUME)*vf)
+	}
+	return ret
+}
+
+type yahooEvents struct {
+	Chart struct {
+		Result []struct {
+			Events struct {
+				Dividends map[string]struct {
+					Amount float64 `json:"amount"`
+					Date   int64   `json:"date"`
+				} `json:"dividends"`
+				Splits map[string]struct {
+					Date        int64   `json:"date"`
+					Numerator   float64 `json:"numerator"`
+					Denominator float64 `json:"denominator"`
+				} `json:"splits"`
+			} `json:"events"`
+		} `json:"result"`
+	} `json:"chart"`
+}
+
+// ParseYahooEvents extracts the splits and dividends of a Yahoo chart response
+// requested with events=div,split
+func ParseYahooEvents(data []byte) ([]CorporateAction, error) {
+	var ye yahooEvents
+	if err := json.Unmarshal(data, &ye); err != nil {
+		return nil, err
+	}
+	ret := make([]CorporateAction, 0)
+	for _, r := range ye.Chart.Result {
+		for _, d := range r.Events.Dividends {
+			ret = append(ret, CorporateAction{
+				Key:    time.Unix(d.Date, 0).UTC().Format("2006-01-02"),
+				Type:   DIVIDEND,
+				Amount: d.Amount,
+			})
+		}
+		for _, s := range r.Events.Splits {
+			if s.Denominator == 0.0 {
+				continue
+			}
+			ret = append(ret, CorporateAction{
+				Key:   time.Unix(s.Date, 0).UTC().Format("2006-01-02"),
+				Type:  SPLIT,
+				Ratio: s.Numerator / s.Denominator,
+			})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+	return ret, nil
+}
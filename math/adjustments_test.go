@@ -0,0 +1,47 @@
+package math
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildAdjustmentCandles() *Matrix {
+	mat := NewCandleMatrix()
+	mat.AddRow("2024-01-02 00:00").Set(OPEN, 98).Set(HIGH, 102).Set(LOW, 96).Set(CLOSE, 100).Set(VOLUME, 1000)
+	mat.AddRow("2024-01-03 00:00").Set(OPEN, 50).Set(HIGH, 52).Set(LOW, 48).Set(CLOSE, 50).Set(VOLUME, 2000)
+	mat.AddRow("2024-01-04 00:00").Set(OPEN, 49).Set(HIGH, 50).Set(LOW, 48).Set(CLOSE, 49).Set(VOLUME, 2000)
+	return mat
+}
+
+var testActions = []CorporateAction{
+	{Key: "2024-01-03", Type: SPLIT, Ratio: 2.0},
+	{Key: "2024-01-04", Type: DIVIDEND, Amount: 1.0},
+}
+
+func TestAdjustBackward(t *testing.T) {
+	cfg := DefaultAdjustmentConfig()
+	cfg.KeepRaw = true
+	mat := buildAdjustmentCandles()
+	raw := AdjustCandles(mat, testActions, cfg)
+	// split 0.5 and dividend 1 - 1/50 = 0.98
+	assert.Equal(t, "49.00", fmt.Sprintf("%.2f", mat.DataRows[0].Close()))
+	assert.Equal(t, "49.98", fmt.Sprintf("%.2f", mat.DataRows[0].High()))
+	assert.Equal(t, 2000.0, mat.DataRows[0].Get(VOLUME))
+	assert.Equal(t, "49.00", fmt.Sprintf("%.2f", mat.DataRows[1].Close()))
+	assert.Equal(t, 49.0, mat.DataRows[2].Close())
+	assert.Equal(t, 100.0, mat.DataRows[0].Get(raw+3))
+	assert.Equal(t, 1000.0, mat.DataRows[0].Get(raw+4))
+}
+
+func TestAdjustForward(t *testing.T) {
+	cfg := DefaultAdjustmentConfig()
+	cfg.Mode = ADJUST_FORWARD
+	cfg.Dividends = false
+	mat := buildAdjustmentCandles()
+	assert.Equal(t, -1, AdjustCandles(mat, testActions, cfg))
+	assert.Equal(t, 100.0, mat.DataRows[0].Close())
+	assert.Equal(t, 100.0, mat.DataRows[1].Close())
+	assert.Equal(t, 1000.0, mat.DataRows[1].Get(VOLUME))
+}
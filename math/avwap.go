@@ -0,0 +1,114 @@
+package math
+
+import (
+	"fmt"
+	"math"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Anchored VWAP
+//
+// -----------------------------------------------------------------------
+// accumulateVWAP fills the VWAP and the volume weighted standard deviation
+// bands from start up to (not including) end. Columns below 0 are skipped.
+func accumulateVWAP(m *Matrix, start, end, ret, upper, lower int, std float64) {
+	sumV := 0.0
+	sumTP := 0.0
+	sumTP2 := 0.0
+	for i := start; i < end; i++ {
+		p := &m.DataRows[i]
+		tp := (p.Get(HIGH) + p.Get(LOW) + p.Get(ADJ_CLOSE)) / 3.0
+		v := p.Get(VOLUME)
+		sumV += v
+		sumTP += tp * v
+		sumTP2 += tp * tp * v
+		if sumV == 0.0 {
+			continue
+		}
+		vw := sumTP / sumV
+		sd := math.Sqrt(math.Max(sumTP2/sumV-vw*vw, 0.0))
+		p.Set(ret, vw)
+		if upper >= 0 {
+			p.Set(upper, vw+std*sd)
+		}
+		if lower >= 0 {
+			p.Set(lower, vw-std*sd)
+		}
+	}
+}
+
+// AnchoredVWAP starts the VWAP at the anchor row. Rows before the anchor are 0.
+func AnchoredVWAP(m *Matrix, anchor int, std float64) int {
+	// 0 = AVWAP 1 = Upper 2 = Lower
+	ret := m.AddNamedColumn("AVWAP")
+	upper := m.AddNamedColumn("Upper")
+	lower := m.AddNamedColumn("Lower")
+	if anchor >= 0 {
+		accumulateVWAP(m, anchor, m.CandleRows(), ret, upper, lower, std)
+	}
+	return ret
+}
+
+// AnchoredVWAPByDate anchors at the first row with a key equal or after the date
+func AnchoredVWAPByDate(m *Matrix, key string, std float64) (int, error) {
+	for i := 0; i < m.CandleRows(); i++ {
+		if m.DataRows[i].Key >= key {
+			return AnchoredVWAP(m, i, std), nil
+		}
+	}
+	return -1, fmt.Errorf("no row at or after %s", key)
+}
+
+// AnchoredVWAPBySwingPoint anchors at the swing point
+func AnchoredVWAPBySwingPoint(m *Matrix, sp SwingPoint, std float64) int {
+	return AnchoredVWAP(m, sp.Index, std)
+}
+
+// AnchoredVWAPByOrderBlock anchors at the candle of the order block
+func AnchoredVWAPByOrderBlock(m *Matrix, ob OrderBlock, std float64) int {
+	return AnchoredVWAP(m, ob.Index, std)
+}
+
+// FindGapAnchor returns the most recent row where the absolute value of the
+// GAP column is at least minGap or -1
+func FindGapAnchor(m *Matrix, gapIdx int, minGap float64) int {
+	for i := m.CandleRows() - 1; i > 0; i-- {
+		if math.Abs(m.DataRows[i].Get(gapIdx)) >= minGap {
+			return i
+		}
+	}
+	return -1
+}
+
+type AnchoredVWAPLine struct {
+	Anchor SwingPoint
+	Column int
+}
+
+// MultiAnchoredVWAP adds one AVWAP column (without bands) for each of the
+// last count swing highs and the last count swing lows of FindSwingPoints
+func MultiAnchoredVWAP(m *Matrix, count int) []AnchoredVWAPLine {
+	ret := make([]AnchoredVWAPLine, 0)
+	sps := m.FindSwingPoints()
+	for _, bt := range []SwingPointType{High, Low} {
+		filtered := sps.FilterByType(bt)
+		start := len(filtered) - count
+		if start < 0 {
+			start = 0
+		}
+		for _, sp := range filtered[start:] {
+			name := "AVWAP-H " + sp.Timestamp
+			if bt == Low {
+				name = "AVWAP-L " + sp.Timestamp
+			}
+			col := m.AddNamedColumn(name)
+			accumulateVWAP(m, sp.Index, m.CandleRows(), col, -1, -1, 0.0)
+			ret = append(ret, AnchoredVWAPLine{
+				Anchor: sp,
+				Column: col,
+			})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,46 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildAVWAPCandles() *Matrix {
+	mat := NewCandleMatrix()
+	add := func(key string, h, l, c, v float64) {
+		mat.AddRow(key).Set(OPEN, c).Set(HIGH, h).Set(LOW, l).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, v)
+	}
+	// typical prices 9, 11, 15 and 16
+	add("2024-01-02 00:00", 10, 8, 9, 100)
+	add("2024-01-03 00:00", 12, 10, 11, 100)
+	add("2024-01-04 00:00", 16, 14, 15, 100)
+	add("2024-01-05 00:00", 17, 15, 16, 200)
+	return mat
+}
+
+func TestAnchoredVWAP(t *testing.T) {
+	mat := buildAVWAPCandles()
+	vw := AnchoredVWAP(mat, 1, 1.0)
+	assert.Equal(t, 0.0, mat.DataRows[0].Get(vw))
+	assert.Equal(t, 11.0, mat.DataRows[1].Get(vw))
+	assert.Equal(t, 11.0, mat.DataRows[1].Get(vw+1))
+	assert.Equal(t, 11.0, mat.DataRows[1].Get(vw+2))
+	// (11 + 15) / 2 with a standard deviation of 2
+	assert.Equal(t, 13.0, mat.DataRows[2].Get(vw))
+	assert.Equal(t, 15.0, mat.DataRows[2].Get(vw+1))
+	assert.Equal(t, 11.0, mat.DataRows[2].Get(vw+2))
+	// (11 * 100 + 15 * 100 + 16 * 200) / 400
+	assert.Equal(t, 14.5, mat.DataRows[3].Get(vw))
+}
+
+func TestAnchoredVWAPByDate(t *testing.T) {
+	mat := buildAVWAPCandles()
+	vw, err := AnchoredVWAPByDate(mat, "2024-01-04", 2.0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, mat.DataRows[1].Get(vw))
+	assert.Equal(t, 15.0, mat.DataRows[2].Get(vw))
+	assert.Equal(t, (15.0*100.0+16.0*200.0)/300.0, mat.DataRows[3].Get(vw))
+	_, err = AnchoredVWAPByDate(mat, "2024-02-01", 2.0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,241 @@
+package math
+
+import (
+	m "math"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Bar builder
+//
+// -----------------------------------------------------------------------
+// Builds candles out of a stream of trades. Bars can be sampled by time or
+// by activity (number of trades, volume, dollar value or order flow imbalance).
+//
+// The resulting matrix contains the default OPEN ... VOLUME columns followed
+// by BAR_DELTA (buy volume - sell volume) and BAR_TRADES (number of trades).
+// Time bars are keyed by their start in KEY_FORMAT. Activity bars are keyed by
+// their first trade in KEY_FORMAT_MILLIS since several of them can start in
+// the same minute.
+const (
+	BAR_DELTA  int = 6
+	BAR_TRADES int = 7
+)
+
+const KEY_FORMAT_MILLIS = "2006-01-02 15:04:05.000"
+
+type BarType int
+
+const (
+	TIME_BARS BarType = iota
+	TICK_BARS
+	VOLUME_BARS
+	DOLLAR_BARS
+	TICK_IMBALANCE_BARS
+	VOLUME_IMBALANCE_BARS
+)
+
+// Trade is a single execution. Side is 1 for buyer initiated, -1 for seller
+// initiated and 0 if unknown. Unknown sides are classified by the tick rule.
+type Trade struct {
+	Time  time.Time
+	Price float64
+	Size  float64
+	Side  int
+}
+
+type BarBuilder struct {
+	Type      BarType
+	Interval  time.Duration
+	Threshold float64
+	// Location of the exchange. Time bars are aligned to its wall clock and
+	// the keys are written in it. nil uses the location of the trades.
+	Location  *time.Location
+	bars      *Matrix
+	cur       *MatrixRow
+	start     time.Time
+	progress  float64
+	lastPrice float64
+	lastSide  int
+}
+
+// NewTimeBarBuilder samples one bar per interval. Intervals without trades create no bar.
+func NewTimeBarBuilder(interval time.Duration) *BarBuilder {
+	return &BarBuilder{
+		Type:     TIME_BARS,
+		Interval: interval,
+		bars:     NewBarMatrix(),
+	}
+}
+
+// NewBarBuilder samples a new bar every time the threshold is reached. The
+// threshold is the number of trades (TICK_BARS), the traded size (VOLUME_BARS),
+// price * size (DOLLAR_BARS) or the absolute sum of signed trades
+// (TICK_IMBALANCE_BARS) or signed size (VOLUME_IMBALANCE_BARS).
+func NewBarBuilder(barType BarType, threshold float64) *BarBuilder {
+	return &BarBuilder{
+		Type:      barType,
+		Threshold: threshold,
+		bars:      NewBarMatrix(),
+	}
+}
+
+// NewBarMatrix returns an empty matrix with the columns produced by the BarBuilder
+func NewBarMatrix() *Matrix {
+	return NewMatrixWithHeaders(8, append(append([]string{}, CANDLE_HEADERS...), "Delta", "Trades"))
+}
+
+func (bb *BarBuilder) side(t Trade) int {
+	side := t.Side
+	if side == 0 {
+		if t.Price > bb.lastPrice {
+			side = 1
+		} else if t.Price < bb.lastPrice {
+			side = -1
+		} else {
+			side = bb.lastSide
+		}
+	}
+	if side == 0 {
+		side = 1
+	}
+	bb.lastPrice = t.Price
+	bb.lastSide = side
+	return side
+}
+
+// truncateIn rounds down to a multiple of the interval on the wall clock of
+// the location (time.Truncate works on UTC)
+func truncateIn(t time.Time, interval time.Duration, loc *time.Location) time.Time {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(interval).Add(-shift)
+}
+
+func (bb *BarBuilder) open(t Trade) {
+	var key string
+	if bb.Type == TIME_BARS {
+		bb.start = truncateIn(t.Time, bb.Interval, bb.Location)
+		key = bb.start.Format(KEY_FORMAT)
+	} else {
+		key = t.Time.Format(KEY_FORMAT_MILLIS)
+	}
+	bb.cur = bb.bars.ForcedAddRow(key)
+	bb.cur.Set(OPEN, t.Price).Set(HIGH, t.Price).Set(LOW, t.Price)
+	bb.progress = 0.0
+}
+
+// Add processes the next trade. Trades must be in chronological order.
+func (bb *BarBuilder) Add(t Trade) {
+	side := bb.side(t)
+	if bb.cur != nil && bb.Type == TIME_BARS && !t.Time.Before(bb.start.Add(bb.Interval)) {
+		bb.cur = nil
+	}
+	if bb.cur == nil {
+		bb.open(t)
+	}
+	c := bb.cur
+	c.Set(HIGH, m.Max(c.Get(HIGH), t.Price))
+	c.Set(LOW, m.Min(c.Get(LOW), t.Price))
+	c.Set(CLOSE, t.Price)
+	c.Set(ADJ_CLOSE, t.Price)
+	c.Set(VOLUME, c.Get(VOLUME)+t.Size)
+	c.Set(BAR_DELTA, c.Get(BAR_DELTA)+float64(side)*t.Size)
+	c.Set(BAR_TRADES, c.Get(BAR_TRADES)+1.0)
+	switch bb.Type {
+	case TICK_BARS:
+		bb.progress += 1.0
+	case VOLUME_BARS:
+		bb.progress += t.Size
+	case DOLLAR_BARS:
+		bb.progress += t.Size * t.Price
+	case TICK_IMBALANCE_BARS:
+		bb.progress += float64(side)
+	case VOLUME_IMBALANCE_BARS:
+		bb.progress += float64(side) * t.Size
+	}
+	if bb.Type != TIME_BARS && m.Abs(bb.progress) >= bb.Threshold {
+		bb.cur = nil
+	}
+}
+
+// AddAll processes all trades in the given order
+func (bb *BarBuilder) AddAll(trades []Trade) {
+	for _, t := range trades {
+		bb.Add(t)
+	}
+}
+
+// IsOpen returns true if the last bar has not reached its threshold or end time yet
+func (bb *BarBuilder) IsOpen() bool {
+	return bb.cur != nil
+}
+
+// Bars returns all bars including the currently open one
+func (bb *BarBuilder) Bars() *Matrix {
+	return bb.bars
+}
+
+// ClosedBars returns a copy of the bars that are complete
+func (bb *BarBuilder) ClosedBars() *Matrix {
+	if bb.cur == nil {
+		return bb.bars
+	}
+	ret := NewBarMatrix()
+	for _, r := range bb.bars.DataRows[:bb.bars.Rows-1] {
+		copy(ret.ForcedAddRow(r.Key).Values, r.Values)
+	}
+	return ret
+}
+
+// BuildBars is a shortcut for building activity based bars from a list of trades
+func BuildBars(trades []Trade, barType BarType, threshold float64) *Matrix {
+	bb := NewBarBuilder(barType, threshold)
+	bb.AddAll(trades)
+	return bb.Bars()
+}
+
+// BuildTimeBars is a shortcut for building time based bars from a list of trades
+func BuildTimeBars(trades []Trade, interval time.Duration) *Matrix {
+	bb := NewTimeBarBuilder(interval)
+	bb.AddAll(trades)
+	return bb.Bars()
+}
+
+// -----------------------------------------------------------------------
+//
+//	TradeDeltaVolume
+//
+// -----------------------------------------------------------------------
+// Same output as DeltaVolume but based on the real signed delta column
+// (e.g. BAR_DELTA) instead of the estimation from the candle shape
+func TradeDeltaVolume(m *Matrix, delta int) int {
+	// 0 = Buy Volume Percentage 1 = Sell Volume Percentage
+	buy := m.AddNamedColumn("BuyVolume")
+	sell := m.AddNamedColumn("SellVolume")
+	for i := range m.Rows {
+		c := &m.DataRows[i]
+		if c.Get(VOLUME) != 0.0 {
+			bvp := (c.Get(VOLUME) + c.Get(delta)) / 2.0 / c.Get(VOLUME) * 100.0
+			c.Set(buy, bvp)
+			c.Set(sell, 100-bvp)
+		}
+	}
+	return buy
+}
+
+// CumulativeDelta sums up the signed delta column
+func CumulativeDelta(m *Matrix, delta int) int {
+	// 0 = Cumulative Delta
+	ret := m.AddNamedColumn("CVD")
+	sum := 0.0
+	for i := range m.Rows {
+		sum += m.DataRows[i].Get(delta)
+		m.DataRows[i].Set(ret, sum)
+	}
+	return ret
+}
@@ -0,0 +1,68 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildTrades() []Trade {
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	prices := []float64{10, 11, 12, 11, 10, 9, 10, 11}
+	ret := make([]Trade, len(prices))
+	for i, p := range prices {
+		ret[i] = Trade{
+			Time:  start.Add(time.Duration(i*30) * time.Second),
+			Price: p,
+			Size:  10,
+		}
+	}
+	return ret
+}
+
+func TestTimeBars(t *testing.T) {
+	bars := BuildTimeBars(buildTrades(), time.Minute)
+	assert.Equal(t, 4, bars.Rows)
+	assert.Equal(t, "2024-01-02 09:00", bars.DataRows[0].Key)
+	assert.Equal(t, 10.0, bars.DataRows[0].Get(OPEN))
+	assert.Equal(t, 11.0, bars.DataRows[0].Get(CLOSE))
+	assert.Equal(t, 20.0, bars.DataRows[0].Get(VOLUME))
+	// tick rule: 10 -> 11 is an uptick, 12 -> 11 a downtick
+	assert.Equal(t, 20.0, bars.DataRows[0].Get(BAR_DELTA))
+	assert.Equal(t, 0.0, bars.DataRows[1].Get(BAR_DELTA))
+}
+
+func TestVolumeBars(t *testing.T) {
+	bb := NewBarBuilder(VOLUME_BARS, 30)
+	bb.AddAll(buildTrades())
+	bars := bb.Bars()
+	assert.Equal(t, 3, bars.Rows)
+	assert.Equal(t, 12.0, bars.DataRows[0].Get(HIGH))
+	assert.Equal(t, 3.0, bars.DataRows[0].Get(BAR_TRADES))
+	// the keys of activity bars work with the calendar
+	assert.Equal(t, "2024-01-02 09:01:30.000", bars.DataRows[1].Key)
+	key, err := XETRACalendar().AddBusinessDaysKey(bars.DataRows[1].Key, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-03 09:01", key)
+	assert.True(t, bb.IsOpen())
+	closed := bb.ClosedBars()
+	assert.Equal(t, 2, closed.Rows)
+	assert.Equal(t, 8, closed.Cols)
+	sma := SMA(closed, 2, ADJ_CLOSE)
+	assert.Equal(t, 10.5, closed.DataRows[1].Get(sma))
+	// the bars of the builder are not touched
+	assert.Equal(t, 8, bars.Cols)
+}
+
+func TestTimeBarsLocation(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	bb := NewTimeBarBuilder(time.Hour)
+	bb.Location = loc
+	bb.Add(Trade{Time: time.Date(2024, 1, 2, 9, 45, 0, 0, loc), Price: 10, Size: 1})
+	bb.Add(Trade{Time: time.Date(2024, 1, 2, 10, 15, 0, 0, loc), Price: 11, Size: 1})
+	bars := bb.Bars()
+	assert.Equal(t, 2, bars.Rows)
+	assert.Equal(t, "2024-01-02 09:00", bars.DataRows[0].Key)
+	assert.Equal(t, "2024-01-02 10:00", bars.DataRows[1].Key)
+}
@@ -0,0 +1,503 @@
+package math
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Trading calendar
+//
+// -----------------------------------------------------------------------
+const DATE_FORMAT = "2006-01-02"
+
+// CalendarDay is a holiday or a half day of an exchange
+type CalendarDay struct {
+	Date    string
+	Name    string
+	HalfDay bool
+}
+
+// HolidayRule returns the holidays and half days of a year
+type HolidayRule func(year int) []CalendarDay
+
+type TradingCalendar struct {
+	Name     string
+	Location *time.Location
+	// session hours as "15:04" in the time zone of the exchange
+	Open         string
+	Close        string
+	HalfDayClose string
+	Weekend      []time.Weekday
+	Rules        []HolidayRule
+	mu           sync.Mutex
+	years        map[int]bool
+	days         map[string]CalendarDay
+}
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func NewTradingCalendar(name, location, open, close string, rules ...HolidayRule) *TradingCalendar {
+	return &TradingCalendar{
+		Name:         name,
+		Location:     loadLocation(location),
+		Open:         open,
+		Close:        close,
+		HalfDayClose: close,
+		Weekend:      []time.Weekday{time.Saturday, time.Sunday},
+		Rules:        rules,
+		years:        make(map[int]bool),
+		days:         make(map[string]CalendarDay),
+	}
+}
+
+// WeekdayCalendar only knows weekends
+func WeekdayCalendar() *TradingCalendar {
+	return NewTradingCalendar("Weekdays", "UTC", "00:00", "23:59")
+}
+
+// XETRACalendar - Frankfurt Xetra 09:00 - 17:30
+func XETRACalendar() *TradingCalendar {
+	return NewTradingCalendar("XETRA", "Europe/Berlin", "09:00", "17:30", xetraHolidays)
+}
+
+// NYSECalendar - New York Stock Exchange 09:30 - 16:00 and 13:00 on half days
+func NYSECalendar() *TradingCalendar {
+	cal := NewTradingCalendar("NYSE", "America/New_York", "09:30", "16:00", nyseHolidays)
+	cal.HalfDayClose = "13:00"
+	return cal
+}
+
+// EasterSunday uses the anonymous gregorian algorithm
+func EasterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := ((h + l - 7*m + 114) % 31) + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+func calendarDay(t time.Time, name string) CalendarDay {
+	return CalendarDay{
+		Date: t.Format(DATE_FORMAT),
+		Name: name,
+	}
+}
+
+func fixedDay(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the n-th weekday of the month. n = -1 is the last one.
+func nthWeekday(year int, month time.Month, wd time.Weekday, n int) time.Time {
+	if n < 0 {
+		t := fixedDay(year, month+1, 1).AddDate(0, 0, -1)
+		for t.Weekday() != wd {
+			t = t.AddDate(0, 0, -1)
+		}
+		return t
+	}
+	t := fixedDay(year, month, 1)
+	for t.Weekday() != wd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.AddDate(0, 0, 7*(n-1))
+}
+
+// observed moves a holiday on a saturday to friday and on a sunday to monday
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+func xetraHolidays(year int) []CalendarDay {
+	easter := EasterSunday(year)
+	return []CalendarDay{
+		calendarDay(fixedDay(year, time.January, 1), "New Year"),
+		calendarDay(easter.AddDate(0, 0, -2), "Good Friday"),
+		calendarDay(easter.AddDate(0, 0, 1), "Easter Monday"),
+		calendarDay(fixedDay(year, time.May, 1), "Labour Day"),
+		calendarDay(fixedDay(year, time.December, 24), "Christmas Eve"),
+		calendarDay(fixedDay(year, time.December, 25), "Christmas"),
+		calendarDay(fixedDay(year, time.December, 26), "Boxing Day"),
+		calendarDay(fixedDay(year, time.December, 31), "New Year's Eve"),
+	}
+}
+
+func nyseHolidays(year int) []CalendarDay {
+	ret := make([]CalendarDay, 0)
+	// New Year on a saturday is not moved to the previous year
+	ny := fixedDay(year, time.January, 1)
+	if ny.Weekday() != time.Saturday {
+		ret = append(ret, calendarDay(observed(ny), "New Year"))
+	}
+	ret = append(ret,
+		calendarDay(nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King Day"),
+		calendarDay(nthWeekday(year, time.February, time.Monday, 3), "Presidents Day"),
+		calendarDay(EasterSunday(year).AddDate(0, 0, -2), "Good Friday"),
+		calendarDay(nthWeekday(year, time.May, time.Monday, -1), "Memorial Day"),
+	)
+	if year >= 2022 {
+		ret = append(ret, calendarDay(observed(fixedDay(year, time.June, 19)), "Juneteenth"))
+	}
+	july4 := fixedDay(year, time.July, 4)
+	ret = append(ret,
+		calendarDay(observed(july4), "Independence Day"),
+		calendarDay(nthWeekday(year, time.September, time.Monday, 1), "Labor Day"),
+		calendarDay(nthWeekday(year, time.November, time.Thursday, 4), "Thanksgiving"),
+		calendarDay(observed(fixedDay(year, time.December, 25)), "Christmas"),
+	)
+	// half days
+	if wd := july4.Weekday(); wd >= time.Tuesday && wd <= time.Friday {
+		hd := calendarDay(july4.AddDate(0, 0, -1), "Independence Day Eve")
+		hd.HalfDay = true
+		ret = append(ret, hd)
+	}
+	bf := calendarDay(nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1), "Black Friday")
+	bf.HalfDay = true
+	ret = append(ret, bf)
+	if wd := fixedDay(year, time.December, 24).Weekday(); wd >= time.Monday && wd <= time.Thursday {
+		ce := calendarDay(fixedDay(year, time.December, 24), "Christmas Eve")
+		ce.HalfDay = true
+		ret = append(ret, ce)
+	}
+	return ret
+}
+
+func (tc *TradingCalendar) ensureYear(year int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.years == nil {
+		tc.years = make(map[int]bool)
+		tc.days = make(map[string]CalendarDay)
+	}
+	if tc.years[year] {
+		return
+	}
+	tc.years[year] = true
+	for _, r := range tc.Rules {
+		for _, d := range r(year) {
+			if _, ok := tc.days[d.Date]; !ok {
+				tc.days[d.Date] = d
+			}
+		}
+	}
+}
+
+func (tc *TradingCalendar) lookup(t time.Time) (CalendarDay, bool) {
+	tc.ensureYear(t.Year())
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	d, ok := tc.days[t.Format(DATE_FORMAT)]
+	return d, ok
+}
+
+// AddHoliday adds a single closed day (e.g. a national day of mourning)
+func (tc *TradingCalendar) AddHoliday(date, name string) {
+	tc.addDay(CalendarDay{Date: date, Name: name})
+}
+
+// AddHalfDay adds a single day with shortened trading hours
+func (tc *TradingCalendar) AddHalfDay(date, name string) {
+	tc.addDay(CalendarDay{Date: date, Name: name, HalfDay: true})
+}
+
+func (tc *TradingCalendar) addDay(d CalendarDay) {
+	if t, err := time.Parse(DATE_FORMAT, d.Date); err == nil {
+		tc.ensureYear(t.Year())
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.days[d.Date] = d
+}
+
+// Holidays returns all holidays and half days of a year sorted by date
+func (tc *TradingCalendar) Holidays(year int) []CalendarDay {
+	tc.ensureYear(year)
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	ret := make([]CalendarDay, 0)
+	prefix := fmt.Sprintf("%04d-", year)
+	for k, d := range tc.days {
+		if strings.HasPrefix(k, prefix) {
+			ret = append(ret, d)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Date < ret[j].Date
+	})
+	return ret
+}
+
+func (tc *TradingCalendar) IsWeekend(t time.Time) bool {
+	for _, wd := range tc.Weekend {
+		if t.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (tc *TradingCalendar) IsHoliday(t time.Time) bool {
+	d, ok := tc.lookup(t)
+	return ok && !d.HalfDay
+}
+
+func (tc *TradingCalendar) IsHalfDay(t time.Time) bool {
+	d, ok := tc.lookup(t)
+	return ok && d.HalfDay && !tc.IsWeekend(t)
+}
+
+func (tc *TradingCalendar) IsBusinessDay(t time.Time) bool {
+	return !tc.IsWeekend(t) && !tc.IsHoliday(t)
+}
+
+func (tc *TradingCalendar) sessionTime(t time.Time, hm string) time.Time {
+	h, _ := time.Parse("15:04", hm)
+	loc := tc.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), h.Hour(), h.Minute(), 0, 0, loc)
+}
+
+// Session returns open and close of the trading day. The last value is false
+// if there is no session on that day.
+func (tc *TradingCalendar) Session(t time.Time) (time.Time, time.Time, bool) {
+	if !tc.IsBusinessDay(t) {
+		return time.Time{}, time.Time{}, false
+	}
+	close := tc.Close
+	if tc.IsHalfDay(t) {
+		close = tc.HalfDayClose
+	}
+	return tc.sessionTime(t, tc.Open), tc.sessionTime(t, close), true
+}
+
+// IsOpen checks if the time is inside a session
+func (tc *TradingCalendar) IsOpen(t time.Time) bool {
+	if tc.Location != nil {
+		t = t.In(tc.Location)
+	}
+	open, close, ok := tc.Session(t)
+	return ok && !t.Before(open) && t.Before(close)
+}
+
+// NextBusinessDay returns the next business day after t
+func (tc *TradingCalendar) NextBusinessDay(t time.Time) time.Time {
+	return tc.AddBusinessDays(t, 1)
+}
+
+// AddBusinessDays moves n business days forward (or backward if n < 0)
+func (tc *TradingCalendar) AddBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step = -1
+		n = -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if tc.IsBusinessDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// BusinessDaysBetween counts the business days after first up to and including
+// second. The result is negative if second is before first.
+func (tc *TradingCalendar) BusinessDaysBetween(first, second time.Time) int {
+	sign := 1
+	if second.Before(first) {
+		first, second = second, first
+		sign = -1
+	}
+	first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+	second = time.Date(second.Year(), second.Month(), second.Day(), 0, 0, 0, 0, time.UTC)
+	cnt := 0
+	for t := first.AddDate(0, 0, 1); !t.After(second); t = t.AddDate(0, 0, 1) {
+		if tc.IsBusinessDay(t) {
+			cnt++
+		}
+	}
+	return sign * cnt
+}
+
+// YearFraction is the number of business days divided by 252
+func (tc *TradingCalendar) YearFraction(first, second time.Time) float64 {
+	return float64(tc.BusinessDaysBetween(first, second)) / 252.0
+}
+
+// DaysToExpiration converts the business days until the expiry into calendar
+// days so the result can be used as Option.TimeToExpiration (which is divided by 365)
+func (tc *TradingCalendar) DaysToExpiration(today, expiry time.Time) float64 {
+	return tc.YearFraction(today, expiry) * 365.0
+}
+
+// parseKey parses keys in DATE_FORMAT, KEY_FORMAT and KEY_FORMAT_MILLIS (activity bars)
+func parseKey(key string) (time.Time, error) {
+	switch len(key) {
+	case len(DATE_FORMAT):
+		return time.Parse(DATE_FORMAT, key)
+	case len(KEY_FORMAT_MILLIS):
+		return time.Parse(KEY_FORMAT_MILLIS, key)
+	}
+	return time.Parse(KEY_FORMAT, key)
+}
+
+// AddBusinessDaysKey is AddBusinessDays for matrix keys
+func (tc *TradingCalendar) AddBusinessDaysKey(key string, n int) (string, error) {
+	t, err := parseKey(key)
+	if err != nil {
+		return "", err
+	}
+	return tc.AddBusinessDays(t, n).Format(KEY_FORMAT), nil
+}
+
+// BusinessDaysBetweenKeys is BusinessDaysBetween for matrix keys
+func (tc *TradingCalendar) BusinessDaysBetweenKeys(first, second string) (int, error) {
+	ft, err := parseKey(first)
+	if err != nil {
+		return 0, err
+	}
+	st, err := parseKey(second)
+	if err != nil {
+		return 0, err
+	}
+	return tc.BusinessDaysBetween(ft, st), nil
+}
+
+// periodStep returns the calendar step of weekly (multiples of 7 days) and
+// monthly (28 days or more) intervals. Both are 0 for shorter intervals.
+func periodStep(interval time.Duration) (int, int) {
+	days := int(interval / (24 * time.Hour))
+	if days >= 28 {
+		return 0, (days + 15) / 30
+	}
+	if days >= 7 && days%7 == 0 {
+		return days, 0
+	}
+	return 0, 0
+}
+
+// addMonths keeps the day of the month or uses the last day of shorter months
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// slot returns the k-th bar start after the anchor. Weekly and monthly bars
+// move by calendar weeks or months from the anchor and snap to the next
+// business day. All other intervals move from prev, the slot before.
+func (tc *TradingCalendar) slot(anchor, prev time.Time, k int, interval time.Duration) time.Time {
+	days, months := periodStep(interval)
+	if days == 0 && months == 0 {
+		return tc.nextSlot(prev, interval)
+	}
+	t := addMonths(anchor, months*k).AddDate(0, 0, days*k)
+	if !tc.IsBusinessDay(t) {
+		t = tc.NextBusinessDay(t)
+	}
+	return t
+}
+
+// nextSlot returns the next bar start after t. Daily intervals move the
+// number of days in business days otherwise the next bar within a session.
+func (tc *TradingCalendar) nextSlot(t time.Time, interval time.Duration) time.Time {
+	if interval >= 24*time.Hour {
+		days := int(interval / (24 * time.Hour))
+		return tc.AddBusinessDays(t, days)
+	}
+	loc := tc.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	// keys carry the local time of the exchange without a zone
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	next := local.Add(interval)
+	_, close, ok := tc.Session(local)
+	if ok && next.Before(close) {
+		return next
+	}
+	day := tc.NextBusinessDay(local)
+	open, _, _ := tc.Session(day)
+	return open
+}
+
+// FutureKeys generates the next n keys after the last key
+func (tc *TradingCalendar) FutureKeys(lastKey string, n int, interval time.Duration) ([]string, error) {
+	t, err := parseKey(lastKey)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, n)
+	anchor := t
+	for i := 1; i <= n; i++ {
+		t = tc.slot(anchor, t, i, interval)
+		ret = append(ret, t.Format(KEY_FORMAT))
+	}
+	return ret, nil
+}
+
+type CalendarGap struct {
+	After   string
+	Before  string
+	Missing []string
+}
+
+// FindGaps compares the keys of the matrix with the calendar and returns all
+// bars that should exist but are missing
+func (tc *TradingCalendar) FindGaps(m *Matrix, interval time.Duration) ([]CalendarGap, error) {
+	ret := make([]CalendarGap, 0)
+	for i := 1; i < m.Rows; i++ {
+		prev := m.DataRows[i-1].Key
+		cur := m.DataRows[i].Key
+		t, err := parseKey(prev)
+		if err != nil {
+			return nil, err
+		}
+		gap := CalendarGap{
+			After:  prev,
+			Before: cur,
+		}
+		anchor := t
+		for k := 1; ; k++ {
+			t = tc.slot(anchor, t, k, interval)
+			key := t.Format(KEY_FORMAT)
+			if key >= cur || len(gap.Missing) > 10000 {
+				break
+			}
+			gap.Missing = append(gap.Missing, key)
+		}
+		if len(gap.Missing) > 0 {
+			ret = append(ret, gap)
+		}
+	}
+	return ret, nil
+}
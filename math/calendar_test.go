@@ -0,0 +1,71 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestNYSECalendar(t *testing.T) {
+	cal := NYSECalendar()
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DATE_FORMAT, s)
+		return d
+	}
+	assert.True(t, cal.IsHoliday(day("2024-03-29")))
+	assert.True(t, cal.IsHoliday(day("2024-07-04")))
+	assert.True(t, cal.IsHalfDay(day("2024-07-03")))
+	assert.True(t, cal.IsHoliday(day("2022-12-26")))
+	assert.False(t, cal.IsHoliday(day("2021-12-31")))
+	assert.Equal(t, "2024-07-05", cal.AddBusinessDays(day("2024-07-03"), 1).Format(DATE_FORMAT))
+	assert.Equal(t, 4, cal.BusinessDaysBetween(day("2024-07-01"), day("2024-07-08")))
+	assert.Equal(t, -4, cal.BusinessDaysBetween(day("2024-07-08"), day("2024-07-01")))
+	_, close, ok := cal.Session(day("2024-11-29"))
+	assert.True(t, ok)
+	assert.Equal(t, 13, close.Hour())
+}
+
+func TestCalendarKeys(t *testing.T) {
+	cal := XETRACalendar()
+	keys, err := cal.FutureKeys("2024-12-20 00:00", 3, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-12-23 00:00", "2024-12-27 00:00", "2024-12-30 00:00"}, keys)
+	keys, err = cal.FutureKeys("2024-12-23 17:00", 2, 15*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-12-23 17:15", "2024-12-27 09:00"}, keys)
+
+	mat := NewCandleMatrix()
+	mat.AddRow("2024-12-20 00:00")
+	mat.AddRow("2024-12-30 00:00")
+	gaps, err := cal.FindGaps(mat, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(gaps))
+	assert.Equal(t, []string{"2024-12-23 00:00", "2024-12-27 00:00"}, gaps[0].Missing)
+}
+
+func TestCalendarPeriodKeys(t *testing.T) {
+	cal := XETRACalendar()
+	// weekly bars move by calendar weeks and not by 7 business days
+	keys, err := cal.FutureKeys("2024-12-09 00:00", 3, 7*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-12-16 00:00", "2024-12-23 00:00", "2024-12-30 00:00"}, keys)
+	// the monday after easter is a holiday
+	keys, err = cal.FutureKeys("2025-04-14 00:00", 2, 7*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2025-04-22 00:00", "2025-04-28 00:00"}, keys)
+	// monthly bars snap to the next business day
+	keys, err = cal.FutureKeys("2024-10-01 00:00", 3, 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-11-01 00:00", "2024-12-02 00:00", "2025-01-02 00:00"}, keys)
+	keys, err = cal.FutureKeys("2025-01-31 00:00", 2, 30*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2025-02-28 00:00", "2025-03-31 00:00"}, keys)
+
+	mat := NewCandleMatrix()
+	mat.AddRow("2024-12-02 00:00")
+	mat.AddRow("2024-12-23 00:00")
+	gaps, err := cal.FindGaps(mat, 7*24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-12-09 00:00", "2024-12-16 00:00"}, gaps[0].Missing)
+}
@@ -0,0 +1,513 @@
+package math
+
+import "math"
+
+// -----------------------------------------------------------------------
+//
+//	Candlestick pattern catalogue
+//
+// -----------------------------------------------------------------------
+type CandlePattern int
+
+const (
+	NO_CANDLE_PATTERN CandlePattern = iota
+	// single bar
+	DOJI
+	DRAGONFLY_DOJI
+	GRAVESTONE_DOJI
+	LONG_LEGGED_DOJI
+	HAMMER
+	HANGING_MAN
+	INVERTED_HAMMER
+	SHOOTING_STAR
+	BULLISH_MARUBOZU
+	BEARISH_MARUBOZU
+	SPINNING_TOP
+	HIGH_WAVE
+	BULLISH_BELT_HOLD
+	BEARISH_BELT_HOLD
+	// two bars
+	BULLISH_ENGULFING
+	BEARISH_ENGULFING
+	BULLISH_HARAMI
+	BEARISH_HARAMI
+	BULLISH_HARAMI_CROSS
+	BEARISH_HARAMI_CROSS
+	PIERCING_LINE
+	DARK_CLOUD_COVER
+	TWEEZER_TOP
+	TWEEZER_BOTTOM
+	BULLISH_KICKER
+	BEARISH_KICKER
+	ON_NECK
+	IN_NECK
+	THRUSTING
+	MATCHING_LOW
+	HOMING_PIGEON
+	BULLISH_COUNTERATTACK
+	BEARISH_COUNTERATTACK
+	// three bars
+	MORNING_STAR
+	EVENING_STAR
+	MORNING_DOJI_STAR
+	EVENING_DOJI_STAR
+	BULLISH_ABANDONED_BABY
+	BEARISH_ABANDONED_BABY
+	THREE_WHITE_SOLDIERS
+	THREE_BLACK_CROWS
+	IDENTICAL_THREE_CROWS
+	THREE_INSIDE_UP
+	THREE_INSIDE_DOWN
+	THREE_OUTSIDE_UP
+	THREE_OUTSIDE_DOWN
+	UPSIDE_TASUKI_GAP
+	DOWNSIDE_TASUKI_GAP
+	TWO_CROWS
+	// five bars
+	RISING_THREE_METHODS
+	FALLING_THREE_METHODS
+)
+
+// candleContext holds the average body and range of the bars before the pattern
+type candleContext struct {
+	avgBody  float64
+	avgRange float64
+}
+
+type candle struct {
+	open, high, low, close float64
+}
+
+func newCandle(r *MatrixRow) candle {
+	return candle{r.Open(), r.High(), r.Low(), r.Close()}
+}
+
+func (c candle) body() float64   { return math.Abs(c.close - c.open) }
+func (c candle) rng() float64    { return c.high - c.low }
+func (c candle) top() float64    { return math.Max(c.open, c.close) }
+func (c candle) bottom() float64 { return math.Min(c.open, c.close) }
+func (c candle) upper() float64  { return c.high - c.top() }
+func (c candle) lower() float64  { return c.bottom() - c.low }
+func (c candle) green() bool     { return c.close > c.open }
+func (c candle) red() bool       { return c.close < c.open }
+func (c candle) mid() float64    { return (c.open + c.close) / 2.0 }
+
+func (c candle) doji() bool {
+	return c.rng() > 0.0 && c.body() <= c.rng()*0.1
+}
+
+func (ctx candleContext) long(c candle) bool {
+	return c.body() > ctx.avgBody*1.3
+}
+
+func (ctx candleContext) short(c candle) bool {
+	return c.body() < ctx.avgBody*0.6
+}
+
+func (ctx candleContext) equal(a, b float64) bool {
+	return math.Abs(a-b) <= ctx.avgBody*0.05
+}
+
+type candleDetector func(w []candle, ctx candleContext) bool
+
+type CandlePatternInfo struct {
+	Pattern CandlePattern
+	Name    string
+	Bars    int
+	// Direction is BULLISH, BEARISH or 0 for indecision
+	Direction int
+	// PriorTrend is the trend required before the pattern (1 = up -1 = down 0 = any)
+	PriorTrend int
+	// Reliability from 1 (low) to 3 (high)
+	Reliability int
+	detect      candleDetector
+}
+
+var candleCatalogue = []CandlePatternInfo{
+	{DOJI, "Doji", 1, 0, 0, 1, func(w []candle, ctx candleContext) bool {
+		return w[0].doji()
+	}},
+	{DRAGONFLY_DOJI, "Dragonfly Doji", 1, BULLISH, -1, 2, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.doji() && c.upper() <= c.rng()*0.1 && c.lower() >= c.rng()*0.6
+	}},
+	{GRAVESTONE_DOJI, "Gravestone Doji", 1, BEARISH, 1, 2, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.doji() && c.lower() <= c.rng()*0.1 && c.upper() >= c.rng()*0.6
+	}},
+	{LONG_LEGGED_DOJI, "Long Legged Doji", 1, 0, 0, 1, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.doji() && c.upper() >= c.rng()*0.3 && c.lower() >= c.rng()*0.3 && c.rng() > ctx.avgRange
+	}},
+	{HAMMER, "Hammer", 1, BULLISH, -1, 2, hammerShape},
+	{HANGING_MAN, "Hanging Man", 1, BEARISH, 1, 2, hammerShape},
+	{INVERTED_HAMMER, "Inverted Hammer", 1, BULLISH, -1, 1, invertedHammerShape},
+	{SHOOTING_STAR, "Shooting Star", 1, BEARISH, 1, 2, invertedHammerShape},
+	{BULLISH_MARUBOZU, "Bullish Marubozu", 1, BULLISH, 0, 2, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.green() && ctx.long(c) && c.upper() <= c.rng()*0.05 && c.lower() <= c.rng()*0.05
+	}},
+	{BEARISH_MARUBOZU, "Bearish Marubozu", 1, BEARISH, 0, 2, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.red() && ctx.long(c) && c.upper() <= c.rng()*0.05 && c.lower() <= c.rng()*0.05
+	}},
+	{SPINNING_TOP, "Spinning Top", 1, 0, 0, 1, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return !c.doji() && c.body() <= c.rng()*0.3 && c.upper() > c.body() && c.lower() > c.body()
+	}},
+	{HIGH_WAVE, "High Wave", 1, 0, 0, 1, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return !c.doji() && c.upper() >= 3.0*c.body() && c.lower() >= 3.0*c.body() && c.rng() > ctx.avgRange
+	}},
+	{BULLISH_BELT_HOLD, "Bullish Belt Hold", 1, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.green() && ctx.long(c) && c.lower() <= c.rng()*0.05
+	}},
+	{BEARISH_BELT_HOLD, "Bearish Belt Hold", 1, BEARISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		c := w[0]
+		return c.red() && ctx.long(c) && c.upper() <= c.rng()*0.05
+	}},
+	{BULLISH_ENGULFING, "Bullish Engulfing", 2, BULLISH, -1, 3, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.green() && c.open <= p.close && c.close >= p.open && c.body() > p.body()
+	}},
+	{BEARISH_ENGULFING, "Bearish Engulfing", 2, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && c.red() && c.open >= p.close && c.close <= p.open && c.body() > p.body()
+	}},
+	{BULLISH_HARAMI, "Bullish Harami", 2, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && !c.doji() && c.top() < p.open && c.bottom() > p.close
+	}},
+	{BEARISH_HARAMI, "Bearish Harami", 2, BEARISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && ctx.long(p) && !c.doji() && c.top() < p.close && c.bottom() > p.open
+	}},
+	{BULLISH_HARAMI_CROSS, "Bullish Harami Cross", 2, BULLISH, -1, 2, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && c.doji() && c.top() < p.open && c.bottom() > p.close
+	}},
+	{BEARISH_HARAMI_CROSS, "Bearish Harami Cross", 2, BEARISH, 1, 2, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && ctx.long(p) && c.doji() && c.top() < p.close && c.bottom() > p.open
+	}},
+	{PIERCING_LINE, "Piercing Line", 2, BULLISH, -1, 2, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && c.green() && c.open < p.low && c.close > p.mid() && c.close < p.open
+	}},
+	{DARK_CLOUD_COVER, "Dark Cloud Cover", 2, BEARISH, 1, 2, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && ctx.long(p) && c.red() && c.open > p.high && c.close < p.mid() && c.close > p.open
+	}},
+	{TWEEZER_TOP, "Tweezer Top", 2, BEARISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && c.red() && ctx.equal(p.high, c.high)
+	}},
+	{TWEEZER_BOTTOM, "Tweezer Bottom", 2, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.green() && ctx.equal(p.low, c.low)
+	}},
+	{BULLISH_KICKER, "Bullish Kicker", 2, BULLISH, 0, 3, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.green() && c.open > p.open && ctx.long(p) && ctx.long(c)
+	}},
+	{BEARISH_KICKER, "Bearish Kicker", 2, BEARISH, 0, 3, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && c.red() && c.open < p.open && ctx.long(p) && ctx.long(c)
+	}},
+	{ON_NECK, "On Neck", 2, BEARISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && c.green() && c.open < p.low && ctx.equal(c.close, p.low)
+	}},
+	{IN_NECK, "In Neck", 2, BEARISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && c.green() && c.open < p.low && c.close >= p.close && c.close <= p.close+p.body()*0.1
+	}},
+	{THRUSTING, "Thrusting", 2, BEARISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && ctx.long(p) && c.green() && c.open < p.low && c.close > p.close+p.body()*0.1 && c.close < p.mid()
+	}},
+	{MATCHING_LOW, "Matching Low", 2, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.red() && ctx.long(p) && ctx.equal(p.close, c.close)
+	}},
+	{HOMING_PIGEON, "Homing Pigeon", 2, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.red() && ctx.long(p) && ctx.short(c) && c.open < p.open && c.close > p.close
+	}},
+	{BULLISH_COUNTERATTACK, "Bullish Counterattack", 2, BULLISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.red() && c.green() && ctx.long(p) && ctx.long(c) && ctx.equal(p.close, c.close)
+	}},
+	{BEARISH_COUNTERATTACK, "Bearish Counterattack", 2, BEARISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		p, c := w[0], w[1]
+		return p.green() && c.red() && ctx.long(p) && ctx.long(c) && ctx.equal(p.close, c.close)
+	}},
+	{MORNING_STAR, "Morning Star", 3, BULLISH, -1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && ctx.long(a) && ctx.short(b) && b.top() < a.close && c.green() && c.close > a.mid()
+	}},
+	{EVENING_STAR, "Evening Star", 3, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && ctx.long(a) && ctx.short(b) && b.bottom() > a.close && c.red() && c.close < a.mid()
+	}},
+	{MORNING_DOJI_STAR, "Morning Doji Star", 3, BULLISH, -1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && ctx.long(a) && b.doji() && b.top() < a.close && c.green() && c.close > a.mid()
+	}},
+	{EVENING_DOJI_STAR, "Evening Doji Star", 3, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && ctx.long(a) && b.doji() && b.bottom() > a.close && c.red() && c.close < a.mid()
+	}},
+	{BULLISH_ABANDONED_BABY, "Bullish Abandoned Baby", 3, BULLISH, -1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && ctx.long(a) && b.doji() && b.high < a.low && b.high < c.low && c.green() && c.close > a.mid()
+	}},
+	{BEARISH_ABANDONED_BABY, "Bearish Abandoned Baby", 3, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && ctx.long(a) && b.doji() && b.low > a.high && b.low > c.high && c.red() && c.close < a.mid()
+	}},
+	{THREE_WHITE_SOLDIERS, "Three White Soldiers", 3, BULLISH, 0, 3, func(w []candle, ctx candleContext) bool {
+		for i, c := range w {
+			if !c.green() || ctx.short(c) || c.upper() > c.rng()*0.2 {
+				return false
+			}
+			if i > 0 && (c.close <= w[i-1].close || c.open < w[i-1].open || c.open > w[i-1].close) {
+				return false
+			}
+		}
+		return true
+	}},
+	{THREE_BLACK_CROWS, "Three Black Crows", 3, BEARISH, 1, 3, threeCrows},
+	{IDENTICAL_THREE_CROWS, "Identical Three Crows", 3, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		return threeCrows(w, ctx) && ctx.equal(w[1].open, w[0].close) && ctx.equal(w[2].open, w[1].close)
+	}},
+	{THREE_INSIDE_UP, "Three Inside Up", 3, BULLISH, -1, 2, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && ctx.long(a) && b.green() && b.close < a.open && b.open > a.close && c.close > a.open
+	}},
+	{THREE_INSIDE_DOWN, "Three Inside Down", 3, BEARISH, 1, 2, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && ctx.long(a) && b.red() && b.close > a.open && b.open < a.close && c.close < a.open
+	}},
+	{THREE_OUTSIDE_UP, "Three Outside Up", 3, BULLISH, -1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && b.green() && b.open <= a.close && b.close >= a.open && b.body() > a.body() && c.close > b.close
+	}},
+	{THREE_OUTSIDE_DOWN, "Three Outside Down", 3, BEARISH, 1, 3, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && b.red() && b.open >= a.close && b.close <= a.open && b.body() > a.body() && c.close < b.close
+	}},
+	{UPSIDE_TASUKI_GAP, "Upside Tasuki Gap", 3, BULLISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && b.green() && b.low > a.high && c.red() && c.open > b.open && c.open < b.close && c.close < b.open && c.close > a.high
+	}},
+	{DOWNSIDE_TASUKI_GAP, "Downside Tasuki Gap", 3, BEARISH, -1, 1, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.red() && b.red() && b.high < a.low && c.green() && c.open < b.open && c.open > b.close && c.close > b.open && c.close < a.low
+	}},
+	{TWO_CROWS, "Two Crows", 3, BEARISH, 1, 1, func(w []candle, ctx candleContext) bool {
+		a, b, c := w[0], w[1], w[2]
+		return a.green() && ctx.long(a) && b.red() && b.bottom() > a.close && c.red() && c.open < b.open && c.open > b.close && c.close < a.close && c.close > a.open
+	}},
+	{RISING_THREE_METHODS, "Rising Three Methods", 5, BULLISH, 1, 2, func(w []candle, ctx candleContext) bool {
+		a, e := w[0], w[4]
+		if !a.green() || !ctx.long(a) || !e.green() || !ctx.long(e) || e.close <= a.close {
+			return false
+		}
+		for _, c := range w[1:4] {
+			if !ctx.short(c) || c.high > a.high || c.low < a.low {
+				return false
+			}
+		}
+		return w[3].close < w[1].close
+	}},
+	{FALLING_THREE_METHODS, "Falling Three Methods", 5, BEARISH, -1, 2, func(w []candle, ctx candleContext) bool {
+		a, e := w[0], w[4]
+		if !a.red() || !ctx.long(a) || !e.red() || !ctx.long(e) || e.close >= a.close {
+			return false
+		}
+		for _, c := range w[1:4] {
+			if !ctx.short(c) || c.high > a.high || c.low < a.low {
+				return false
+			}
+		}
+		return w[3].close > w[1].close
+	}},
+}
+
+func hammerShape(w []candle, ctx candleContext) bool {
+	c := w[0]
+	return !c.doji() && c.body() <= c.rng()*0.33 && c.lower() >= 2.0*c.body() && c.upper() <= c.rng()*0.1
+}
+
+func invertedHammerShape(w []candle, ctx candleContext) bool {
+	c := w[0]
+	return !c.doji() && c.body() <= c.rng()*0.33 && c.upper() >= 2.0*c.body() && c.lower() <= c.rng()*0.1
+}
+
+func threeCrows(w []candle, ctx candleContext) bool {
+	for i, c := range w {
+		if !c.red() || ctx.short(c) || c.lower() > c.rng()*0.2 {
+			return false
+		}
+		if i > 0 && (c.close >= w[i-1].close || c.open > w[i-1].open || c.open < w[i-1].close) {
+			return false
+		}
+	}
+	return true
+}
+
+// Info returns the catalogue entry of the pattern
+func (cp CandlePattern) Info() CandlePatternInfo {
+	for _, ci := range candleCatalogue {
+		if ci.Pattern == cp {
+			return ci
+		}
+	}
+	return CandlePatternInfo{Name: "-"}
+}
+
+func (cp CandlePattern) String() string {
+	return cp.Info().Name
+}
+
+// CandlePatterns returns all patterns of the catalogue
+func CandlePatterns() []CandlePattern {
+	ret := make([]CandlePattern, len(candleCatalogue))
+	for i, ci := range candleCatalogue {
+		ret[i] = ci.Pattern
+	}
+	return ret
+}
+
+type CandlePatternMatch struct {
+	Pattern CandlePattern
+	// Index and Key of the last bar of the pattern
+	Index      int
+	Key        string
+	Direction  int
+	PriorTrend int
+	// Strength between 0 and 1 based on the reliability of the pattern and
+	// the range of the last bar compared to the average range
+	Strength float64
+}
+
+type CandlePatternConfig struct {
+	// Period of the average body and range and of the trend before the pattern
+	Period int
+	// RequireTrend only reports patterns if the trend before the pattern matches
+	RequireTrend bool
+	// Patterns to search for (empty = all)
+	Patterns []CandlePattern
+}
+
+func DefaultCandlePatternConfig() CandlePatternConfig {
+	return CandlePatternConfig{
+		Period:       10,
+		RequireTrend: true,
+	}
+}
+
+func (cfg CandlePatternConfig) enabled(cp CandlePattern) bool {
+	if len(cfg.Patterns) == 0 {
+		return true
+	}
+	for _, p := range cfg.Patterns {
+		if p == cp {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchCandlePatterns checks all patterns ending at the last row of the window.
+// The rows before the pattern are used for the average body size and the
+// prior trend so the window should be at least Period + 5 rows long.
+func MatchCandlePatterns(window []MatrixRow, cfg CandlePatternConfig) []CandlePatternMatch {
+	ret := make([]CandlePatternMatch, 0)
+	n := len(window)
+	if n == 0 {
+		return ret
+	}
+	candles := make([]candle, n)
+	for i := range window {
+		candles[i] = newCandle(&window[i])
+	}
+	for _, ci := range candleCatalogue {
+		if !cfg.enabled(ci.Pattern) || n < ci.Bars {
+			continue
+		}
+		first := n - ci.Bars
+		ctx := candleContext{}
+		cnt := 0
+		for i := max(first-cfg.Period, 0); i < first; i++ {
+			ctx.avgBody += candles[i].body()
+			ctx.avgRange += candles[i].rng()
+			cnt++
+		}
+		if cnt == 0 {
+			continue
+		}
+		ctx.avgBody /= float64(cnt)
+		ctx.avgRange /= float64(cnt)
+		trend := 0
+		if first-cfg.Period-1 >= 0 {
+			d := candles[first-1].close - candles[first-cfg.Period-1].close
+			if d > 0.0 {
+				trend = 1
+			} else if d < 0.0 {
+				trend = -1
+			}
+		}
+		if cfg.RequireTrend && ci.PriorTrend != 0 && trend != ci.PriorTrend {
+			continue
+		}
+		if !ci.detect(candles[first:], ctx) {
+			continue
+		}
+		strength := float64(ci.Reliability) / 3.0
+		if ctx.avgRange > 0.0 {
+			strength *= math.Max(0.5, math.Min(1.5, candles[n-1].rng()/ctx.avgRange)) / 1.5
+		}
+		ret = append(ret, CandlePatternMatch{
+			Pattern:    ci.Pattern,
+			Index:      n - 1,
+			Key:        window[n-1].Key,
+			Direction:  ci.Direction,
+			PriorTrend: trend,
+			Strength:   strength,
+		})
+	}
+	return ret
+}
+
+// FindCandlePatterns runs MatchCandlePatterns for every row of the matrix
+func FindCandlePatterns(m *Matrix, cfg CandlePatternConfig) []CandlePatternMatch {
+	ret := make([]CandlePatternMatch, 0)
+	for i := 0; i < m.CandleRows(); i++ {
+		start := max(i-cfg.Period-5, 0)
+		for _, cm := range MatchCandlePatterns(m.DataRows[start:i+1], cfg) {
+			cm.Index = i
+			ret = append(ret, cm)
+		}
+	}
+	return ret
+}
+
+// CandlePatternColumn adds the strongest pattern of every row
+func CandlePatternColumn(m *Matrix, cfg CandlePatternConfig) int {
+	// 0 = Pattern (CandlePattern) 1 = Direction 2 = Strength
+	ret := m.AddNamedColumn("CandlePattern")
+	dirIdx := m.AddNamedColumn("Direction")
+	strIdx := m.AddNamedColumn("Strength")
+	for _, cm := range FindCandlePatterns(m, cfg) {
+		r := &m.DataRows[cm.Index]
+		if cm.Strength > r.Get(strIdx) {
+			r.Set(ret, float64(cm.Pattern))
+			r.Set(dirIdx, float64(cm.Direction))
+			r.Set(strIdx, cm.Strength)
+		}
+	}
+	return ret
+}
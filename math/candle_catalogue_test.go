@@ -0,0 +1,65 @@
+package math
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// buildTrend adds count bars moving by step with a body of 1
+func buildTrend(mat *Matrix, start, step float64, count int) float64 {
+	v := start
+	for i := 0; i < count; i++ {
+		o, c := v, v+step
+		mat.AddRow(fmt.Sprintf("2024-01-%02d 00:00", mat.Rows+1)).Set(OPEN, o).Set(HIGH, max(o, c)+0.2).Set(LOW, min(o, c)-0.2).Set(ADJ_CLOSE, c)
+		v = c
+	}
+	return v
+}
+
+func hasCandlePattern(matches []CandlePatternMatch, cp CandlePattern) bool {
+	for _, m := range matches {
+		if m.Pattern == cp {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCandleCatalogue(t *testing.T) {
+	mat := NewCandleMatrix()
+	v := buildTrend(mat, 120, -1, 12)
+	// long red bar followed by a bigger green bar
+	mat.AddRow("2024-01-13 00:00").Set(OPEN, v).Set(HIGH, v+0.1).Set(LOW, v-2.1).Set(ADJ_CLOSE, v-2)
+	mat.AddRow("2024-01-14 00:00").Set(OPEN, v-2.5).Set(HIGH, v+1.1).Set(LOW, v-2.6).Set(ADJ_CLOSE, v+1)
+	matches := FindCandlePatterns(mat, DefaultCandlePatternConfig())
+	assert.True(t, hasCandlePattern(matches, BULLISH_ENGULFING))
+	assert.False(t, hasCandlePattern(matches, BEARISH_ENGULFING))
+	last := matches[len(matches)-1]
+	assert.Equal(t, 13, last.Index)
+	assert.Equal(t, -1, last.PriorTrend)
+	assert.Equal(t, "Bullish Engulfing", BULLISH_ENGULFING.String())
+
+	// the same bars after an uptrend do not match when the trend is required
+	mat = NewCandleMatrix()
+	v = buildTrend(mat, 100, 1, 12)
+	mat.AddRow("2024-01-13 00:00").Set(OPEN, v).Set(HIGH, v+0.1).Set(LOW, v-2.1).Set(ADJ_CLOSE, v-2)
+	mat.AddRow("2024-01-14 00:00").Set(OPEN, v-2.5).Set(HIGH, v+1.1).Set(LOW, v-2.6).Set(ADJ_CLOSE, v+1)
+	cfg := DefaultCandlePatternConfig()
+	assert.False(t, hasCandlePattern(MatchCandlePatterns(mat.DataRows, cfg), BULLISH_ENGULFING))
+	cfg.RequireTrend = false
+	assert.True(t, hasCandlePattern(MatchCandlePatterns(mat.DataRows, cfg), BULLISH_ENGULFING))
+	assert.True(t, len(CandlePatterns()) >= 40)
+}
+
+func TestMorningStar(t *testing.T) {
+	mat := NewCandleMatrix()
+	v := buildTrend(mat, 120, -1, 12)
+	mat.AddRow("2024-01-13 00:00").Set(OPEN, v).Set(HIGH, v+0.1).Set(LOW, v-3.1).Set(ADJ_CLOSE, v-3)
+	mat.AddRow("2024-01-14 00:00").Set(OPEN, v-3.5).Set(HIGH, v-3.2).Set(LOW, v-4).Set(ADJ_CLOSE, v-3.6)
+	mat.AddRow("2024-01-15 00:00").Set(OPEN, v-3.4).Set(HIGH, v-0.4).Set(LOW, v-3.5).Set(ADJ_CLOSE, v-0.5)
+	cp := CandlePatternColumn(mat, DefaultCandlePatternConfig())
+	assert.Equal(t, float64(MORNING_STAR), mat.DataRows[14].Get(cp))
+	assert.Equal(t, 1.0, mat.DataRows[14].Get(cp+1))
+}
@@ -0,0 +1,398 @@
+package math
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------
+//
+//	CandleStore
+//
+// -----------------------------------------------------------------------
+// An append-only on disk store of candles. Every symbol/timeframe pair is one file:
+//
+//	header: magic "FMCS" | version | column count | header count | headers
+//	batch:  payload length | crc32 | payload (row count | rows)
+//
+// Every Upsert writes exactly one batch. A batch that was only partially written
+// (crash, full disk) fails the checksum and is cut off before the next append.
+// Reading replays all batches and the last written row wins for each key.
+// The column count is stored separately because a matrix can have fewer
+// headers than columns (NewMatrix). Version 1 files had one header per column.
+const (
+	candleStoreMagic   = "FMCS"
+	candleStoreVersion = uint16(2)
+	candleStoreExt     = ".fmcs"
+)
+
+var ErrCandleStoreHeaders = errors.New("matrix columns do not match the stored columns")
+var ErrCandleStoreCorrupt = errors.New("corrupt batch in candle store")
+
+type CandleStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func NewCandleStore(dir string) (*CandleStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &CandleStore{
+		Dir: dir,
+	}, nil
+}
+
+func (cs *CandleStore) fileName(symbol, timeframe string) string {
+	return filepath.Join(cs.Dir, sanitizeStoreName(symbol), sanitizeStoreName(timeframe)+candleStoreExt)
+}
+
+func sanitizeStoreName(name string) string {
+	r := strings.NewReplacer("/", "_", "\\", "_", ":", "_", " ", "_", "..", "_")
+	return r.Replace(name)
+}
+
+func writeStoreString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+func readStoreString(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+		return "", err
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func encodeStoreHeader(headers []string, cols int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(candleStoreMagic)
+	binary.Write(&buf, binary.LittleEndian, candleStoreVersion)
+	binary.Write(&buf, binary.LittleEndian, uint16(cols))
+	binary.Write(&buf, binary.LittleEndian, uint16(len(headers)))
+	for _, h := range headers {
+		writeStoreString(&buf, h)
+	}
+	return buf.Bytes()
+}
+
+func decodeStoreHeader(r io.Reader) ([]string, int, int64, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, 0, 0, err
+	}
+	if string(magic) != candleStoreMagic {
+		return nil, 0, 0, errors.New("not a candle store file")
+	}
+	var version, cols, cnt uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, 0, 0, err
+	}
+	if version < 1 || version > candleStoreVersion {
+		return nil, 0, 0, fmt.Errorf("unsupported candle store version %d", version)
+	}
+	size := int64(6)
+	if version > 1 {
+		if err := binary.Read(r, binary.LittleEndian, &cols); err != nil {
+			return nil, 0, 0, err
+		}
+		size += 2
+	}
+	if err := binary.Read(r, binary.LittleEndian, &cnt); err != nil {
+		return nil, 0, 0, err
+	}
+	size += 2
+	headers := make([]string, cnt)
+	for i := range headers {
+		h, err := readStoreString(r)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		headers[i] = h
+		size += int64(2 + len(h))
+	}
+	if version == 1 {
+		cols = uint16(len(headers) - 1)
+	}
+	return headers, int(cols), size, nil
+}
+
+func encodeStoreBatch(rows []MatrixRow, cols int) []byte {
+	var payload bytes.Buffer
+	binary.Write(&payload, binary.LittleEndian, uint32(len(rows)))
+	for _, r := range rows {
+		writeStoreString(&payload, r.Key)
+		for i := 0; i < cols; i++ {
+			binary.Write(&payload, binary.LittleEndian, r.Get(i))
+		}
+		writeStoreString(&payload, r.Comment)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(payload.Len()))
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(payload.Bytes()))
+	buf.Write(payload.Bytes())
+	return buf.Bytes()
+}
+
+func decodeStoreBatch(payload []byte, cols int) ([]MatrixRow, error) {
+	r := bytes.NewReader(payload)
+	var cnt uint32
+	if err := binary.Read(r, binary.LittleEndian, &cnt); err != nil {
+		return nil, err
+	}
+	ret := make([]MatrixRow, 0, cnt)
+	for i := uint32(0); i < cnt; i++ {
+		key, err := readStoreString(r)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]float64, cols)
+		if err := binary.Read(r, binary.LittleEndian, values); err != nil {
+			return nil, err
+		}
+		cmt, err := readStoreString(r)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, MatrixRow{
+			Key:     key,
+			Num:     cols,
+			Values:  values,
+			Comment: cmt,
+		})
+	}
+	return ret, nil
+}
+
+// readStoreFile replays all valid batches and returns the rows in write order
+// together with the offset of the end of the last valid batch. Only a torn
+// last batch (running past the end of the file) is skipped. A damaged batch
+// before that returns ErrCandleStoreCorrupt.
+func readStoreFile(fileName string) ([]string, int, []MatrixRow, int64, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+	total := fi.Size()
+	r := bufio.NewReader(f)
+	headers, cols, offset, err := decodeStoreHeader(r)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+	rows := make([]MatrixRow, 0)
+	for offset < total {
+		if offset+8 > total {
+			break
+		}
+		var size, crc uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, 0, nil, 0, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &crc); err != nil {
+			return nil, 0, nil, 0, err
+		}
+		if offset+8+int64(size) > total {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, 0, nil, 0, err
+		}
+		if crc32.ChecksumIEEE(payload) != crc {
+			return nil, 0, nil, 0, fmt.Errorf("%w at offset %d: checksum mismatch", ErrCandleStoreCorrupt, offset)
+		}
+		batch, err := decodeStoreBatch(payload, cols)
+		if err != nil {
+			return nil, 0, nil, 0, fmt.Errorf("%w at offset %d: %v", ErrCandleStoreCorrupt, offset, err)
+		}
+		rows = append(rows, batch...)
+		offset += int64(8 + size)
+	}
+	return headers, cols, rows, offset, nil
+}
+
+func createStoreFile(fileName string, headers []string, cols int) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return err
+	}
+	tmp := fileName + ".tmp"
+	if err := writeFileSync(tmp, encodeStoreHeader(headers, cols)); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fileName)
+}
+
+func writeFileSync(fileName string, data []byte) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func equalHeaders(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Upsert appends all rows of the matrix as one atomic batch. Rows with a key
+// that is already stored replace the old row.
+func (cs *CandleStore) Upsert(symbol, timeframe string, candles *Matrix) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	fileName := cs.fileName(symbol, timeframe)
+	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+		if err := createStoreFile(fileName, candles.Headers, candles.Cols); err != nil {
+			return err
+		}
+	}
+	headers, cols, _, offset, err := readStoreFile(fileName)
+	if err != nil {
+		return err
+	}
+	if cols != candles.Cols || !equalHeaders(headers, candles.Headers) {
+		return ErrCandleStoreHeaders
+	}
+	if candles.Rows == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(fileName, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// cut off anything a previous crash might have left behind
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(encodeStoreBatch(candles.DataRows[:candles.CandleRows()], candles.Cols), offset); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func buildStoreMatrix(headers []string, cols int, rows []MatrixRow, from, to string) *Matrix {
+	latest := make(map[string]int)
+	for i, r := range rows {
+		latest[r.Key] = i
+	}
+	keys := make([]string, 0, len(latest))
+	for k := range latest {
+		if (from == "" || k >= from) && (to == "" || k <= to) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	ret := &Matrix{
+		Cols:    cols,
+		Headers: headers,
+	}
+	for _, k := range keys {
+		ret.DataRows = append(ret.DataRows, rows[latest[k]])
+		ret.Rows++
+	}
+	return ret
+}
+
+// Read returns all stored candles sorted by key
+func (cs *CandleStore) Read(symbol, timeframe string) (*Matrix, error) {
+	return cs.ReadRange(symbol, timeframe, "", "")
+}
+
+// ReadRange returns all candles with from <= key <= to sorted by key. An empty
+// from or to is open ended.
+func (cs *CandleStore) ReadRange(symbol, timeframe, from, to string) (*Matrix, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	headers, cols, rows, _, err := readStoreFile(cs.fileName(symbol, timeframe))
+	if err != nil {
+		return nil, err
+	}
+	return buildStoreMatrix(headers, cols, rows, from, to), nil
+}
+
+// Compact rewrites the file so that it only contains one row per key
+func (cs *CandleStore) Compact(symbol, timeframe string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	fileName := cs.fileName(symbol, timeframe)
+	headers, cols, rows, _, err := readStoreFile(fileName)
+	if err != nil {
+		return err
+	}
+	mat := buildStoreMatrix(headers, cols, rows, "", "")
+	data := encodeStoreHeader(headers, cols)
+	if mat.Rows > 0 {
+		data = append(data, encodeStoreBatch(mat.DataRows, mat.Cols)...)
+	}
+	tmp := fileName + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fileName)
+}
+
+// Symbols returns the names of all symbols in the store
+func (cs *CandleStore) Symbols() ([]string, error) {
+	entries, err := os.ReadDir(cs.Dir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() {
+			ret = append(ret, e.Name())
+		}
+	}
+	return ret, nil
+}
+
+// Timeframes returns all timeframes stored for a symbol
+func (cs *CandleStore) Timeframes(symbol string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(cs.Dir, sanitizeStoreName(symbol)))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0)
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), candleStoreExt) {
+			ret = append(ret, strings.TrimSuffix(e.Name(), candleStoreExt))
+		}
+	}
+	return ret, nil
+}
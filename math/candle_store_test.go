@@ -0,0 +1,96 @@
+package math
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildStoreCandles(keys []string, start float64) *Matrix {
+	mat := NewMatrixWithHeaders(6, []string{"Open", "High", "Low", "Close", "AdjClose", "Volume"})
+	for i, k := range keys {
+		v := start + float64(i)
+		mat.AddRow(k).Set(OPEN, v).Set(HIGH, v+1).Set(LOW, v-1).Set(CLOSE, v).Set(ADJ_CLOSE, v).Set(VOLUME, 100)
+	}
+	return mat
+}
+
+func TestCandleStoreUpsert(t *testing.T) {
+	cs, err := NewCandleStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-02 00:00", "2024-01-03 00:00"}, 10)))
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-03 00:00", "2024-01-04 00:00"}, 20)))
+	mat, err := cs.Read("SAP", "1d")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, mat.Rows)
+	assert.Equal(t, 20.0, mat.DataRows[1].Get(OPEN))
+	mat, err = cs.ReadRange("SAP", "1d", "2024-01-03 00:00", "2024-01-04 00:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mat.Rows)
+	assert.Equal(t, "2024-01-03 00:00", mat.DataRows[0].Key)
+}
+
+func TestCandleStoreTornWrite(t *testing.T) {
+	cs, err := NewCandleStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-02 00:00"}, 10)))
+	// simulate a crash in the middle of a batch
+	f, err := os.OpenFile(cs.fileName("SAP", "1d"), os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	f.Write([]byte{0x20, 0x00, 0x00, 0x00, 0x01, 0x02})
+	f.Close()
+	mat, err := cs.Read("SAP", "1d")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mat.Rows)
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-03 00:00"}, 11)))
+	assert.NoError(t, cs.Compact("SAP", "1d"))
+	mat, err = cs.Read("SAP", "1d")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mat.Rows)
+	assert.Equal(t, 11.0, mat.DataRows[1].Get(OPEN))
+}
+
+func TestCandleStoreCorruptBatch(t *testing.T) {
+	cs, err := NewCandleStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-02 00:00"}, 10)))
+	fileName := cs.fileName("SAP", "1d")
+	fi, err := os.Stat(fileName)
+	assert.NoError(t, err)
+	assert.NoError(t, cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-03 00:00"}, 11)))
+	// damage the payload of the first batch
+	data, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	data[fi.Size()-2] ^= 0xFF
+	assert.NoError(t, os.WriteFile(fileName, data, 0o644))
+	_, err = cs.Read("SAP", "1d")
+	assert.IsError(t, err, ErrCandleStoreCorrupt)
+	err = cs.Upsert("SAP", "1d", buildStoreCandles([]string{"2024-01-04 00:00"}, 12))
+	assert.IsError(t, err, ErrCandleStoreCorrupt)
+	// nothing was cut off
+	after, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), len(after))
+}
+
+func TestCandleStoreColumnCount(t *testing.T) {
+	cs, err := NewCandleStore(t.TempDir())
+	assert.NoError(t, err)
+	// NewMatrix has one header less than columns
+	mat := NewMatrix(6)
+	mat.AddRow("2024-01-02 00:00").Set(OPEN, 1).Set(HIGH, 2).Set(LOW, 3).Set(CLOSE, 4).Set(ADJ_CLOSE, 5).Set(VOLUME, 6)
+	mat.AddRow("2024-01-03 00:00").Set(OPEN, 7).Set(VOLUME, 12)
+	assert.NoError(t, cs.Upsert("SAP", "1d", mat))
+	ret, err := cs.Read("SAP", "1d")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, ret.Cols)
+	assert.Equal(t, 2, ret.Rows)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6}, ret.DataRows[0].Values)
+	assert.Equal(t, []float64{7, 0, 0, 0, 0, 12}, ret.DataRows[1].Values)
+	// same headers but a different number of columns
+	other := NewMatrix(7)
+	other.Headers = mat.Headers
+	other.AddRow("2024-01-04 00:00")
+	assert.IsError(t, cs.Upsert("SAP", "1d", other), ErrCandleStoreHeaders)
+}
@@ -248,8 +248,6 @@ func FindCandleStickPatterns(prices *Matrix) int {
 		}
 
 	}
-	prices.RemoveColumns(11)
-
 	return ret
 }
 
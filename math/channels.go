@@ -1,14 +1,36 @@
 package math
 
 import (
+	m "math"
 	"time"
 )
 
+// TrendLine starts at the row StartIndex with Value and changes by Slope
+// (price per bar) on every row
 type TrendLine struct {
-	Start string
-	End   string
-	Value float64
-	Angle float64
+	Start      string
+	End        string
+	StartIndex int
+	EndIndex   int
+	Value      float64
+	Slope      float64
+}
+
+// NewTrendLine creates the line through two swing points
+func NewTrendLine(sps SwingPoints, first, second int) TrendLine {
+	return TrendLine{
+		Start:      sps[first].Timestamp,
+		End:        sps[second].Timestamp,
+		StartIndex: sps[first].Index,
+		EndIndex:   sps[second].Index,
+		Value:      sps[first].Value,
+		Slope:      sps.GetAngle(first, second),
+	}
+}
+
+// ValueAt returns the value of the line at the row index
+func (tl TrendLine) ValueAt(index int) float64 {
+	return tl.Value + float64(index-tl.StartIndex)*tl.Slope
 }
 
 type TrendChannel struct {
@@ -133,13 +155,14 @@ func CalculateTrendLine(prices *Matrix) int {
 	return li
 }
 
-/*
 type MarketCycle struct {
-	Start string
-	End   string
-	Days  int
-	Trend int
-	Type  int
+	Start      string
+	End        string
+	StartIndex int
+	EndIndex   int
+	Days       int
+	Trend      int
+	Type       int
 }
 
 type MarketCycles []MarketCycle
@@ -153,45 +176,64 @@ func (m MarketCycles) Contains(date string) bool {
 	return false
 }
 
-func FindContractionPhases(prices *model.DataFrame, threshold float64) []MarketCycle {
+// rollingNormalize scales the field into 0..1 based on the min and max of the
+// last period rows
+func rollingNormalize(prices *Matrix, field, period int) []float64 {
+	ret := make([]float64, prices.CandleRows())
+	for i := range ret {
+		start := max(i-period+1, 0)
+		lo, hi := prices.DataRows[start].Get(field), prices.DataRows[start].Get(field)
+		for j := start + 1; j <= i; j++ {
+			v := prices.DataRows[j].Get(field)
+			lo = m.Min(lo, v)
+			hi = m.Max(hi, v)
+		}
+		if hi > lo {
+			ret[i] = (prices.DataRows[i].Get(field) - lo) / (hi - lo)
+		}
+	}
+	return ret
+}
+
+// FindContractionPhases finds the phases where the normalized highs and lows
+// stay within threshold for more than two bars
+func FindContractionPhases(prices *Matrix, threshold float64) MarketCycles {
 	var ret MarketCycles
-	nh := prices.Normalize("High", "NH", 50)
-	nl := prices.Normalize("Low", "NL", 50)
-	for i, p := range nh.DataRows {
-		cur := p.Get("NH")
-		cl := nl.FindByTimestamp(p.Timestamp).Get("NL")
+	nh := rollingNormalize(prices, HIGH, 50)
+	nl := rollingNormalize(prices, LOW, 50)
+	for i := range nh {
+		cur := nh[i]
+		cl := nl[i]
 		hc := 0
-		for j := i; j < nh.Rows; j++ {
-			c := nh.DataRows[j].Get("NH")
-			if m.Abs(c-cur) > threshold {
+		for j := i; j < len(nh); j++ {
+			if m.Abs(nh[j]-cur) > threshold {
 				break
 			}
 			hc++
 		}
 		lc := 0
-		ed := ""
-		for j := i; j < nl.Rows; j++ {
-			c := nl.DataRows[j].Get("NL")
-			if m.Abs(c-cl) > threshold {
+		ed := -1
+		for j := i; j < len(nl); j++ {
+			if m.Abs(nl[j]-cl) > threshold {
 				break
 			}
-			ed = nl.DataRows[j].Timestamp
+			ed = j
 			lc++
 		}
 		if hc > 2 && lc > 2 {
-			sd := p.Timestamp
-			if ret.Contains(sd) == false {
-				mc := MarketCycle{
-					Start: p.Timestamp,
-					End:   ed,
-					Days:  hc,
-					Type:  1,
-					Trend: 0,
-				}
-				ret = append(ret, mc)
+			sd := prices.DataRows[i].Key
+			if !ret.Contains(sd) {
+				ret = append(ret, MarketCycle{
+					Start:      sd,
+					End:        prices.DataRows[ed].Key,
+					StartIndex: i,
+					EndIndex:   ed,
+					Days:       hc,
+					Type:       1,
+					Trend:      0,
+				})
 			}
 		}
 	}
 	return ret
 }
-*/
@@ -0,0 +1,468 @@
+package math
+
+import (
+	"math"
+	"sort"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Classical chart patterns
+//
+// -----------------------------------------------------------------------
+type ChartPatternType int
+
+const (
+	HEAD_AND_SHOULDERS ChartPatternType = iota + 1
+	INVERSE_HEAD_AND_SHOULDERS
+	DOUBLE_TOP
+	DOUBLE_BOTTOM
+	TRIPLE_TOP
+	TRIPLE_BOTTOM
+	ASCENDING_TRIANGLE
+	DESCENDING_TRIANGLE
+	SYMMETRIC_TRIANGLE
+	RISING_WEDGE
+	FALLING_WEDGE
+	BULL_FLAG
+	BEAR_FLAG
+	BULL_PENNANT
+	BEAR_PENNANT
+)
+
+func (cp ChartPatternType) String() string {
+	switch cp {
+	case HEAD_AND_SHOULDERS:
+		return "Head and Shoulders"
+	case INVERSE_HEAD_AND_SHOULDERS:
+		return "Inverse Head and Shoulders"
+	case DOUBLE_TOP:
+		return "Double Top"
+	case DOUBLE_BOTTOM:
+		return "Double Bottom"
+	case TRIPLE_TOP:
+		return "Triple Top"
+	case TRIPLE_BOTTOM:
+		return "Triple Bottom"
+	case ASCENDING_TRIANGLE:
+		return "Ascending Triangle"
+	case DESCENDING_TRIANGLE:
+		return "Descending Triangle"
+	case SYMMETRIC_TRIANGLE:
+		return "Symmetric Triangle"
+	case RISING_WEDGE:
+		return "Rising Wedge"
+	case FALLING_WEDGE:
+		return "Falling Wedge"
+	case BULL_FLAG:
+		return "Bull Flag"
+	case BEAR_FLAG:
+		return "Bear Flag"
+	case BULL_PENNANT:
+		return "Bull Pennant"
+	case BEAR_PENNANT:
+		return "Bear Pennant"
+	}
+	return "-"
+}
+
+func flatTrendLine(sp SwingPoint, value float64) TrendLine {
+	return TrendLine{
+		Start:      sp.Timestamp,
+		End:        sp.Timestamp,
+		StartIndex: sp.Index,
+		EndIndex:   sp.Index,
+		Value:      value,
+	}
+}
+
+type ChartPattern struct {
+	Type ChartPatternType
+	// Direction is the expected breakout (1 = up -1 = down)
+	Direction int
+	// Pivots are the row indices of the swing points forming the pattern
+	Pivots []int
+	Start  int
+	End    int
+	// Neckline of head and shoulders and double/triple tops and bottoms
+	Neckline TrendLine
+	// Upper and Lower are the boundaries of triangles, wedges, flags and pennants
+	Upper TrendLine
+	Lower TrendLine
+	// Breakout is the level at the last pivot. BreakoutIndex is the first close
+	// beyond the breakout line after the pattern or -1.
+	Breakout      float64
+	BreakoutIndex int
+	// Target is the measured move from the breakout level
+	Target float64
+	// Quality is between 0 and 1
+	Quality float64
+}
+
+type ChartPatternConfig struct {
+	// Tolerance in percent for levels that should be equal
+	Tolerance float64
+	// MinDepth in percent between tops and the valley in between
+	MinDepth float64
+	// FlatSlope in percent per bar below which a line counts as flat
+	FlatSlope float64
+	// FlagPole is the minimum move before a flag or pennant in multiples of the ATR
+	FlagPole  float64
+	ATRPeriod int
+}
+
+func DefaultChartPatternConfig() ChartPatternConfig {
+	return ChartPatternConfig{
+		Tolerance: 2.0,
+		MinDepth:  3.0,
+		FlatSlope: 0.05,
+		FlagPole:  3.0,
+		ATRPeriod: 14,
+	}
+}
+
+// alternateSwingPoints keeps only the extreme of consecutive highs or lows
+func alternateSwingPoints(sps SwingPoints) SwingPoints {
+	sorted := make(SwingPoints, len(sps))
+	copy(sorted, sps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+	var ret SwingPoints
+	for _, sp := range sorted {
+		if n := len(ret); n > 0 && ret[n-1].BaseType == sp.BaseType {
+			if (sp.BaseType == High && sp.Value > ret[n-1].Value) || (sp.BaseType == Low && sp.Value < ret[n-1].Value) {
+				ret[n-1] = sp
+			}
+			continue
+		}
+		ret = append(ret, sp)
+	}
+	return ret
+}
+
+func equalLevels(a, b, tolerance float64) (bool, float64) {
+	ref := math.Max(math.Abs(a), math.Abs(b))
+	if ref == 0.0 {
+		return true, 1.0
+	}
+	d := math.Abs(a-b) / ref * 100.0
+	if d > tolerance {
+		return false, 0.0
+	}
+	return true, 1.0 - d/tolerance
+}
+
+func timeSymmetry(a, b int) float64 {
+	mx := math.Max(float64(a), float64(b))
+	if mx == 0.0 {
+		return 1.0
+	}
+	return 1.0 - math.Abs(float64(a-b))/mx
+}
+
+func pivotIndices(sps SwingPoints) []int {
+	ret := make([]int, len(sps))
+	for i, s := range sps {
+		ret[i] = s.Index
+	}
+	return ret
+}
+
+// FindChartPatterns searches the swing points (e.g. FindSwingPoints or FindZigZag)
+// for classical chart patterns. Unconfirmed swing points are skipped so a
+// pattern never ends on a pivot that can still move.
+func FindChartPatterns(m *Matrix, sps SwingPoints, cfg ChartPatternConfig) []ChartPattern {
+	ret := make([]ChartPattern, 0)
+	var confirmed SwingPoints
+	for _, sp := range sps {
+		if !sp.Unconfirmed {
+			confirmed = append(confirmed, sp)
+		}
+	}
+	pts := alternateSwingPoints(confirmed)
+	ret = append(ret, findHeadAndShoulders(pts, cfg)...)
+	ret = append(ret, findMultipleTops(pts, cfg)...)
+	ret = append(ret, findTriangles(pts, cfg)...)
+	if m.CandleRows() > 0 {
+		atr := ATR(m, cfg.ATRPeriod)
+		ret = append(ret, findFlags(m, pts, atr, cfg)...)
+		m.RemoveColumn()
+	}
+	for i := range ret {
+		ret[i].BreakoutIndex = findPatternBreakout(m, &ret[i])
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].End < ret[j].End
+	})
+	return ret
+}
+
+func (cp *ChartPattern) breakoutLine() TrendLine {
+	switch cp.Type {
+	case HEAD_AND_SHOULDERS, INVERSE_HEAD_AND_SHOULDERS, DOUBLE_TOP, DOUBLE_BOTTOM, TRIPLE_TOP, TRIPLE_BOTTOM:
+		return cp.Neckline
+	}
+	if cp.Direction == 1 {
+		return cp.Upper
+	}
+	return cp.Lower
+}
+
+func findPatternBreakout(m *Matrix, cp *ChartPattern) int {
+	line := cp.breakoutLine()
+	for i := cp.End + 1; i < m.CandleRows(); i++ {
+		c := m.DataRows[i].Close()
+		lv := line.ValueAt(i)
+		if (cp.Direction == 1 && c > lv) || (cp.Direction == -1 && c < lv) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findHeadAndShoulders(pts SwingPoints, cfg ChartPatternConfig) []ChartPattern {
+	ret := make([]ChartPattern, 0)
+	for i := 0; i+4 < len(pts); i++ {
+		ls, head, rs := pts[i], pts[i+2], pts[i+4]
+		dir := -1
+		tp := HEAD_AND_SHOULDERS
+		if ls.BaseType == Low {
+			dir = 1
+			tp = INVERSE_HEAD_AND_SHOULDERS
+		}
+		// the head must exceed both shoulders
+		if float64(dir)*(ls.Value-head.Value) <= 0.0 || float64(dir)*(rs.Value-head.Value) <= 0.0 {
+			continue
+		}
+		ok, sym := equalLevels(ls.Value, rs.Value, cfg.Tolerance*2.0)
+		if !ok {
+			continue
+		}
+		neck := NewTrendLine(pts, i+1, i+3)
+		height := math.Abs(head.Value - neck.ValueAt(head.Index))
+		breakout := neck.ValueAt(rs.Index)
+		ret = append(ret, ChartPattern{
+			Type:      tp,
+			Direction: dir,
+			Pivots:    pivotIndices(pts[i : i+5]),
+			Start:     ls.Index,
+			End:       rs.Index,
+			Neckline:  neck,
+			Breakout:  breakout,
+			Target:    breakout + float64(dir)*height,
+			Quality:   (sym + timeSymmetry(head.Index-ls.Index, rs.Index-head.Index)) / 2.0,
+		})
+	}
+	return ret
+}
+
+// findMultipleTops finds double and triple tops and bottoms
+func findMultipleTops(pts SwingPoints, cfg ChartPatternConfig) []ChartPattern {
+	ret := make([]ChartPattern, 0)
+	for i := 0; i+2 < len(pts); i++ {
+		first := pts[i]
+		dir := -1
+		if first.BaseType == Low {
+			dir = 1
+		}
+		for _, count := range []int{3, 2} {
+			last := i + (count-1)*2
+			if last >= len(pts) {
+				continue
+			}
+			quality := 0.0
+			matched := true
+			valley := pts[i+1].Value
+			for j := i + 2; j <= last; j += 2 {
+				ok, q := equalLevels(first.Value, pts[j].Value, cfg.Tolerance)
+				if !ok {
+					matched = false
+					break
+				}
+				quality += q
+				if dir == -1 {
+					valley = math.Min(valley, pts[j-1].Value)
+				} else {
+					valley = math.Max(valley, pts[j-1].Value)
+				}
+			}
+			if !matched {
+				continue
+			}
+			top := 0.0
+			for j := i; j <= last; j += 2 {
+				top += pts[j].Value
+			}
+			top /= float64(count)
+			if math.Abs(top-valley)/top*100.0 < cfg.MinDepth {
+				continue
+			}
+			tp := DOUBLE_TOP
+			switch {
+			case count == 3 && dir == -1:
+				tp = TRIPLE_TOP
+			case count == 3:
+				tp = TRIPLE_BOTTOM
+			case dir == 1:
+				tp = DOUBLE_BOTTOM
+			}
+			ret = append(ret, ChartPattern{
+				Type:      tp,
+				Direction: dir,
+				Pivots:    pivotIndices(pts[i : last+1]),
+				Start:     first.Index,
+				End:       pts[last].Index,
+				Neckline:  flatTrendLine(pts[i+1], valley),
+				Breakout:  valley,
+				Target:    valley - (top - valley),
+				Quality:   quality / float64(count-1),
+			})
+			// a triple top is not reported as double top as well
+			break
+		}
+	}
+	return ret
+}
+
+func slopePercent(tl TrendLine, price float64) float64 {
+	if price == 0.0 {
+		return 0.0
+	}
+	return tl.Slope / price * 100.0
+}
+
+// findTriangles uses four alternating pivots to draw the upper and lower boundary
+func findTriangles(pts SwingPoints, cfg ChartPatternConfig) []ChartPattern {
+	ret := make([]ChartPattern, 0)
+	for i := 0; i+3 < len(pts); i++ {
+		hi, lo := i, i+1
+		if pts[i].BaseType == Low {
+			hi, lo = i+1, i
+		}
+		upper := NewTrendLine(pts, hi, hi+2)
+		lower := NewTrendLine(pts, lo, lo+2)
+		start := pts[i].Index
+		end := pts[i+3].Index
+		ws := upper.ValueAt(start) - lower.ValueAt(start)
+		we := upper.ValueAt(end) - lower.ValueAt(end)
+		// the lines have to converge
+		if ws <= 0.0 || we <= 0.0 || we >= ws {
+			continue
+		}
+		price := pts[i].Value
+		su := slopePercent(upper, price)
+		sl := slopePercent(lower, price)
+		flatU := math.Abs(su) <= cfg.FlatSlope
+		flatL := math.Abs(sl) <= cfg.FlatSlope
+		cp := ChartPattern{
+			Pivots:  pivotIndices(pts[i : i+4]),
+			Start:   start,
+			End:     end,
+			Upper:   upper,
+			Lower:   lower,
+			Quality: 1.0 - we/ws,
+		}
+		switch {
+		case flatU && sl > cfg.FlatSlope:
+			cp.Type = ASCENDING_TRIANGLE
+			cp.Direction = 1
+		case flatL && su < -cfg.FlatSlope:
+			cp.Type = DESCENDING_TRIANGLE
+			cp.Direction = -1
+		case su < -cfg.FlatSlope && sl > cfg.FlatSlope:
+			cp.Type = SYMMETRIC_TRIANGLE
+			// continuation of the move into the triangle
+			cp.Direction = 1
+			if i > 0 && pts[i-1].Value > pts[i].Value {
+				cp.Direction = -1
+			}
+		case su > cfg.FlatSlope && sl > cfg.FlatSlope:
+			cp.Type = RISING_WEDGE
+			cp.Direction = -1
+		case su < -cfg.FlatSlope && sl < -cfg.FlatSlope:
+			cp.Type = FALLING_WEDGE
+			cp.Direction = 1
+		default:
+			continue
+		}
+		if cp.Direction == 1 {
+			cp.Breakout = upper.ValueAt(end)
+		} else {
+			cp.Breakout = lower.ValueAt(end)
+		}
+		cp.Target = cp.Breakout + float64(cp.Direction)*ws
+		ret = append(ret, cp)
+	}
+	return ret
+}
+
+// findFlags searches for a strong move (the pole) followed by a small
+// consolidation against the move (flag) or a converging one (pennant)
+func findFlags(m *Matrix, pts SwingPoints, atr int, cfg ChartPatternConfig) []ChartPattern {
+	ret := make([]ChartPattern, 0)
+	for i := 0; i+4 < len(pts); i++ {
+		base, top := pts[i], pts[i+1]
+		pole := top.Value - base.Value
+		dir := 1
+		if base.BaseType == High {
+			dir = -1
+		}
+		a := m.DataRows[top.Index].Get(atr)
+		if a == 0.0 || math.Abs(pole) < cfg.FlagPole*a {
+			continue
+		}
+		// the consolidation may not retrace more than half of the pole
+		if math.Abs(pts[i+2].Value-top.Value) > math.Abs(pole)/2.0 || math.Abs(pts[i+4].Value-top.Value) > math.Abs(pole)/2.0 {
+			continue
+		}
+		var upper, lower TrendLine
+		if dir == 1 {
+			upper = NewTrendLine(pts, i+1, i+3)
+			lower = NewTrendLine(pts, i+2, i+4)
+		} else {
+			upper = NewTrendLine(pts, i+2, i+4)
+			lower = NewTrendLine(pts, i+1, i+3)
+		}
+		su := slopePercent(upper, top.Value)
+		sl := slopePercent(lower, top.Value)
+		cp := ChartPattern{
+			Direction: dir,
+			Pivots:    pivotIndices(pts[i : i+5]),
+			Start:     base.Index,
+			End:       pts[i+4].Index,
+			Upper:     upper,
+			Lower:     lower,
+		}
+		parallel := math.Abs(su-sl) <= cfg.FlatSlope*2.0
+		switch {
+		case parallel && float64(dir)*su <= cfg.FlatSlope:
+			cp.Type = BULL_FLAG
+			if dir == -1 {
+				cp.Type = BEAR_FLAG
+			}
+			cp.Quality = 1.0 - math.Abs(su-sl)/(cfg.FlatSlope*2.0)/2.0
+		case su < -cfg.FlatSlope/2.0 && sl > cfg.FlatSlope/2.0:
+			cp.Type = BULL_PENNANT
+			if dir == -1 {
+				cp.Type = BEAR_PENNANT
+			}
+			ws := upper.ValueAt(top.Index) - lower.ValueAt(top.Index)
+			if ws <= 0.0 {
+				continue
+			}
+			cp.Quality = 1.0 - (upper.ValueAt(cp.End)-lower.ValueAt(cp.End))/ws
+		default:
+			continue
+		}
+		if dir == 1 {
+			cp.Breakout = upper.ValueAt(cp.End)
+		} else {
+			cp.Breakout = lower.ValueAt(cp.End)
+		}
+		cp.Target = cp.Breakout + pole
+		cp.Quality = math.Max(0.0, math.Min(1.0, cp.Quality))
+		ret = append(ret, cp)
+	}
+	return ret
+}
@@ -0,0 +1,54 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func findPattern(patterns []ChartPattern, tp ChartPatternType) *ChartPattern {
+	for i := range patterns {
+		if patterns[i].Type == tp {
+			return &patterns[i]
+		}
+	}
+	return nil
+}
+
+func TestHeadAndShoulders(t *testing.T) {
+	pivots := []float64{90, 110, 100, 120, 100, 110, 90}
+	mat := candleMatrix(closeBars(append(pathCloses(pivots, 4), pivots[len(pivots)-1]), 0))
+	sps := mat.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, 5.0))
+	patterns := FindChartPatterns(mat, sps, DefaultChartPatternConfig())
+	hs := findPattern(patterns, HEAD_AND_SHOULDERS)
+	assert.NotZero(t, hs)
+	assert.Equal(t, []int{4, 8, 12, 16, 20}, hs.Pivots)
+	assert.Equal(t, 100.0, hs.Breakout)
+	assert.Equal(t, 80.0, hs.Target)
+	assert.Equal(t, 1.0, hs.Quality)
+	assert.Equal(t, 23, hs.BreakoutIndex)
+	// the right shoulder is the last pivot and can still move
+	pivots = []float64{90, 110, 100, 120, 100, 110}
+	mat = candleMatrix(closeBars(append(pathCloses(pivots, 4), 110, 108), 0))
+	sps = mat.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, 5.0))
+	assert.True(t, sps[len(sps)-1].Unconfirmed)
+	assert.Zero(t, findPattern(FindChartPatterns(mat, sps, DefaultChartPatternConfig()), HEAD_AND_SHOULDERS))
+}
+
+func TestDoubleBottomAndTriangle(t *testing.T) {
+	pivots := []float64{120, 100, 110, 100.5, 125}
+	mat := candleMatrix(closeBars(append(pathCloses(pivots, 4), pivots[len(pivots)-1]), 0))
+	patterns := FindChartPatterns(mat, mat.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, 5.0)), DefaultChartPatternConfig())
+	db := findPattern(patterns, DOUBLE_BOTTOM)
+	assert.NotZero(t, db)
+	assert.Equal(t, 110.0, db.Breakout)
+	assert.Equal(t, 119.75, db.Target)
+
+	pivots = []float64{90, 110, 95, 110, 100, 110}
+	mat = candleMatrix(closeBars(append(pathCloses(pivots, 4), pivots[len(pivots)-1]), 0))
+	patterns = FindChartPatterns(mat, mat.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, 3.0)), DefaultChartPatternConfig())
+	at := findPattern(patterns, ASCENDING_TRIANGLE)
+	assert.NotZero(t, at)
+	assert.Equal(t, 1, at.Direction)
+	assert.Equal(t, 110.0, at.Breakout)
+}
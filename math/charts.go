@@ -0,0 +1,248 @@
+package math
+
+import (
+	"fmt"
+	m "math"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Alternative charts
+//
+// -----------------------------------------------------------------------
+// Renko, range bars, Kagi and Point & Figure turn a time based matrix into a
+// new matrix where time is not relevant anymore. Every row has the default
+// OPEN ... VOLUME columns so all indicators work on the result. CHART_SOURCE
+// is the row in the source matrix that completed the row and CHART_DIRECTION is
+// 1 for up and -1 for down bricks, lines or columns. Several rows can be
+// created by the same source row, so the key is the key of the source row with
+// the number of the row for this source as suffix (2024-01-05#1, 2024-01-05#2).
+const (
+	CHART_SOURCE    int = 6
+	CHART_DIRECTION int = 7
+)
+
+// NewChartMatrix returns an empty matrix with the columns produced by the chart transformers
+func NewChartMatrix() *Matrix {
+	return NewMatrixWithHeaders(8, append(append([]string{}, CANDLE_HEADERS...), "Source", "Direction"))
+}
+
+func addChartRow(ret, src *Matrix, index int, open, high, low, close, volume float64, dir int) *MatrixRow {
+	seq := 1
+	for i := ret.Rows - 1; i >= 0 && int(ret.DataRows[i].Get(CHART_SOURCE)) == index; i-- {
+		seq++
+	}
+	r := ret.AddRow(fmt.Sprintf("%s#%d", src.DataRows[index].Key, seq))
+	r.Set(OPEN, open).Set(HIGH, high).Set(LOW, low).Set(CLOSE, close).Set(ADJ_CLOSE, close).Set(VOLUME, volume)
+	r.Set(CHART_SOURCE, float64(index)).Set(CHART_DIRECTION, float64(dir))
+	return r
+}
+
+// Renko builds bricks of the given size on the close. A new brick in the
+// same direction needs a move of one box beyond the last brick, a reversal
+// a move of one box beyond the open of the last brick. The volume since the
+// last brick goes to the first brick of a row.
+func Renko(prices *Matrix, box float64) *Matrix {
+	return renko(prices, box, 0)
+}
+
+func renko(prices *Matrix, box float64, start int) *Matrix {
+	ret := NewChartMatrix()
+	rows := prices.CandleRows()
+	if start >= rows || box <= 0.0 {
+		return ret
+	}
+	top := prices.DataRows[start].Close()
+	bottom := top
+	volume := 0.0
+	for i := start; i < rows; i++ {
+		c := &prices.DataRows[i]
+		volume += c.Get(VOLUME)
+		price := c.Close()
+		for price >= top+box {
+			addChartRow(ret, prices, i, top, top+box, top, top+box, volume, 1)
+			volume = 0.0
+			bottom = top
+			top += box
+		}
+		for price <= bottom-box {
+			addChartRow(ret, prices, i, bottom, bottom, bottom-box, bottom-box, volume, -1)
+			volume = 0.0
+			top = bottom
+			bottom -= box
+		}
+	}
+	return ret
+}
+
+// RenkoATR uses the ATR after the first period candles as box size. The
+// bricks start at this candle so no later candle changes the box.
+func RenkoATR(prices *Matrix, period int) *Matrix {
+	rows := prices.CandleRows()
+	if rows == 0 {
+		return NewChartMatrix()
+	}
+	start := min(max(period, 0), rows-1)
+	atr := ATR(prices, period)
+	box := prices.DataRows[start].Get(atr)
+	prices.RemoveColumn()
+	return renko(prices, box, start)
+}
+
+// candlePath returns the assumed path of the price inside a candle. Green
+// candles visit the low first, red candles the high.
+func candlePath(c *MatrixRow) []float64 {
+	if c.Close() >= c.Open() {
+		return []float64{c.Open(), c.Low(), c.High(), c.Close()}
+	}
+	return []float64{c.Open(), c.High(), c.Low(), c.Close()}
+}
+
+// RangeBars builds bars with a range (high - low) of size. The path inside
+// every candle is estimated by candlePath. The volume of a candle goes to the
+// bar that is open at the close of the candle.
+func RangeBars(prices *Matrix, size float64) *Matrix {
+	ret := NewChartMatrix()
+	rows := prices.CandleRows()
+	if rows == 0 || size <= 0.0 {
+		return ret
+	}
+	open := prices.DataRows[0].Open()
+	high := open
+	low := open
+	volume := 0.0
+	for i := 0; i < rows; i++ {
+		c := &prices.DataRows[i]
+		for _, p := range candlePath(c) {
+			for p > high && p-low >= size {
+				end := low + size
+				addChartRow(ret, prices, i, open, end, low, end, volume, 1)
+				volume = 0.0
+				open, high, low = end, end, end
+			}
+			for p < low && high-p >= size {
+				end := high - size
+				addChartRow(ret, prices, i, open, high, end, end, volume, -1)
+				volume = 0.0
+				open, high, low = end, end, end
+			}
+			high = m.Max(high, p)
+			low = m.Min(low, p)
+		}
+		volume += c.Get(VOLUME)
+	}
+	return ret
+}
+
+// Kagi draws a new line every time the close reverses by more than reversal
+// (absolute or in percent of the last extreme). Every row is one line from
+// the previous extreme to the current one and belongs to the candle of the
+// reversal. The last line is still open and belongs to the last candle.
+func Kagi(prices *Matrix, reversal float64, percent bool) *Matrix {
+	ret := NewChartMatrix()
+	rows := prices.CandleRows()
+	if rows == 0 || reversal <= 0.0 {
+		return ret
+	}
+	start := prices.DataRows[0].Close()
+	extreme := start
+	dir := 0
+	volume := 0.0
+	for i := 0; i < rows; i++ {
+		c := &prices.DataRows[i]
+		volume += c.Get(VOLUME)
+		price := c.Close()
+		amount := reversal
+		if percent {
+			amount = extreme * reversal / 100.0
+		}
+		switch {
+		case dir == 0:
+			if price-start >= amount {
+				dir, extreme = 1, price
+			} else if start-price >= amount {
+				dir, extreme = -1, price
+			}
+		case dir == 1 && price > extreme:
+			extreme = price
+		case dir == -1 && price < extreme:
+			extreme = price
+		case dir == 1 && extreme-price >= amount:
+			addChartRow(ret, prices, i, start, extreme, start, extreme, volume, 1)
+			volume = 0.0
+			start, extreme, dir = extreme, price, -1
+		case dir == -1 && price-extreme >= amount:
+			addChartRow(ret, prices, i, start, start, extreme, extreme, volume, -1)
+			volume = 0.0
+			start, extreme, dir = extreme, price, 1
+		}
+	}
+	if dir != 0 {
+		addChartRow(ret, prices, rows-1, start, m.Max(start, extreme), m.Min(start, extreme), extreme, volume, dir)
+	}
+	return ret
+}
+
+// PointAndFigure builds X (up) and O (down) columns on the close. A column
+// extends with every full box and reverses after reversal boxes. OPEN is the
+// first and CLOSE the last box of a column. A column belongs to the candle of
+// the reversal. The last column is still open and belongs to the last candle.
+func PointAndFigure(prices *Matrix, box float64, reversal int) *Matrix {
+	ret := NewChartMatrix()
+	rows := prices.CandleRows()
+	if rows == 0 || box <= 0.0 || reversal < 1 {
+		return ret
+	}
+	floor := func(v float64) float64 {
+		return m.Floor(v/box+1e-9) * box
+	}
+	ceil := func(v float64) float64 {
+		return m.Ceil(v/box-1e-9) * box
+	}
+	base := floor(prices.DataRows[0].Close())
+	top := base
+	bottom := base
+	dir := 0
+	volume := 0.0
+	rev := float64(reversal) * box
+	for i := 0; i < rows; i++ {
+		c := &prices.DataRows[i]
+		volume += c.Get(VOLUME)
+		price := c.Close()
+		switch dir {
+		case 0:
+			if price >= base+box {
+				dir, top = 1, floor(price)
+			} else if price <= base-box {
+				dir, bottom = -1, ceil(price)
+			}
+		case 1:
+			if price >= top+box {
+				top = floor(price)
+			} else if price <= top-rev {
+				addChartRow(ret, prices, i, bottom, top, bottom, top, volume, 1)
+				volume = 0.0
+				dir, top, bottom = -1, top-box, ceil(price)
+			}
+		case -1:
+			if price <= bottom-box {
+				bottom = ceil(price)
+			} else if price >= bottom+rev {
+				addChartRow(ret, prices, i, top, top, bottom, bottom, volume, -1)
+				volume = 0.0
+				dir, bottom, top = 1, bottom+box, floor(price)
+			}
+		}
+	}
+	if dir == 1 {
+		addChartRow(ret, prices, rows-1, bottom, top, bottom, top, volume, 1)
+	} else if dir == -1 {
+		addChartRow(ret, prices, rows-1, top, top, bottom, bottom, volume, -1)
+	}
+	return ret
+}
+
+// PointAndFigureBoxes returns the number of boxes of a Point & Figure column
+func PointAndFigureBoxes(row *MatrixRow, box float64) int {
+	return int(m.Round((row.High()-row.Low())/box)) + 1
+}
@@ -0,0 +1,92 @@
+package math
+
+import (
+	"math"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestRenko(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 104, 103, 102, 101}, 0.5))
+	renko := Renko(mat, 1.0)
+	// 5 bricks up and the reversal needs 2 boxes so 3 bricks down
+	assert.Equal(t, 8, renko.Rows)
+	assert.Equal(t, 1.0, renko.DataRows[4].Get(CHART_DIRECTION))
+	assert.Equal(t, 105.0, renko.DataRows[4].Close())
+	assert.Equal(t, -1.0, renko.DataRows[5].Get(CHART_DIRECTION))
+	assert.Equal(t, 103.0, renko.DataRows[5].Close())
+	assert.Equal(t, 7.0, renko.DataRows[5].Get(CHART_SOURCE))
+	assert.Equal(t, mat.DataRows[7].Key+"#1", renko.DataRows[5].Key)
+	// two bricks per candle get their own keys
+	half := Renko(mat, 0.5)
+	assert.Equal(t, mat.DataRows[1].Key+"#1", half.DataRows[0].Key)
+	assert.Equal(t, mat.DataRows[1].Key+"#2", half.DataRows[1].Key)
+	keys := make(map[string]bool)
+	for _, k := range half.GetKeys() {
+		keys[k] = true
+	}
+	assert.Equal(t, half.Rows, len(keys))
+	// indicators run on the bricks
+	sma := SMA(renko, 3, ADJ_CLOSE)
+	assert.Equal(t, 104.0, renko.DataRows[4].Get(sma))
+}
+
+func TestRenkoATR(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 104, 103, 102, 101}, 0.5))
+	cols := mat.Cols
+	renko := RenkoATR(mat, 3)
+	assert.Equal(t, cols, mat.Cols)
+	// every true range is 1.5 so the ATR on row 3 is 1.0556 and the bricks
+	// start at the close of 103
+	box := ((0.5*2.0+1.5)/3.0*2.0 + 1.5) / 3.0
+	assert.Equal(t, 2, renko.Rows)
+	assert.Equal(t, 1.0, renko.DataRows[0].Get(CHART_DIRECTION))
+	assert.Equal(t, 103.0, renko.DataRows[0].Open())
+	assert.True(t, math.Abs(renko.DataRows[0].Close()-(103.0+box)) < 1e-9)
+	assert.Equal(t, 5.0, renko.DataRows[0].Get(CHART_SOURCE))
+	// the volume starts at row 3
+	assert.Equal(t, 300.0, renko.DataRows[0].Get(VOLUME))
+	assert.Equal(t, -1.0, renko.DataRows[1].Get(CHART_DIRECTION))
+	assert.Equal(t, 103.0, renko.DataRows[1].Open())
+	assert.True(t, math.Abs(renko.DataRows[1].Close()-(103.0-box)) < 1e-9)
+	assert.Equal(t, 9.0, renko.DataRows[1].Get(CHART_SOURCE))
+}
+
+func TestPointAndFigure(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 104, 103, 102, 101}, 0.5))
+	pnf := PointAndFigure(mat, 1.0, 3)
+	assert.Equal(t, 2, pnf.Rows)
+	assert.Equal(t, 100.0, pnf.DataRows[0].Open())
+	assert.Equal(t, 105.0, pnf.DataRows[0].Close())
+	assert.Equal(t, 6, PointAndFigureBoxes(&pnf.DataRows[0], 1.0))
+	assert.Equal(t, 104.0, pnf.DataRows[1].Open())
+	assert.Equal(t, 101.0, pnf.DataRows[1].Close())
+	// the X column is complete when the close reverses 3 boxes on row 8
+	assert.Equal(t, mat.DataRows[8].Key+"#1", pnf.DataRows[0].Key)
+	assert.Equal(t, 8.0, pnf.DataRows[0].Get(CHART_SOURCE))
+	assert.Equal(t, 9.0, pnf.DataRows[1].Get(CHART_SOURCE))
+}
+
+func TestKagi(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 104, 103, 102, 101}, 0.5))
+	kagi := Kagi(mat, 2.0, false)
+	assert.Equal(t, 2, kagi.Rows)
+	assert.Equal(t, 105.0, kagi.DataRows[0].Close())
+	assert.Equal(t, 105.0, kagi.DataRows[1].Open())
+	assert.Equal(t, 101.0, kagi.DataRows[1].Close())
+	assert.Equal(t, -1.0, kagi.DataRows[1].Get(CHART_DIRECTION))
+	// the first line is known when the close reverses by 2 on row 7
+	assert.Equal(t, 7.0, kagi.DataRows[0].Get(CHART_SOURCE))
+	assert.Equal(t, mat.DataRows[7].Key+"#1", kagi.DataRows[0].Key)
+	assert.Equal(t, 9.0, kagi.DataRows[1].Get(CHART_SOURCE))
+}
+
+func TestRangeBars(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 104, 103, 102, 101}, 0.5))
+	bars := RangeBars(mat, 2.0)
+	assert.NotEqual(t, 0, bars.Rows)
+	for _, r := range bars.DataRows {
+		assert.Equal(t, 2.0, r.High()-r.Low())
+	}
+}
@@ -0,0 +1,358 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// -----------------------------------------------------------------------
+//
+//	K-Means clustering
+//
+// -----------------------------------------------------------------------
+type FeatureScaling int
+
+const (
+	NO_SCALING FeatureScaling = iota
+	MIN_MAX_SCALING
+	Z_SCORE_SCALING
+)
+
+var (
+	ErrInvalidK       = errors.New("k must be > 0")
+	ErrNoFeatures     = errors.New("no features to cluster")
+	ErrNotEnoughRows  = errors.New("less rows than clusters")
+	ErrFeatureMissing = errors.New("points have different number of features")
+)
+
+type ClusterConfig struct {
+	K             int
+	MaxIterations int
+	// Tolerance stops the iterations when no centroid moves more than this (in scaled units)
+	Tolerance float64
+	// Seed makes the k-means++ initialization reproducible
+	Seed    int64
+	Scaling FeatureScaling
+}
+
+func DefaultClusterConfig(k int) ClusterConfig {
+	return ClusterConfig{
+		K:             k,
+		MaxIterations: 100,
+		Tolerance:     1e-6,
+		Seed:          1,
+		Scaling:       Z_SCORE_SCALING,
+	}
+}
+
+type ClusterResult struct {
+	// Centroids are in the units of the input
+	Centroids   [][]float64
+	Assignments []int
+	// Inertia is the sum of the squared distances to the centroids (scaled units)
+	Inertia    float64
+	Iterations int
+	Converged  bool
+	// the scaled points
+	points    [][]float64
+	centroids [][]float64
+}
+
+func squaredDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// scaleFeatures returns the scaled copy of the points and a function to
+// convert scaled values back
+func scaleFeatures(points [][]float64, scaling FeatureScaling) ([][]float64, func([]float64) []float64) {
+	dims := len(points[0])
+	offset := make([]float64, dims)
+	factor := make([]float64, dims)
+	for d := 0; d < dims; d++ {
+		factor[d] = 1.0
+		if scaling == NO_SCALING {
+			continue
+		}
+		min := math.MaxFloat64
+		max := -math.MaxFloat64
+		sum := 0.0
+		for _, p := range points {
+			min = math.Min(min, p[d])
+			max = math.Max(max, p[d])
+			sum += p[d]
+		}
+		if scaling == MIN_MAX_SCALING {
+			offset[d] = min
+			if max > min {
+				factor[d] = max - min
+			}
+		} else {
+			mean := sum / float64(len(points))
+			variance := 0.0
+			for _, p := range points {
+				variance += (p[d] - mean) * (p[d] - mean)
+			}
+			offset[d] = mean
+			if sd := math.Sqrt(variance / float64(len(points))); sd > 0.0 {
+				factor[d] = sd
+			}
+		}
+	}
+	ret := make([][]float64, len(points))
+	for i, p := range points {
+		ret[i] = make([]float64, dims)
+		for d := range p {
+			ret[i][d] = (p[d] - offset[d]) / factor[d]
+		}
+	}
+	unscale := func(v []float64) []float64 {
+		r := make([]float64, dims)
+		for d := range v {
+			r[d] = v[d]*factor[d] + offset[d]
+		}
+		return r
+	}
+	return ret, unscale
+}
+
+// initCentroids uses k-means++: the first centroid is random, every next one
+// is picked with a probability proportional to the squared distance to the
+// nearest centroid
+func initCentroids(points [][]float64, k int, rnd *rand.Rand) [][]float64 {
+	ret := make([][]float64, 0, k)
+	first := points[rnd.Intn(len(points))]
+	ret = append(ret, append([]float64{}, first...))
+	dist := make([]float64, len(points))
+	for len(ret) < k {
+		sum := 0.0
+		for i, p := range points {
+			dist[i] = squaredDistance(p, ret[0])
+			for _, c := range ret[1:] {
+				dist[i] = math.Min(dist[i], squaredDistance(p, c))
+			}
+			sum += dist[i]
+		}
+		idx := 0
+		if sum > 0.0 {
+			target := rnd.Float64() * sum
+			for idx < len(points)-1 && target >= dist[idx] {
+				target -= dist[idx]
+				idx++
+			}
+		} else {
+			idx = rnd.Intn(len(points))
+		}
+		ret = append(ret, append([]float64{}, points[idx]...))
+	}
+	return ret
+}
+
+func nearestCentroid(p []float64, centroids [][]float64) (int, float64) {
+	ret := 0
+	min := squaredDistance(p, centroids[0])
+	for c := 1; c < len(centroids); c++ {
+		if d := squaredDistance(p, centroids[c]); d < min {
+			min = d
+			ret = c
+		}
+	}
+	return ret, min
+}
+
+// Cluster runs k-means on the points. Every point must have the same number
+// of features.
+func Cluster(points [][]float64, cfg ClusterConfig) (*ClusterResult, error) {
+	if cfg.K <= 0 {
+		return nil, ErrInvalidK
+	}
+	if len(points) == 0 || len(points[0]) == 0 {
+		return nil, ErrNoFeatures
+	}
+	if len(points) < cfg.K {
+		return nil, ErrNotEnoughRows
+	}
+	for _, p := range points {
+		if len(p) != len(points[0]) {
+			return nil, ErrFeatureMissing
+		}
+	}
+	if cfg.MaxIterations <= 0 {
+		cfg.MaxIterations = 100
+	}
+	scaled, unscale := scaleFeatures(points, cfg.Scaling)
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	centroids := initCentroids(scaled, cfg.K, rnd)
+	ret := &ClusterResult{
+		Assignments: make([]int, len(points)),
+		points:      scaled,
+	}
+	dims := len(scaled[0])
+	for ret.Iterations < cfg.MaxIterations {
+		ret.Iterations++
+		for i, p := range scaled {
+			ret.Assignments[i], _ = nearestCentroid(p, centroids)
+		}
+		sums := make([][]float64, cfg.K)
+		counts := make([]int, cfg.K)
+		for c := range sums {
+			sums[c] = make([]float64, dims)
+		}
+		for i, c := range ret.Assignments {
+			counts[c]++
+			for d, v := range scaled[i] {
+				sums[c][d] += v
+			}
+		}
+		moved := 0.0
+		for c := range centroids {
+			next := sums[c]
+			if counts[c] == 0 {
+				// an empty cluster takes the point farthest from its centroid
+				far := 0
+				fd := -1.0
+				for i, p := range scaled {
+					if d := squaredDistance(p, centroids[ret.Assignments[i]]); d > fd {
+						fd = d
+						far = i
+					}
+				}
+				next = append([]float64{}, scaled[far]...)
+			} else {
+				for d := range next {
+					next[d] /= float64(counts[c])
+				}
+			}
+			moved = math.Max(moved, math.Sqrt(squaredDistance(next, centroids[c])))
+			centroids[c] = next
+		}
+		if moved <= cfg.Tolerance {
+			ret.Converged = true
+			break
+		}
+	}
+	ret.Inertia = 0.0
+	for i, p := range scaled {
+		c, d := nearestCentroid(p, centroids)
+		ret.Assignments[i] = c
+		ret.Inertia += d
+	}
+	ret.centroids = centroids
+	ret.Centroids = make([][]float64, len(centroids))
+	for c := range centroids {
+		ret.Centroids[c] = unscale(centroids[c])
+	}
+	return ret, nil
+}
+
+// ClusterMatrix clusters the rows of the matrix using the given columns as features
+func ClusterMatrix(m *Matrix, fields []int, cfg ClusterConfig) (*ClusterResult, error) {
+	if len(fields) == 0 {
+		return nil, ErrNoFeatures
+	}
+	for _, f := range fields {
+		if f < 0 || f >= m.Cols {
+			return nil, fmt.Errorf("invalid column %d", f)
+		}
+	}
+	points := make([][]float64, m.CandleRows())
+	for i := range points {
+		points[i] = make([]float64, len(fields))
+		for j, f := range fields {
+			points[i][j] = m.DataRows[i].Get(f)
+		}
+	}
+	return Cluster(points, cfg)
+}
+
+// Silhouette returns the mean silhouette coefficient (-1 to 1) of all points.
+// Higher values mean better separated clusters.
+func (cr *ClusterResult) Silhouette() float64 {
+	n := len(cr.points)
+	k := len(cr.centroids)
+	if n < 2 || k < 2 {
+		return 0.0
+	}
+	total := 0.0
+	for i, p := range cr.points {
+		sums := make([]float64, k)
+		counts := make([]int, k)
+		for j, q := range cr.points {
+			if i != j {
+				sums[cr.Assignments[j]] += math.Sqrt(squaredDistance(p, q))
+				counts[cr.Assignments[j]]++
+			}
+		}
+		own := cr.Assignments[i]
+		if counts[own] == 0 {
+			continue
+		}
+		a := sums[own] / float64(counts[own])
+		b := math.MaxFloat64
+		for c := 0; c < k; c++ {
+			if c != own && counts[c] > 0 {
+				b = math.Min(b, sums[c]/float64(counts[c]))
+			}
+		}
+		if b == math.MaxFloat64 {
+			continue
+		}
+		if s := math.Max(a, b); s > 0.0 {
+			total += (b - a) / s
+		}
+	}
+	return total / float64(n)
+}
+
+// AddClusterColumn stores the assignment of every row in a new column
+func AddClusterColumn(m *Matrix, cr *ClusterResult) int {
+	ret := m.AddNamedColumn("Cluster")
+	for i := 0; i < len(cr.Assignments) && i < m.Rows; i++ {
+		m.DataRows[i].Set(ret, float64(cr.Assignments[i]))
+	}
+	return ret
+}
+
+// Elbow clusters the points for k = 1 to maxK and returns the inertias and the
+// k at the elbow (the point with the largest distance to the line between the
+// first and the last inertia)
+func Elbow(points [][]float64, maxK int, cfg ClusterConfig) (int, []float64, error) {
+	if maxK <= 0 {
+		return 0, nil, ErrInvalidK
+	}
+	inertias := make([]float64, 0, maxK)
+	for k := 1; k <= maxK; k++ {
+		cfg.K = k
+		cr, err := Cluster(points, cfg)
+		if err != nil {
+			return 0, nil, err
+		}
+		inertias = append(inertias, cr.Inertia)
+	}
+	if maxK < 3 {
+		return maxK, inertias, nil
+	}
+	// normalize both axes and take the point farthest below the line from the
+	// first to the last inertia
+	span := inertias[0] - inertias[maxK-1]
+	if span <= 0.0 {
+		return 1, inertias, nil
+	}
+	best := 1
+	bd := 0.0
+	for i, y := range inertias {
+		x := float64(i) / float64(maxK-1)
+		d := 1.0 - x - (y-inertias[maxK-1])/span
+		if d > bd {
+			bd = d
+			best = i + 1
+		}
+	}
+	return best, inertias, nil
+}
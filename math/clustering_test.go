@@ -0,0 +1,46 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func clusterPoints() [][]float64 {
+	ret := make([][]float64, 0)
+	for _, c := range [][]float64{{0, 0}, {10, 100}, {20, 0}} {
+		for i := 0; i < 5; i++ {
+			ret = append(ret, []float64{c[0] + float64(i%2), c[1] + float64(i)})
+		}
+	}
+	return ret
+}
+
+func TestCluster(t *testing.T) {
+	points := clusterPoints()
+	cr, err := Cluster(points, DefaultClusterConfig(3))
+	assert.NoError(t, err)
+	assert.True(t, cr.Converged)
+	assert.Equal(t, cr.Assignments[0], cr.Assignments[4])
+	assert.NotEqual(t, cr.Assignments[0], cr.Assignments[5])
+	assert.NotEqual(t, cr.Assignments[5], cr.Assignments[10])
+	assert.True(t, cr.Silhouette() > 0.8)
+	// same seed same result
+	other, _ := Cluster(points, DefaultClusterConfig(3))
+	assert.Equal(t, cr.Assignments, other.Assignments)
+	k, inertias, err := Elbow(points, 6, DefaultClusterConfig(1))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(inertias))
+	assert.Equal(t, 3, k)
+}
+
+func TestClusterErrors(t *testing.T) {
+	_, err := Cluster(clusterPoints(), DefaultClusterConfig(0))
+	assert.Equal(t, ErrInvalidK, err)
+	_, err = Cluster(clusterPoints()[:2], DefaultClusterConfig(3))
+	assert.Equal(t, ErrNotEnoughRows, err)
+	c, a, err := KMeans(NewCandleMatrix(), 3, 10)
+	assert.Equal(t, ErrNoFeatures, err)
+	assert.Equal(t, 0, len(c))
+	assert.Equal(t, 0, len(a))
+}
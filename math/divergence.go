@@ -0,0 +1,228 @@
+package math
+
+import (
+	"math"
+	"sort"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Divergence engine
+//
+// -----------------------------------------------------------------------
+type DivergenceType int
+
+const (
+	NO_DIVERGENCE DivergenceType = iota
+	// price makes a lower low, the oscillator a higher low
+	REGULAR_BULLISH
+	// price makes a higher high, the oscillator a lower high
+	REGULAR_BEARISH
+	// price makes a higher low, the oscillator a lower low
+	HIDDEN_BULLISH
+	// price makes a lower high, the oscillator a higher high
+	HIDDEN_BEARISH
+)
+
+func (dt DivergenceType) String() string {
+	switch dt {
+	case REGULAR_BULLISH:
+		return "Regular Bullish"
+	case REGULAR_BEARISH:
+		return "Regular Bearish"
+	case HIDDEN_BULLISH:
+		return "Hidden Bullish"
+	case HIDDEN_BEARISH:
+		return "Hidden Bearish"
+	}
+	return "-"
+}
+
+// Direction returns 1 for bullish and -1 for bearish divergences
+func (dt DivergenceType) Direction() int {
+	switch dt {
+	case REGULAR_BULLISH, HIDDEN_BULLISH:
+		return 1
+	case REGULAR_BEARISH, HIDDEN_BEARISH:
+		return -1
+	}
+	return 0
+}
+
+type DivergenceConfig struct {
+	// PriceHigh and PriceLow are the fields used for the price pivots
+	PriceHigh int
+	PriceLow  int
+	// Lookback is the number of bars on each side of a pivot
+	Lookback int
+	// MinDistance and MaxDistance limit the bars between the two price pivots
+	MinDistance int
+	MaxDistance int
+	// MatchWindow is the maximum distance in bars between a price pivot and
+	// the matching oscillator pivot
+	MatchWindow int
+	Regular     bool
+	Hidden      bool
+}
+
+func DefaultDivergenceConfig() DivergenceConfig {
+	return DivergenceConfig{
+		PriceHigh:   HIGH,
+		PriceLow:    LOW,
+		Lookback:    3,
+		MinDistance: 5,
+		MaxDistance: 60,
+		MatchWindow: 3,
+		Regular:     true,
+		Hidden:      true,
+	}
+}
+
+// DivergenceEvent contains the pair of price pivots and the pair of oscillator
+// pivots. Index is the row where the second pivots are confirmed.
+type DivergenceEvent struct {
+	Type       DivergenceType
+	Price      [2]SwingPoint
+	Oscillator [2]SwingPoint
+	Index      int
+	Key        string
+	// Strength is the price change in percent plus the oscillator change in
+	// percent of the oscillator range. Both move in opposite directions so a
+	// bigger value means a wider divergence.
+	Strength float64
+}
+
+func matchPivot(pivots SwingPoints, index, window int) int {
+	ret := -1
+	best := window + 1
+	for i, p := range pivots {
+		d := p.Index - index
+		if d < 0 {
+			d = -d
+		}
+		if d < best {
+			best = d
+			ret = i
+		}
+	}
+	return ret
+}
+
+func classifyDivergence(base SwingPointType, price, osc float64) DivergenceType {
+	if base == Low {
+		if price < 0.0 && osc > 0.0 {
+			return REGULAR_BULLISH
+		}
+		if price > 0.0 && osc < 0.0 {
+			return HIDDEN_BULLISH
+		}
+	} else {
+		if price > 0.0 && osc < 0.0 {
+			return REGULAR_BEARISH
+		}
+		if price < 0.0 && osc > 0.0 {
+			return HIDDEN_BEARISH
+		}
+	}
+	return NO_DIVERGENCE
+}
+
+// FindDivergences matches the price pivots with the pivots of the oscillator in
+// field (RSI, MACD, CCI, OBV or any other column)
+func FindDivergences(m *Matrix, field int, cfg DivergenceConfig) []DivergenceEvent {
+	ret := make([]DivergenceEvent, 0)
+	rows := m.CandleRows()
+	if rows == 0 {
+		return ret
+	}
+	// skip the warm-up rows of the oscillator which are still zero
+	start := 0
+	for start < rows && m.DataRows[start].Get(field) == 0.0 {
+		start++
+	}
+	oscMin, oscMax := math.MaxFloat64, -math.MaxFloat64
+	for i := start; i < rows; i++ {
+		v := m.DataRows[i].Get(field)
+		oscMin = math.Min(oscMin, v)
+		oscMax = math.Max(oscMax, v)
+	}
+	oscRange := 0.0
+	if start < rows {
+		oscRange = oscMax - oscMin
+	}
+	prices := m.FindPivots(cfg.PriceHigh, cfg.PriceLow, cfg.Lookback)
+	// pivots next to the warm-up rows are compared against the zeros
+	var oscillator SwingPoints
+	for _, sp := range m.FindPivots(field, field, cfg.Lookback) {
+		if sp.Index >= start+cfg.Lookback {
+			oscillator = append(oscillator, sp)
+		}
+	}
+	for _, base := range []SwingPointType{Low, High} {
+		pp := prices.FilterByType(base)
+		op := oscillator.FilterByType(base)
+		for i := 1; i < len(pp); i++ {
+			p1 := pp[i-1]
+			p2 := pp[i]
+			dist := p2.Index - p1.Index
+			if dist < cfg.MinDistance || dist > cfg.MaxDistance {
+				continue
+			}
+			o1 := matchPivot(op, p1.Index, cfg.MatchWindow)
+			o2 := matchPivot(op, p2.Index, cfg.MatchWindow)
+			if o1 == -1 || o2 == -1 || o1 == o2 {
+				continue
+			}
+			dp := p2.Value - p1.Value
+			do := op[o2].Value - op[o1].Value
+			dt := classifyDivergence(base, dp, do)
+			if dt == NO_DIVERGENCE {
+				continue
+			}
+			if (!cfg.Regular && (dt == REGULAR_BULLISH || dt == REGULAR_BEARISH)) || (!cfg.Hidden && (dt == HIDDEN_BULLISH || dt == HIDDEN_BEARISH)) {
+				continue
+			}
+			idx := min(max(p2.Index, op[o2].Index)+cfg.Lookback, rows-1)
+			de := DivergenceEvent{
+				Type:       dt,
+				Price:      [2]SwingPoint{p1, p2},
+				Oscillator: [2]SwingPoint{op[o1], op[o2]},
+				Index:      idx,
+				Key:        m.DataRows[idx].Key,
+			}
+			if p1.Value != 0.0 {
+				de.Strength = math.Abs(dp / p1.Value * 100.0)
+			}
+			if oscRange > 0.0 {
+				de.Strength += math.Abs(do / oscRange * 100.0)
+			}
+			ret = append(ret, de)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Index < ret[j].Index
+	})
+	return ret
+}
+
+// Divergences adds the divergences of the oscillator in field. DivType is 1 for
+// regular bullish, -1 for regular bearish, 2 for hidden bullish and -2 for
+// hidden bearish divergences on the row where they are confirmed.
+func Divergences(m *Matrix, field int, cfg DivergenceConfig) int {
+	events := FindDivergences(m, field, cfg)
+	ret := m.AddNamedColumn("DivType")
+	strength := m.AddNamedColumn("DivStrength")
+	// 0 = Type 1 = Strength
+	for _, e := range events {
+		v := float64(e.Type.Direction())
+		if e.Type == HIDDEN_BULLISH || e.Type == HIDDEN_BEARISH {
+			v *= 2.0
+		}
+		r := &m.DataRows[e.Index]
+		if e.Strength > r.Get(strength) {
+			r.Set(ret, v)
+			r.Set(strength, e.Strength)
+		}
+	}
+	return ret
+}
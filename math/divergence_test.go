@@ -0,0 +1,55 @@
+package math
+
+import (
+	"math"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindDivergences(t *testing.T) {
+	mat := candleMatrix(closeBars(pathCloses([]float64{110, 100, 108, 95, 105, 112}, 6), 0.5))
+	osc := mat.AddNamedColumn("OSC")
+	for i := range mat.DataRows {
+		v := mat.DataRows[i].Close()
+		if i >= 12 {
+			v += 12.0
+		}
+		if i < 3 {
+			// warm-up rows
+			v = 0.0
+		}
+		mat.DataRows[i].Set(osc, v)
+	}
+	events := FindDivergences(mat, osc, DefaultDivergenceConfig())
+	assert.Equal(t, 1, len(events))
+	e := events[0]
+	assert.Equal(t, REGULAR_BULLISH, e.Type)
+	assert.Equal(t, 6, e.Price[0].Index)
+	assert.Equal(t, 18, e.Price[1].Index)
+	assert.Equal(t, 18, e.Oscillator[1].Index)
+	assert.Equal(t, 21, e.Index)
+	// the oscillator ranges from 100 at row 6 to 122.83 at row 29
+	strength := 5.0/99.5*100.0 + 7.0/(105.0+5.0/6.0*7.0+12.0-100.0)*100.0
+	assert.True(t, math.Abs(e.Strength-strength) < 1e-9)
+	cfg := DefaultDivergenceConfig()
+	cfg.Regular = false
+	assert.Equal(t, 0, len(FindDivergences(mat, osc, cfg)))
+	div := Divergences(mat, osc, DefaultDivergenceConfig())
+	assert.Equal(t, 1.0, mat.DataRows[21].Get(div))
+}
+
+func TestFindDivergencesNegativeOscillator(t *testing.T) {
+	mat := candleMatrix(closeBars(pathCloses([]float64{110, 100, 108, 95, 105, 112}, 6), 0.5))
+	osc := mat.AddNamedColumn("OSC")
+	// zero during the warm-up, then a negative first value and a low at row 18
+	for i := 6; i < mat.Rows; i++ {
+		v := -4.0 + 0.2*math.Abs(float64(i-18))
+		if i == 6 {
+			v = -6.0
+		}
+		mat.DataRows[i].Set(osc, v)
+	}
+	// the first value is no pivot low against the zeros before it
+	assert.Equal(t, 0, len(FindDivergences(mat, osc, DefaultDivergenceConfig())))
+}
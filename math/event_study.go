@@ -0,0 +1,328 @@
+package math
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Event study
+//
+// -----------------------------------------------------------------------
+// EventSignal is a signal on the close of the row Index. Direction is 1 for
+// long and -1 for short signals.
+type EventSignal struct {
+	Index     int
+	Direction int
+}
+
+// SignalsFromColumn uses every row with a value != 0. The sign of the value is the direction.
+func SignalsFromColumn(m *Matrix, field int) []EventSignal {
+	return SignalsFromFunc(m, func(i int, row *MatrixRow) int {
+		v := row.Get(field)
+		if v > 0.0 {
+			return 1
+		} else if v < 0.0 {
+			return -1
+		}
+		return 0
+	})
+}
+
+// SignalsFromFunc calls the function for every row. A return value != 0 is a signal.
+func SignalsFromFunc(m *Matrix, fn func(index int, row *MatrixRow) int) []EventSignal {
+	ret := make([]EventSignal, 0)
+	for i := 0; i < m.CandleRows(); i++ {
+		if d := fn(i, &m.DataRows[i]); d != 0 {
+			ret = append(ret, EventSignal{Index: i, Direction: d})
+		}
+	}
+	return ret
+}
+
+// SignalsFromCrossUp creates long signals when the first column crosses above the second
+func SignalsFromCrossUp(m *Matrix, first, second int) []EventSignal {
+	return SignalsFromFunc(m, func(i int, row *MatrixRow) int {
+		if m.CrossUp(first, second, i) {
+			return 1
+		}
+		return 0
+	})
+}
+
+// SignalsFromCandlePatterns uses the direction of the pattern (neutral patterns are long)
+func SignalsFromCandlePatterns(matches []CandlePatternMatch) []EventSignal {
+	ret := make([]EventSignal, 0, len(matches))
+	for _, cm := range matches {
+		d := cm.Direction
+		if d == 0 {
+			d = 1
+		}
+		ret = append(ret, EventSignal{Index: cm.Index, Direction: d})
+	}
+	return ret
+}
+
+// SignalsFromFairValueGaps fires on the row where the gap is known
+func SignalsFromFairValueGaps(gaps []FairValueGap) []EventSignal {
+	ret := make([]EventSignal, 0, len(gaps))
+	for _, g := range gaps {
+		ret = append(ret, EventSignal{Index: g.Start(), Direction: g.Type})
+	}
+	return ret
+}
+
+// SignalsFromOrderBlockTouches fires on the first candle after the order block
+// that trades into it. A touch that closes through the block is no signal.
+func SignalsFromOrderBlockTouches(m *Matrix, blocks []OrderBlock) []EventSignal {
+	ret := make([]EventSignal, 0)
+	for _, ob := range blocks {
+		if ob.FirstTouch != -1 && ob.FirstTouch < m.CandleRows() && ob.FirstTouch != ob.BreakerIndex {
+			ret = append(ret, EventSignal{Index: ob.FirstTouch, Direction: ob.InitialType()})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Index < ret[j].Index
+	})
+	return ret
+}
+
+type EventStudyConfig struct {
+	// Horizons in bars for the forward returns
+	Horizons  []int
+	ATRPeriod int
+	// Window for MFE and MAE in bars (0 = largest horizon)
+	Window int
+	// Baseline is the number of random entries
+	Baseline int
+	Seed     int64
+}
+
+func DefaultEventStudyConfig() EventStudyConfig {
+	return EventStudyConfig{
+		Horizons:  []int{1, 5, 10, 20},
+		ATRPeriod: 14,
+		Baseline:  1000,
+		Seed:      1,
+	}
+}
+
+func (cfg EventStudyConfig) window() int {
+	if cfg.Window > 0 {
+		return cfg.Window
+	}
+	ret := 0
+	for _, h := range cfg.Horizons {
+		ret = max(ret, h)
+	}
+	return ret
+}
+
+type EventOutcome struct {
+	// Matrix is the position of the matrix in RunEventStudies
+	Matrix    int
+	Index     int
+	Key       string
+	Direction int
+	// Returns in percent for each horizon in the direction of the signal. NaN
+	// if there are not enough bars.
+	Returns []float64
+	// MFE and MAE are the maximum favorable and adverse excursion in ATR units
+	MFE float64
+	MAE float64
+}
+
+type HorizonStats struct {
+	Horizon int
+	Count   int
+	Mean    float64
+	Median  float64
+	StdDev  float64
+	HitRate float64
+	// Distribution contains the 10, 25, 50, 75 and 90 percent quantiles
+	Distribution [5]float64
+	// baseline of random entries and the t-stat of the difference (Welch)
+	BaselineMean   float64
+	BaselineStdDev float64
+	TStat          float64
+}
+
+type EventStudy struct {
+	Events   []EventOutcome
+	Horizons []HorizonStats
+	MeanMFE  float64
+	MeanMAE  float64
+}
+
+func forwardReturn(m *Matrix, index, horizon, dir int) float64 {
+	if index+horizon >= m.CandleRows() || m.DataRows[index].Close() == 0.0 {
+		return math.NaN()
+	}
+	return (m.DataRows[index+horizon].Close()/m.DataRows[index].Close() - 1.0) * 100.0 * float64(dir)
+}
+
+func excursion(m *Matrix, index, window, dir, atr int) (float64, float64) {
+	a := m.DataRows[index].Get(atr)
+	if a == 0.0 || index+1 >= m.CandleRows() {
+		// no later bars to measure
+		return math.NaN(), math.NaN()
+	}
+	entry := m.DataRows[index].Close()
+	mfe := 0.0
+	mae := 0.0
+	for i := index + 1; i <= index+window && i < m.CandleRows(); i++ {
+		c := &m.DataRows[i]
+		if dir == 1 {
+			mfe = math.Max(mfe, c.High()-entry)
+			mae = math.Max(mae, entry-c.Low())
+		} else {
+			mfe = math.Max(mfe, entry-c.Low())
+			mae = math.Max(mae, c.High()-entry)
+		}
+	}
+	return mfe / a, mae / a
+}
+
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0.0
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0.0, 0.0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0.0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)-1))
+}
+
+// welchTStat returns the t-stat of the difference of the means of both
+// samples with unequal variances or 0 if it is not defined
+func welchTStat(values, baseline []float64) float64 {
+	if len(values) == 0 || len(baseline) == 0 {
+		return 0.0
+	}
+	mean, sd := meanStdDev(values)
+	bm, bsd := meanStdDev(baseline)
+	se := math.Sqrt(sd*sd/float64(len(values)) + bsd*bsd/float64(len(baseline)))
+	if se == 0.0 {
+		return 0.0
+	}
+	return (mean - bm) / se
+}
+
+// RunEventStudy measures the outcome of the signals on one matrix
+func RunEventStudy(m *Matrix, signals []EventSignal, cfg EventStudyConfig) *EventStudy {
+	return RunEventStudies([]*Matrix{m}, [][]EventSignal{signals}, cfg)
+}
+
+// RunEventStudies pools the signals of many matrices. signals[i] belongs to matrices[i].
+func RunEventStudies(matrices []*Matrix, signals [][]EventSignal, cfg EventStudyConfig) *EventStudy {
+	ret := &EventStudy{
+		Events: make([]EventOutcome, 0),
+	}
+	window := cfg.window()
+	baseline := make([][]float64, len(cfg.Horizons))
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	directions := make([]int, 0)
+	for mi, m := range matrices {
+		if mi >= len(signals) || m.CandleRows() == 0 {
+			continue
+		}
+		atr := ATR(m, cfg.ATRPeriod)
+		for _, s := range signals[mi] {
+			if s.Index < 0 || s.Index >= m.CandleRows() || s.Direction == 0 {
+				continue
+			}
+			eo := EventOutcome{
+				Matrix:    mi,
+				Index:     s.Index,
+				Key:       m.DataRows[s.Index].Key,
+				Direction: s.Direction,
+				Returns:   make([]float64, len(cfg.Horizons)),
+			}
+			for hi, h := range cfg.Horizons {
+				eo.Returns[hi] = forwardReturn(m, s.Index, h, s.Direction)
+			}
+			eo.MFE, eo.MAE = excursion(m, s.Index, window, s.Direction, atr)
+			ret.Events = append(ret.Events, eo)
+			directions = append(directions, s.Direction)
+		}
+		m.RemoveColumn()
+	}
+	// random entries spread over all matrices with the same mix of long and short
+	if len(directions) > 0 {
+		for i := 0; i < cfg.Baseline; i++ {
+			m := matrices[rnd.Intn(len(matrices))]
+			if m.CandleRows() == 0 {
+				continue
+			}
+			idx := rnd.Intn(m.CandleRows())
+			dir := directions[rnd.Intn(len(directions))]
+			for hi, h := range cfg.Horizons {
+				if r := forwardReturn(m, idx, h, dir); !math.IsNaN(r) {
+					baseline[hi] = append(baseline[hi], r)
+				}
+			}
+		}
+	}
+	cnt := 0
+	for _, e := range ret.Events {
+		if !math.IsNaN(e.MFE) {
+			ret.MeanMFE += e.MFE
+			ret.MeanMAE += e.MAE
+			cnt++
+		}
+	}
+	if cnt > 0 {
+		ret.MeanMFE /= float64(cnt)
+		ret.MeanMAE /= float64(cnt)
+	}
+	for hi, h := range cfg.Horizons {
+		values := make([]float64, 0)
+		hits := 0
+		for _, e := range ret.Events {
+			if r := e.Returns[hi]; !math.IsNaN(r) {
+				values = append(values, r)
+				if r > 0.0 {
+					hits++
+				}
+			}
+		}
+		hs := HorizonStats{
+			Horizon: h,
+			Count:   len(values),
+		}
+		hs.Mean, hs.StdDev = meanStdDev(values)
+		hs.BaselineMean, hs.BaselineStdDev = meanStdDev(baseline[hi])
+		if len(values) > 0 {
+			sort.Float64s(values)
+			hs.Median = quantile(values, 0.5)
+			hs.HitRate = float64(hits) / float64(len(values))
+			for qi, q := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+				hs.Distribution[qi] = quantile(values, q)
+			}
+		}
+		hs.TStat = welchTStat(values, baseline[hi])
+		ret.Horizons = append(ret.Horizons, hs)
+	}
+	return ret
+}
@@ -0,0 +1,76 @@
+package math
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestRunEventStudy(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		c := 100.0 + float64(i)
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(OPEN, c-0.5).Set(HIGH, c+1).Set(LOW, c-1).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, 100)
+	}
+	cols := mat.Cols
+	cfg := DefaultEventStudyConfig()
+	cfg.Horizons = []int{1, 5}
+	signals := []EventSignal{{Index: 10, Direction: 1}, {Index: 20, Direction: -1}, {Index: 38, Direction: 1}}
+	es := RunEventStudy(mat, signals, cfg)
+	assert.Equal(t, cols, mat.Cols)
+	assert.Equal(t, 3, len(es.Events))
+	assert.True(t, math.Abs(es.Events[0].Returns[0]-100.0/110.0) < 1e-9)
+	assert.True(t, es.Events[1].Returns[1] < 0.0)
+	assert.True(t, math.IsNaN(es.Events[2].Returns[1]))
+	assert.Equal(t, 3, es.Horizons[0].Count)
+	assert.Equal(t, 2, es.Horizons[1].Count)
+	assert.Equal(t, 0.5, es.Horizons[1].HitRate)
+	assert.True(t, es.Events[0].MFE > es.Events[0].MAE)
+}
+
+func TestEventStudyLastCandle(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		c := 100.0 + float64(i)
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(OPEN, c-0.5).Set(HIGH, c+1).Set(LOW, c-1).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, 100)
+	}
+	cfg := DefaultEventStudyConfig()
+	cfg.Horizons = []int{1, 5}
+	es := RunEventStudy(mat, []EventSignal{{Index: 10, Direction: 1}, {Index: 39, Direction: 1}}, cfg)
+	// the signal on the last candle has no excursion and does not pull the means to 0
+	assert.True(t, math.IsNaN(es.Events[1].MFE))
+	assert.True(t, math.IsNaN(es.Events[1].MAE))
+	assert.Equal(t, es.Events[0].MFE, es.MeanMFE)
+	assert.Equal(t, es.Events[0].MAE, es.MeanMAE)
+}
+
+func TestEventStudyBaseline(t *testing.T) {
+	// every close is 1% above the one before so every long entry returns 1% per bar
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := 100.0
+	for i := 0; i < 30; i++ {
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(OPEN, c).Set(HIGH, c).Set(LOW, c).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, 100)
+		c *= 1.01
+	}
+	cfg := DefaultEventStudyConfig()
+	cfg.Horizons = []int{1, 2}
+	es := RunEventStudy(mat, []EventSignal{{Index: 5, Direction: 1}, {Index: 15, Direction: 1}}, cfg)
+	assert.True(t, math.Abs(es.Horizons[0].BaselineMean-1.0) < 1e-9)
+	assert.True(t, math.Abs(es.Horizons[1].BaselineMean-2.01) < 1e-9)
+	assert.True(t, es.Horizons[0].BaselineStdDev < 1e-9)
+	assert.True(t, math.Abs(es.Horizons[0].Mean-1.0) < 1e-9)
+}
+
+func TestWelchTStat(t *testing.T) {
+	// mean 2 and variance 1 against mean 0.5 and variance 1/3:
+	// (2 - 0.5) / sqrt(1/3 + 1/12) = 1.5 * sqrt(12/5)
+	ts := welchTStat([]float64{1, 2, 3}, []float64{0, 0, 1, 1})
+	assert.True(t, math.Abs(ts-1.5*math.Sqrt(12.0/5.0)) < 1e-9)
+	assert.Equal(t, 0.0, welchTStat([]float64{1, 1}, []float64{1, 1}))
+	assert.Equal(t, 0.0, welchTStat(nil, []float64{1, 2}))
+}
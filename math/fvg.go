@@ -1,265 +1,336 @@
 package math
 
 import (
-	"fmt"
 	m "math"
+	"sort"
 )
 
-type Gap struct {
-	Timestamp string
-	Upper     float64
-	Lower     float64
-	Filled    int
-	Index     int
+// -----------------------------------------------------------------------
+//
+//	Fair value gaps
+//
+// -----------------------------------------------------------------------
+type GapState int
+
+const (
+	// price has not traded into the gap yet
+	GAP_OPEN GapState = iota
+	// price traded into the gap but not through it
+	GAP_PARTIAL
+	// price traded through the gap
+	GAP_FILLED
+	// a candle closed beyond the far side of the gap
+	GAP_INVALIDATED
+)
+
+func (gs GapState) String() string {
+	switch gs {
+	case GAP_OPEN:
+		return "Open"
+	case GAP_PARTIAL:
+		return "Partial"
+	case GAP_FILLED:
+		return "Filled"
+	case GAP_INVALIDATED:
+		return "Invalidated"
+	}
+	return "Unknown"
 }
 
+// FairValueGap is the gap between the first and the third candle. Index is the
+// first candle. A bullish gap (Type 1) is below the price and acts as support,
+// a bearish gap (Type -1) as resistance. An inversion gap is an invalidated gap
+// that is now used from the other side and has the opposite type.
 type FairValueGap struct {
 	Timestamp string
 	Upper     float64
 	Lower     float64
-	Filled    int
 	Index     int
 	Type      int
+	Inversion bool
+	State     GapState
+	// Fill in percent of the gap size
+	Fill float64
+	// FirstTouch, FilledIndex and InvalidatedIndex are -1 until it happens
+	FirstTouch       int
+	FilledIndex      int
+	InvalidatedIndex int
+}
+
+func (g FairValueGap) Size() float64 {
+	return g.Upper - g.Lower
 }
 
-func getGapState(lower, upper, low, high float64) int {
-	if high > upper && low < lower {
-		return 1
+func (g FairValueGap) Mid() float64 {
+	return g.Lower + (g.Upper-g.Lower)/2.0
+}
+
+// Start is the row where the gap is known (the third candle or the candle that invalidated the original gap)
+func (g FairValueGap) Start() int {
+	if g.Inversion {
+		return g.Index
+	}
+	return g.Index + 2
+}
+
+// Active is true as long as the gap is neither filled nor invalidated
+func (g FairValueGap) Active() bool {
+	return g.State == GAP_OPEN || g.State == GAP_PARTIAL
+}
+
+// StateAt returns the state the gap had on the close of the given row
+func (g FairValueGap) StateAt(index int) GapState {
+	if g.InvalidatedIndex != -1 && index >= g.InvalidatedIndex {
+		return GAP_INVALIDATED
+	}
+	if g.FilledIndex != -1 && index >= g.FilledIndex {
+		return GAP_FILLED
 	}
-	if high > upper && low > upper {
-		return 0
+	if g.FirstTouch != -1 && index >= g.FirstTouch {
+		return GAP_PARTIAL
+	}
+	return GAP_OPEN
+}
+
+type FVGConfig struct {
+	// the middle candle body must be bigger than BodyMultiplier times the
+	// average body of the last BodyPeriod candles (0 = no check)
+	BodyMultiplier float64
+	BodyPeriod     int
+	// the middle candle must close beyond the first candle
+	RequireClose bool
+	// minimum size of the gap in ATR units (0 = no check)
+	MinATR    float64
+	ATRPeriod int
+	Bullish   bool
+	Bearish   bool
+	// Inversion adds an inversion gap for every invalidated gap
+	Inversion bool
+	// Merge combines overlapping active gaps of the same type
+	Merge bool
+}
+
+func DefaultFVGConfig() FVGConfig {
+	return FVGConfig{
+		BodyMultiplier: 1.5,
+		BodyPeriod:     20,
+		RequireClose:   true,
+		ATRPeriod:      14,
+		Bullish:        true,
+		Bearish:        true,
 	}
-	if high < lower && low < lower {
-		return 0
+}
+
+func averageBodies(candles *Matrix, period int) []float64 {
+	ret := make([]float64, candles.Rows)
+	if period < 1 {
+		period = 1
 	}
-	if high > upper && low > lower {
-		return 2
+	sum := 0.0
+	for i := 0; i < candles.Rows; i++ {
+		c := &candles.DataRows[i]
+		sum += m.Abs(c.Close() - c.Open())
+		if i >= period {
+			p := &candles.DataRows[i-period]
+			sum -= m.Abs(p.Close() - p.Open())
+		}
+		ret[i] = sum / float64(min(i+1, period))
 	}
-	if high < upper && low < lower {
-		return 3
+	return ret
+}
+
+// trackGap walks the candles after the gap and records the lifecycle
+func trackGap(candles *Matrix, g *FairValueGap) {
+	g.State = GAP_OPEN
+	g.Fill = 0.0
+	g.FirstTouch = -1
+	g.FilledIndex = -1
+	g.InvalidatedIndex = -1
+	size := g.Size()
+	if size <= 0.0 {
+		return
 	}
-	if high < upper && low > lower {
-		return 4
+	for i := g.Start() + 1; i < candles.CandleRows(); i++ {
+		c := &candles.DataRows[i]
+		depth := 0.0
+		if g.Type == 1 {
+			depth = g.Upper - c.Low()
+		} else {
+			depth = c.High() - g.Lower
+		}
+		if depth > 0.0 {
+			if g.FirstTouch == -1 {
+				g.FirstTouch = i
+				g.State = GAP_PARTIAL
+			}
+			g.Fill = m.Max(g.Fill, m.Min(depth/size*100.0, 100.0))
+			if depth >= size && g.FilledIndex == -1 {
+				g.FilledIndex = i
+				g.State = GAP_FILLED
+			}
+		}
+		if (g.Type == 1 && c.Close() < g.Lower) || (g.Type == -1 && c.Close() > g.Upper) {
+			g.InvalidatedIndex = i
+			g.State = GAP_INVALIDATED
+			return
+		}
 	}
-	return 0
 }
 
+// MergeGaps combines overlapping active gaps of the same type. The later gap
+// takes over the range of the earlier one which is removed.
 func MergeGaps(gaps []FairValueGap) []FairValueGap {
+	merged := make([]bool, len(gaps))
 	for j := 1; j < len(gaps); j++ {
 		cg := &gaps[j]
-		if cg.Filled == 0 {
-			s := j - 10
-			if s < 0 {
-				s = 0
+		if !cg.Active() {
+			continue
+		}
+		s := max(j-10, 0)
+		for i := s; i < j; i++ {
+			ng := &gaps[i]
+			if merged[i] || !ng.Active() || ng.Type != cg.Type || ng.Inversion != cg.Inversion {
+				continue
 			}
-			for i := s; i < j; i++ {
-				ng := &gaps[i]
-				if ng.Filled == 0 {
-					t := 0
-					if cg.Upper <= ng.Upper && cg.Upper >= ng.Lower && cg.Lower >= ng.Lower {
-						t = 1
-					}
-					if cg.Upper >= ng.Upper && cg.Lower <= ng.Lower {
-						t = 1
-					}
-					if cg.Upper >= ng.Upper && cg.Lower >= ng.Lower && cg.Lower <= ng.Upper {
-						t = 1
-					}
-					if t == 1 {
-						ng.Filled = 1
-						cg.Lower = m.Min(cg.Lower, ng.Lower)
-						cg.Upper = m.Max(cg.Upper, ng.Upper)
-					}
-				}
+			if cg.Lower <= ng.Upper && cg.Upper >= ng.Lower {
+				merged[i] = true
+				cg.Lower = m.Min(cg.Lower, ng.Lower)
+				cg.Upper = m.Max(cg.Upper, ng.Upper)
 			}
 		}
 	}
 	var ret = make([]FairValueGap, 0)
-	for _, g := range gaps {
-		if g.Filled == 0 {
+	for i, g := range gaps {
+		if !merged[i] {
 			ret = append(ret, g)
 		}
 	}
 	return ret
 }
 
-func FindFairValueGaps3(candles *Matrix, bodyMultiplier float64) []FairValueGap {
-	var gaps = make([]FairValueGap, 0)
-	avgBody := candles.Apply(func(mr MatrixRow) float64 {
-		return m.Abs(mr.Close() - mr.Open())
-	})
-	for i := 2; i < candles.Rows; i++ {
-		c := candles.Row(i)
-		mid := candles.Row(i - 1)
-		p := candles.Row(i - 2)
-		if c.Get(HIGH) < p.Get(LOW) && m.Abs(mid.Close()-mid.Open()) > c.Get(avgBody)*bodyMultiplier {
+// FindFairValueGaps detects the gaps and tracks each one bar by bar
+func FindFairValueGaps(candles *Matrix, cfg FVGConfig) []FairValueGap {
+	gaps := make([]FairValueGap, 0)
+	rows := candles.CandleRows()
+	if rows < 3 {
+		return gaps
+	}
+	bodies := averageBodies(candles, cfg.BodyPeriod)
+	atr := -1
+	if cfg.MinATR > 0.0 {
+		atr = ATR(candles, cfg.ATRPeriod)
+	}
+	for i := 2; i < rows; i++ {
+		c := &candles.DataRows[i]
+		mid := &candles.DataRows[i-1]
+		p := &candles.DataRows[i-2]
+		if cfg.BodyMultiplier > 0.0 && m.Abs(mid.Close()-mid.Open()) <= bodies[i-2]*cfg.BodyMultiplier {
+			continue
+		}
+		minSize := 0.0
+		if atr != -1 {
+			minSize = c.Get(atr) * cfg.MinATR
+		}
+		if cfg.Bullish && c.Low() > p.High() && c.Low()-p.High() >= minSize && (!cfg.RequireClose || mid.Close() > p.High()) {
 			gaps = append(gaps, FairValueGap{
 				Timestamp: p.Key,
-				Filled:    0,
-				Upper:     p.Get(LOW),
-				Lower:     c.Get(HIGH),
+				Upper:     c.Low(),
+				Lower:     p.High(),
 				Index:     i - 2,
-				Type:      -1,
+				Type:      1,
 			})
 		}
-		if c.Get(LOW) > p.Get(HIGH) && m.Abs(mid.Close()-mid.Open()) > c.Get(avgBody)*bodyMultiplier {
+		if cfg.Bearish && c.High() < p.Low() && p.Low()-c.High() >= minSize && (!cfg.RequireClose || mid.Close() < p.Low()) {
 			gaps = append(gaps, FairValueGap{
 				Timestamp: p.Key,
-				Filled:    0,
-				Upper:     c.Get(LOW),
-				Lower:     p.Get(HIGH),
+				Upper:     p.Low(),
+				Lower:     c.High(),
 				Index:     i - 2,
-				Type:      1,
+				Type:      -1,
 			})
 		}
 	}
-	for j, g := range gaps {
-		cg := &gaps[j]
-		for i := g.Index + 3; i < candles.Rows; i++ {
-			c := candles.Row(i)
-			state := getGapState(cg.Lower, cg.Upper, c.Get(LOW), c.Get(HIGH))
-			if state == 1 {
-				cg.Filled = 1
-				break
-			} else if state == 2 {
-				cg.Upper = c.Get(LOW)
-			} else if state == 3 {
-				cg.Lower = c.Get(HIGH)
-			} else if state == 4 {
-				fmt.Println("SPLIT IT:", c.Key)
-			}
-			if cg.Upper < cg.Lower {
-				cg.Filled = 1
-				break
-			}
-		}
+	if atr != -1 {
+		candles.RemoveColumn()
 	}
-	return MergeGaps(gaps)
-}
-
-func FindFairValueGaps(candles *Matrix, bodyMultiplier float64) []Gap {
-	avgBody := candles.Apply(func(mr MatrixRow) float64 {
-		return m.Abs(mr.Close() - mr.Open())
-	})
-	var gaps = make([]Gap, 0)
-	for i := 2; i < candles.Rows; i++ {
-		c := candles.Row(i)
-		mid := candles.Row(i - 1)
-		p := candles.Row(i - 2)
-		if c.Get(HIGH) < p.Get(LOW) && m.Abs(mid.Close()-mid.Open()) > c.Get(avgBody)*bodyMultiplier {
-			gaps = append(gaps, Gap{
-				Timestamp: c.Key,
-				Filled:    0,
-				Upper:     p.Get(LOW),
-				Lower:     c.Get(HIGH),
-				Index:     i - 2,
-			})
-		}
-		if c.Get(LOW) > p.Get(HIGH) && m.Abs(mid.Close()-mid.Open()) > c.Get(avgBody)*bodyMultiplier {
-			gaps = append(gaps, Gap{
-				Timestamp: c.Key,
-				Filled:    0,
-				Upper:     c.Get(LOW),
-				Lower:     p.Get(HIGH),
-				Index:     i - 2,
-			})
-		}
+	for i := range gaps {
+		trackGap(candles, &gaps[i])
 	}
-	for j, g := range gaps {
-		cg := &gaps[j]
-		for i := g.Index + 3; i < candles.Rows; i++ {
-			c := candles.Row(i)
-			state := getGapState(cg.Lower, cg.Upper, c.Get(LOW), c.Get(HIGH))
-			if state == 1 {
-				cg.Filled = 1
-				break
-			} else if state == 2 {
-				cg.Upper = c.Get(LOW)
-			} else if state == 3 {
-				cg.Lower = c.Get(HIGH)
-			} else if state == 4 {
-				fmt.Println("SPLIT IT:", c.Key)
-			}
-			if cg.Upper < cg.Lower {
-				cg.Filled = 1
-				break
-			}
+	if cfg.Merge {
+		gaps = MergeGaps(gaps)
+		for i := range gaps {
+			trackGap(candles, &gaps[i])
 		}
 	}
-	for j := 1; j < len(gaps); j++ {
-		cg := &gaps[j]
-		if cg.Filled == 0 {
-			s := j - 10
-			if s < 0 {
-				s = 0
+	if cfg.Inversion {
+		cnt := len(gaps)
+		for i := 0; i < cnt; i++ {
+			g := gaps[i]
+			if g.State != GAP_INVALIDATED {
+				continue
 			}
-			for i := s; i < len(gaps); i++ {
-				ng := &gaps[i]
-				if ng.Filled == 0 {
-					t := 0
-					if cg.Upper < ng.Upper && cg.Upper > ng.Lower && cg.Lower < ng.Lower {
-						t = 1
-					}
-					if cg.Upper > ng.Upper && cg.Lower > ng.Lower && cg.Lower < ng.Upper {
-						t = 1
-					}
-					if t == 1 {
-						ng.Filled = 1
-						cg.Lower = m.Min(cg.Lower, ng.Lower)
-						cg.Upper = m.Max(cg.Upper, ng.Upper)
-					}
-				}
+			inv := FairValueGap{
+				Timestamp: candles.DataRows[g.InvalidatedIndex].Key,
+				Upper:     g.Upper,
+				Lower:     g.Lower,
+				Index:     g.InvalidatedIndex,
+				Type:      -g.Type,
+				Inversion: true,
 			}
+			trackGap(candles, &inv)
+			gaps = append(gaps, inv)
 		}
+		sort.SliceStable(gaps, func(i, j int) bool {
+			return gaps[i].Start() < gaps[j].Start()
+		})
 	}
 	return gaps
 }
 
-func FindFairValueGaps2(candles *Matrix) []Gap {
-	var gaps = make([]Gap, 0)
-	for i := 2; i < candles.Rows; i++ {
-		c := candles.Row(i)
-		p := candles.Row(i - 1)
-		p2 := candles.Row(i - 2)
-		if c.Get(HIGH) < p2.Get(LOW) && p.Get(ADJ_CLOSE) < p2.Get(LOW) {
-			gaps = append(gaps, Gap{
-				Timestamp: c.Key,
-				Filled:    0,
-				Upper:     p2.Get(LOW),
-				Lower:     c.Get(HIGH),
-				Index:     i - 2,
-			})
+// OpenFairValueGaps returns the gaps that are still active on the last candle
+func OpenFairValueGaps(gaps []FairValueGap) []FairValueGap {
+	ret := make([]FairValueGap, 0)
+	for _, g := range gaps {
+		if g.Active() {
+			ret = append(ret, g)
 		}
-		if c.Get(LOW) > p2.Get(HIGH) && p.Get(ADJ_CLOSE) > p2.Get(HIGH) {
-			gaps = append(gaps, Gap{
-				Timestamp: c.Key,
-				Filled:    0,
-				Upper:     c.Get(LOW),
-				Lower:     p2.Get(HIGH),
-				Index:     i - 2,
-			})
+	}
+	return ret
+}
+
+// FairValueGapColumns adds the gaps to the matrix. FVG is the type on the row
+// where the gap is known (2 and -2 for inversion gaps). FVGUpper, FVGLower and
+// FVGState describe the most recent gap which is still active on that row.
+// Upper and Lower are 0 if there is no active gap.
+func FairValueGapColumns(candles *Matrix, cfg FVGConfig) int {
+	ret := candles.AddNamedColumn("FVG")
+	upper := candles.AddNamedColumn("FVGUpper")
+	lower := candles.AddNamedColumn("FVGLower")
+	state := candles.AddNamedColumn("FVGState")
+	// 0 = FVG 1 = Upper 2 = Lower 3 = State
+	gaps := FindFairValueGaps(candles, cfg)
+	for _, g := range gaps {
+		tp := float64(g.Type)
+		if g.Inversion {
+			tp *= 2.0
 		}
+		candles.DataRows[g.Start()].Set(ret, tp)
 	}
-	for j, g := range gaps {
-		cg := &gaps[j]
-		for i := g.Index + 3; i < candles.Rows; i++ {
-			c := candles.Row(i)
-			state := getGapState(cg.Lower, cg.Upper, c.Get(LOW), c.Get(HIGH))
-			if state == 1 {
-				cg.Filled = 1
-				break
-			} else if state == 2 {
-				cg.Upper = c.Get(LOW)
-			} else if state == 3 {
-				cg.Lower = c.Get(HIGH)
-			} else if state == 4 {
-				fmt.Println("SPLIT IT:", c.Key)
+	for i := 0; i < candles.CandleRows(); i++ {
+		c := &candles.DataRows[i]
+		for j := len(gaps) - 1; j >= 0; j-- {
+			g := gaps[j]
+			if g.Start() > i {
+				continue
 			}
-			if cg.Upper < cg.Lower {
-				cg.Filled = 1
+			st := g.StateAt(i)
+			if st == GAP_OPEN || st == GAP_PARTIAL {
+				c.Set(upper, g.Upper)
+				c.Set(lower, g.Lower)
+				c.Set(state, float64(st))
 				break
 			}
 		}
 	}
-	return gaps
+	return ret
 }
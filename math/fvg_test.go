@@ -0,0 +1,62 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindFairValueGaps(t *testing.T) {
+	mat := candleMatrix([][4]float64{
+		{100, 101, 99, 100},
+		{100, 102, 99, 101},
+		{101, 110, 101, 109},
+		{109, 112, 106, 111},
+		{111, 113, 108, 112},
+		{112, 112, 104, 105},
+		{105, 106, 100, 101},
+		{101, 102, 99, 100},
+	})
+	cols := mat.Cols
+	cfg := DefaultFVGConfig()
+	cfg.MinATR = 0.1
+	cfg.Inversion = true
+	gaps := FindFairValueGaps(mat, cfg)
+	assert.Equal(t, cols, mat.Cols)
+	// bullish gap 102-106, bearish gap 106-108 and the inversion of the bullish gap
+	assert.Equal(t, 3, len(gaps))
+	g := gaps[0]
+	assert.Equal(t, 1, g.Type)
+	assert.Equal(t, 1, g.Index)
+	assert.Equal(t, 102.0, g.Lower)
+	assert.Equal(t, 106.0, g.Upper)
+	assert.Equal(t, 5, g.FirstTouch)
+	assert.Equal(t, 6, g.FilledIndex)
+	assert.Equal(t, 6, g.InvalidatedIndex)
+	assert.Equal(t, GAP_PARTIAL, g.StateAt(5))
+	assert.Equal(t, GAP_INVALIDATED, g.State)
+	assert.Equal(t, 100.0, g.Fill)
+	assert.Equal(t, -1, gaps[1].Type)
+	assert.Equal(t, 108.0, gaps[1].Upper)
+	inv := gaps[2]
+	assert.True(t, inv.Inversion)
+	assert.Equal(t, -1, inv.Type)
+	assert.Equal(t, GAP_OPEN, inv.State)
+	fvg := FairValueGapColumns(mat, DefaultFVGConfig())
+	assert.Equal(t, 1.0, mat.DataRows[3].Get(fvg))
+	assert.Equal(t, float64(GAP_PARTIAL), mat.DataRows[5].Get(fvg+3))
+	assert.Equal(t, -1.0, mat.DataRows[6].Get(fvg))
+	assert.Equal(t, 108.0, mat.DataRows[6].Get(fvg+1))
+}
+
+func TestMergeGaps(t *testing.T) {
+	gaps := []FairValueGap{
+		{Upper: 105, Lower: 100, Type: 1},
+		{Upper: 108, Lower: 104, Type: 1},
+		{Upper: 108, Lower: 104, Type: -1},
+	}
+	merged := MergeGaps(gaps)
+	assert.Equal(t, 2, len(merged))
+	assert.Equal(t, 100.0, merged[0].Lower)
+	assert.Equal(t, 108.0, merged[0].Upper)
+}
@@ -0,0 +1,38 @@
+package math
+
+import (
+	"time"
+)
+
+// candleMatrix builds daily candles starting at 2024-01-01 from
+// [open, high, low, close] bars. Every candle has a volume of 100.
+func candleMatrix(bars [][4]float64) *Matrix {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, b := range bars {
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(OPEN, b[0]).Set(HIGH, b[1]).Set(LOW, b[2]).Set(CLOSE, b[3]).Set(ADJ_CLOSE, b[3]).Set(VOLUME, 100)
+	}
+	return mat
+}
+
+// closeBars returns bars that open and close at the close with the high and
+// low spread away from it
+func closeBars(closes []float64, spread float64) [][4]float64 {
+	ret := make([][4]float64, len(closes))
+	for i, c := range closes {
+		ret[i] = [4]float64{c, c + spread, c - spread, c}
+	}
+	return ret
+}
+
+// pathCloses returns the closes on straight lines between the pivots with
+// steps closes per leg. The last pivot is not included.
+func pathCloses(pivots []float64, steps int) []float64 {
+	ret := make([]float64, 0)
+	for i := 1; i < len(pivots); i++ {
+		for s := 0; s < steps; s++ {
+			ret = append(ret, pivots[i-1]+(pivots[i]-pivots[i-1])*float64(s)/float64(steps))
+		}
+	}
+	return ret
+}
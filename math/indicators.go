@@ -124,7 +124,7 @@ func MAFuncByName(name string) MAFunc {
 func SMA(m *Matrix, days, field int) int {
 	ret := m.AddNamedColumn(fmt.Sprintf("SMA%d", days))
 	n := float64(days)
-	for i := days - 1; i < m.Rows; i++ {
+	for i := days - 1; i < m.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < days; j++ {
 			idx := i - days + 1 + j
@@ -145,7 +145,7 @@ func WeightedMA(m *Matrix, days, field int) int {
 	for i := range days {
 		n += float64(i + 1)
 	}
-	for i := range m.Rows {
+	for i := range m.CandleRows() {
 		if i >= days-1 {
 			sum := 0.0
 			for j := range days {
@@ -164,7 +164,7 @@ func WeightedMA(m *Matrix, days, field int) int {
 func ShiftedSMA(m *Matrix, days, offset, field int) int {
 	ret := m.AddNamedColumn(fmt.Sprintf("SMA%d", days))
 	n := float64(days)
-	for i := days - 1; i < m.Rows; i++ {
+	for i := days - 1; i < m.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < days; j++ {
 			idx := i - days + 1 + j
@@ -174,6 +174,7 @@ func ShiftedSMA(m *Matrix, days, offset, field int) int {
 		m.DataRows[i].Set(ret, avg)
 	}
 
+	// the shift moves the average into the projected rows
 	for i := m.Rows - 1; i >= offset; i-- {
 		m.DataRows[i].Set(ret, m.DataRows[i-offset].Get(ret))
 	}
@@ -188,7 +189,7 @@ func ShiftedSMA(m *Matrix, days, offset, field int) int {
 // -----------------------------------------------------------------------
 func SWMA(m *Matrix, field int) int {
 	ret := m.AddColumn()
-	for i := 3; i < m.Rows; i++ {
+	for i := 3; i < m.CandleRows(); i++ {
 		sum := (m.DataRows[i-3].Get(field) + 2.0*m.DataRows[i-2].Get(field) + 2.0*m.DataRows[i-1].Get(field) + m.DataRows[i].Get(field)) / 6.0
 		m.DataRows[i].Set(ret, sum)
 	}
@@ -200,12 +201,12 @@ func SWMA(m *Matrix, field int) int {
 // -----------------------------------------------------------------------
 func EMA(m *Matrix, days, field int) int {
 	ret := m.AddNamedColumn(fmt.Sprintf("EMA%d", days))
-	if m.Rows > days {
+	if m.CandleRows() > days {
 		n := float64(days)
 		sma := SMA(m, days, field)
 		multiplier := 2.0 / (n + 1)
 		m.DataRows[days].Set(ret, m.DataRows[days-1].Get(sma))
-		for i := days + 1; i < m.Rows; i++ {
+		for i := days + 1; i < m.CandleRows(); i++ {
 			v := m.DataRows[i-1].Get(ret)*(1.0-multiplier) + m.DataRows[i].Get(field)*multiplier
 			m.DataRows[i].Set(ret, v)
 		}
@@ -219,7 +220,7 @@ func EMA(m *Matrix, days, field int) int {
 // -----------------------------------------------------------------------
 func RMA(m *Matrix, days, field int) int {
 	ret := m.AddNamedColumn(fmt.Sprintf("RMA%d", days))
-	total := m.Rows
+	total := m.CandleRows()
 	n := float64(days)
 	if total >= days {
 		sum := 0.0
@@ -246,7 +247,7 @@ func RMA(m *Matrix, days, field int) int {
 func WMA(m *Matrix, days, field int) int {
 	ret := m.AddNamedColumn(fmt.Sprintf("WMA%d", days))
 	n := float64(days)
-	for i := days - 1; i < m.Rows; i++ {
+	for i := days - 1; i < m.CandleRows(); i++ {
 		sum := 0.0
 		for j := range days {
 			idx := i - days + 1 + j
@@ -267,7 +268,7 @@ func TEMA(m *Matrix, days, field int) int {
 	ema1 := EMA(m, days, field)
 	ema2 := EMA(m, days, ema1)
 	ema3 := EMA(m, days, ema2)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		e1 := m.DataRows[i].Get(ema1)
 		e2 := m.DataRows[i].Get(ema2)
 		e3 := m.DataRows[i].Get(ema3)
@@ -288,7 +289,7 @@ func DEMA(m *Matrix, days, field int) int {
 	ema1 := EMA(m, days, field)
 	ema2 := EMA(m, days, ema1)
 	ema3 := EMA(m, days, ema2)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		e1 := m.DataRows[i].Get(ema1)
 		e3 := m.DataRows[i].Get(ema3)
 		m.DataRows[i].Set(ret, 2.0*e1-e3)
@@ -303,7 +304,7 @@ func ZLEMA(m *Matrix, days, field int) int {
 	ret := m.AddColumn()
 	lag := (days - 1) / 2
 	d := m.AddColumn()
-	for i := lag; i < m.Rows; i++ {
+	for i := lag; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(d, 2.0*m.DataRows[i].Get(field)-m.DataRows[i-lag].Get(field))
 	}
 	ei := EMA(m, days, d)
@@ -316,7 +317,7 @@ func ZLSMA(m *Matrix, days, field int) int {
 	ret := m.AddColumn()
 	lag := (days - 1) / 2
 	d := m.AddColumn()
-	for i := lag; i < m.Rows; i++ {
+	for i := lag; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(d, 2.0*m.DataRows[i].Get(field)-m.DataRows[i-lag].Get(field))
 	}
 	ei := SMA(m, days, d)
@@ -329,7 +330,7 @@ func ZLSMA(m *Matrix, days, field int) int {
 // fastPeriod = typically 2; slowPeriod = typically 30; erPeriod = typically 10.
 // func KAMA(prices []float64, erPeriod, fastPeriod, slowPeriod int) []float64 {
 func KAMA(m *Matrix, erPeriod, fastPeriod, slowPeriod int) int {
-	n := m.Rows
+	n := m.CandleRows()
 	kama := m.AddColumn()
 	fastSC := 2.0 / (float64(fastPeriod) + 1)
 	slowSC := 2.0 / (float64(slowPeriod) + 1)
@@ -363,7 +364,7 @@ func MASlope(prices *Matrix, operator MAFunc, days, lookback int) int {
 	ret := prices.AddNamedColumn(fmt.Sprintf("MAS%d", days))
 	steps := float64(lookback)
 	si := operator(prices, days, ADJ_CLOSE)
-	for i := lookback; i < prices.Rows; i++ {
+	for i := lookback; i < prices.CandleRows(); i++ {
 		cur := prices.DataRows[i].Get(si)
 		prev := prices.DataRows[i-lookback].Get(si)
 		prices.DataRows[i].Set(ret, (cur-prev)/steps)
@@ -385,7 +386,7 @@ func Disparity(prices *Matrix, days int) int {
 	ret := prices.AddColumn()
 	// DI 14 = [ C.PRICE - MOVING  AVG 14 ] / [ MOVING AVG 14 ] * 100
 	sma := EMA(prices, days, 4)
-	for _, p := range prices.DataRows {
+	for _, p := range prices.DataRows[:prices.CandleRows()] {
 		p.Set(ret, (p.Get(ADJ_CLOSE)-p.Get(sma))/p.Get(sma)*100.0)
 	}
 	prices.RemoveColumn()
@@ -405,7 +406,7 @@ func AO(m *Matrix, short, long int) int {
 	sma34 := SMA(m, long, mid)
 	// AO = sma((high+low)/2, LängeAO1) - sma((high+low)/2, LängeAO2)
 	oldHist := 0.0
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		d := m.DataRows[i].Get(sma5) - m.DataRows[i].Get(sma34)
 		if d > oldHist {
 			m.DataRows[i].Set(clr, 1.0)
@@ -428,7 +429,7 @@ func LinearRegression(m *Matrix, period int) int {
 	// 0 = M 1 = C
 	lr := m.AddColumn()
 	ci := m.AddColumn()
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		s, c := SimpleLinearRegression(m, i-period, i, 4)
 		m.DataRows[i].Set(lr, s)
 		m.DataRows[i].Set(ci, c)
@@ -446,7 +447,7 @@ func ACC(m *Matrix, short, long, s int) int {
 	ret := m.AddColumn()
 	ao := AO(m, short, long)
 	sma := SMA(m, s, ao)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(ao)-m.DataRows[i].Get(sma))
 	}
 	m.RemoveColumn()
@@ -467,11 +468,11 @@ func MACD(m *Matrix, short, long, signal int) int {
 	f := EMA(m, short, 4)
 	s := EMA(m, long, 4)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(f)-m.DataRows[i].Get(s))
 	}
 	signalPairs := EMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -493,11 +494,11 @@ func MACDS(m *Matrix, short, long, signal int) int {
 	f := SMA(m, short, 4)
 	s := SMA(m, long, 4)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(f)-m.DataRows[i].Get(s))
 	}
 	signalPairs := SMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -528,7 +529,7 @@ func MACDV(m *Matrix, short, long, signal int) int {
 	})
 
 	signalPairs := EMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -550,11 +551,11 @@ func MACDZL(m *Matrix, short, long, signal int) int {
 	f := ZLEMA(m, short, 4)
 	s := ZLEMA(m, long, 4)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(f)-m.DataRows[i].Get(s))
 	}
 	signalPairs := EMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -576,11 +577,11 @@ func MACDHMA(m *Matrix, short, long, signal int) int {
 	f := HMA(m, short, 4)
 	s := HMA(m, long, 4)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(f)-m.DataRows[i].Get(s))
 	}
 	signalPairs := HMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -599,11 +600,11 @@ func MACDExt(m *Matrix, field, short, long, signal int) int {
 	f := EMA(m, short, field)
 	s := EMA(m, long, field)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(f)-m.DataRows[i].Get(s))
 	}
 	signalPairs := EMA(m, signal, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sig, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(ret)-m.DataRows[i].Get(signalPairs))
 	}
@@ -620,7 +621,7 @@ func Momentum(m *Matrix, days, smoothed int) int {
 	// 0 = Momentum 1 = Momentum Percentage 2 = EMA Momentum
 	ret := m.AddNamedColumn("Momentum")
 	per := m.AddNamedColumn("Momentum-Pct")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, (m.DataRows[i].Get(ADJ_CLOSE) - m.DataRows[i-days].Get(ADJ_CLOSE)))
 		m.DataRows[i].Set(per, (m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i-days].Get(ADJ_CLOSE))/m.DataRows[i-days].Get(ADJ_CLOSE)*100.0)
 	}
@@ -637,7 +638,7 @@ func MomentumExt(m *Matrix, days, smoothed, field int) int {
 	// 0 = Momentum 1 = Momentum Percentage 2 = EMA Momentum
 	ret := m.AddNamedColumn("Momentum")
 	per := m.AddNamedColumn("Momentum-Pct")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, (m.DataRows[i].Get(field) - m.DataRows[i-days].Get(field)))
 		m.DataRows[i].Set(per, (m.DataRows[i].Get(field)-m.DataRows[i-days].Get(field))/m.DataRows[i-days].Get(field)*100.0)
 	}
@@ -656,7 +657,7 @@ func Change(m *Matrix, atr int) int {
 	rel := m.AddColumn()
 	aa := m.AddColumn()
 	ai := ATR(m, atr)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Close()-m.DataRows[i-1].Close())
 		m.DataRows[i].Set(rel, (m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i-1].Get(ADJ_CLOSE))/m.DataRows[i-1].Get(ADJ_CLOSE)*100.0)
 		if m.DataRows[i].Get(ai) != 0.0 {
@@ -675,7 +676,7 @@ func Change(m *Matrix, atr int) int {
 func DPC(m *Matrix) int {
 	// 0 = DPC
 	ret := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, (m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i-1].Get(ADJ_CLOSE))/m.DataRows[i-1].Get(ADJ_CLOSE)*100.0)
 	}
 	return ret
@@ -684,7 +685,7 @@ func DPC(m *Matrix) int {
 // calcReturns computes log returns
 func LogReturns(m *Matrix) int {
 	returns := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		r := math.Log(m.DataRows[i].Close() / m.DataRows[i-1].Close())
 		m.DataRows[i].Set(returns, r)
 	}
@@ -699,7 +700,7 @@ func LogReturns(m *Matrix) int {
 func PPCH(m *Matrix) int {
 	// 0 = percentage change of price
 	ret := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, (m.DataRows[i].Close()-m.DataRows[i-1].Close())/m.DataRows[i-1].Close()*100.0)
 	}
 	return ret
@@ -713,7 +714,7 @@ func MeanBreakout(m *Matrix, period int) int {
 	// 0 = MBO
 	ret := m.AddColumn()
 	sma := EMA(m, period, 4)
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		cs := m.DataRows[i].Get(sma)
 		min, max := m.FindMinMaxBetween(4, i-period, period)
@@ -734,13 +735,13 @@ func ConsolidatedPriceDifference(m *Matrix, min int) int {
 	sub := m.AddColumn()
 	ret := m.AddColumn()
 	md := 0.0
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		pp := m.DataRows[i-1]
 		cnt := 0
 		counting := true
 		price := cp.Get(ADJ_CLOSE)
-		for j := i + 1; j < m.Rows; j++ {
+		for j := i + 1; j < m.CandleRows(); j++ {
 			if counting == true {
 				p := m.DataRows[j]
 				if p.Get(ADJ_CLOSE) > pp.Get(2) && p.Get(ADJ_CLOSE) < cp.Get(HIGH) {
@@ -756,7 +757,7 @@ func ConsolidatedPriceDifference(m *Matrix, min int) int {
 		}
 		m.DataRows[i].Set(sub, md)
 	}
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(sub) != 0.0 {
 			m.DataRows[i].Set(ret, (m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i].Get(sub))/m.DataRows[i].Get(sub)*100.0)
 		}
@@ -773,7 +774,7 @@ func RSI(m *Matrix, days, field int) int {
 	// 0 = RSI
 	ret := m.AddNamedColumn(fmt.Sprintf("RSI%d", days))
 	diff := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(field)-m.DataRows[i-1].Get(field))
 	}
 	//up = ta.rma(math.max(ta.change(rsiSourceInput), 0), rsiLengthInput)
@@ -788,7 +789,7 @@ func RSI(m *Matrix, days, field int) int {
 	up := RMA(m, days, cgi)
 	down := RMA(m, days, cli)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		rsi := 0.0
 		if m.DataRows[i].Get(down) == 0.0 {
 			rsi = 100.0
@@ -807,7 +808,7 @@ func ModifiedRSI(m *Matrix, days, field int) int {
 	// 0 = RSI
 	ret := m.AddNamedColumn("MRSI")
 	diff := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(field)-m.DataRows[i-1].Get(field))
 	}
 	//up = ta.rma(math.max(ta.change(rsiSourceInput), 0), rsiLengthInput)
@@ -822,7 +823,7 @@ func ModifiedRSI(m *Matrix, days, field int) int {
 	up := SMA(m, days, cgi)
 	down := SMA(m, days, cli)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		rsi := 0.0
 		if m.DataRows[i].Get(down) == 0.0 {
 			rsi = 100.0
@@ -853,7 +854,7 @@ func RSITrend(m *Matrix, days, sma, field int) int {
 	ret := m.AddNamedColumn("RSITrend")
 	ri := RSI(m, days, field)
 	si := EMA(m, sma, ri)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		cnt := 0.0
@@ -902,7 +903,7 @@ func RSIMomentum(m *Matrix, short, long, field int) int {
 	ret := m.AddColumn()
 	shortRSI := RSI(m, short, field)
 	longRSI := RSI(m, long, field)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		l := m.DataRows[i].Get(longRSI)
 		if l != 0.0 {
 			m.DataRows[i].Set(ret, m.DataRows[i].Get(shortRSI)/l)
@@ -918,7 +919,7 @@ func RSIMomentum(m *Matrix, short, long, field int) int {
 func ATS(mat *Matrix, days int) int {
 	// 0 = ATR
 	tmp := mat.AddColumn()
-	for i := 0; i < mat.Rows; i++ {
+	for i := 0; i < mat.CandleRows(); i++ {
 		mat.DataRows[i].Set(tmp, m.Abs(mat.DataRows[i].Open()-mat.DataRows[i].Close()))
 	}
 	return EMA(mat, days, tmp)
@@ -932,7 +933,7 @@ func ATR(m *Matrix, days int) int {
 	// 0 = ATR
 	ret := m.AddNamedColumn("ATR")
 	tr := TrueRange(m)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		trueRange := m.DataRows[i].Get(tr)
 		m.DataRows[i].Set(ret, (m.DataRows[i-1].Get(ret)*(float64(days)-1)+trueRange)/float64(days))
 	}
@@ -945,10 +946,10 @@ func ATRExt(m *Matrix, days int, ops MAFunc) int {
 	ret := m.AddColumn()
 	smoothed := m.AddColumn()
 	trIdx := m.AddColumn()
-	if m.Rows < 1 {
+	if m.CandleRows() < 1 {
 		return -1
 	}
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		curH := m.DataRows[i].Get(HIGH)
 		curL := m.DataRows[i].Get(LOW)
 		prevC := m.DataRows[i-1].Get(ADJ_CLOSE)
@@ -966,7 +967,7 @@ func ATRExt(m *Matrix, days int, ops MAFunc) int {
 	}
 	rma := ops(m, days, trIdx)
 	ema := EMA(m, days, rma)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(rma))
 		m.DataRows[i].Set(smoothed, m.DataRows[i].Get(ema))
 	}
@@ -985,7 +986,7 @@ func ADR(m *Matrix, days int) int {
 	ret := m.AddColumn()
 	s1 := SMA(m, days, 1)
 	s2 := SMA(m, days, 2)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		c := &m.DataRows[i]
 		c.Set(ret, c.Get(s1)-c.Get(s2))
 	}
@@ -1003,13 +1004,13 @@ func DailyRange(m *Matrix, days int) int {
 	// 0 = DailyRange
 	ret := m.AddColumn()
 	ti := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(2) != 0.0 {
 			m.DataRows[i].Set(ti, m.DataRows[i].Get(HIGH)/m.DataRows[i].Get(2))
 		}
 	}
 	si := SMA(m, days, ti)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, 100.0*m.DataRows[i].Get(si))
 	}
 	return ret
@@ -1019,11 +1020,11 @@ func RVA(m *Matrix, days int) int {
 	// 0 = RVA
 	ret := m.AddColumn()
 	ti := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ti, m.DataRows[i].Get(HIGH)-m.DataRows[i].Get(LOW))
 	}
 	si := SMA(m, days, ti)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		rng := m.DataRows[i].Get(HIGH) - m.DataRows[i].Get(LOW)
 		if m.DataRows[i].Get(si) != 0.0 {
 			m.DataRows[i].Set(ret, rng/m.DataRows[i].Get(si))
@@ -1039,7 +1040,7 @@ func ROC(m *Matrix, days, field int) int {
 	// 0 = ROC 1 = Diff
 	ret := m.AddNamedColumn(fmt.Sprintf("ROC%d", days))
 	di := m.AddNamedColumn("ROC-D")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		current := m.DataRows[i].Get(field)
 		prev := m.DataRows[i-days].Get(field)
 		v := 0.0
@@ -1059,7 +1060,7 @@ func ROC(m *Matrix, days, field int) int {
 func AbsoluteROC(m *Matrix, days, field int) int {
 	// 0 = ROC
 	ret := m.AddNamedColumn(fmt.Sprintf("ROC%d", days))
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		current := m.DataRows[i].Get(field)
 		prev := m.DataRows[i-days].Get(field)
 		m.DataRows[i].Set(ret, current-prev)
@@ -1070,7 +1071,7 @@ func AbsoluteROC(m *Matrix, days, field int) int {
 func Trend(m *Matrix) int {
 	// 0 = Trend
 	ret := m.AddNamedColumn("Trend")
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(OPEN) > m.DataRows[i].Get(ADJ_CLOSE) {
 			m.DataRows[i].Set(ret, -1.0)
 		} else {
@@ -1086,7 +1087,7 @@ func DiffTrendCounter(m *Matrix, field int) int {
 	ret := m.AddNamedColumn("Trend")
 	tc := 0.0
 	cnt := 1.0
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(field)
 		dir := -1.0
 		if c >= 0.0 {
@@ -1106,7 +1107,7 @@ func DiffTrendCounter(m *Matrix, field int) int {
 func TrendCounter(m *Matrix, field int) int {
 	// 0 = Trend
 	ret := m.AddNamedColumn("Trend")
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		val := p.Get(ret)
@@ -1134,7 +1135,7 @@ func ThresholdCounter(m *Matrix, threshold float64, field int) int {
 	ret := m.AddNamedColumn("Trend")
 	dir := 0.0
 	cnt := 0.0
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(field)
 		curDir := 1.0
 		if c < threshold {
@@ -1154,7 +1155,7 @@ func CompareCounter(m *Matrix, first, second int) int {
 	// 0 = Trend
 	ret := m.AddNamedColumn("Compare")
 	tc := 0.0
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(first)
 		p := m.DataRows[i].Get(second)
 		if c >= p {
@@ -1182,7 +1183,7 @@ func TDROC(m *Matrix, days, field int) int {
 	// 0 = ROC
 	ret := m.AddNamedColumn(fmt.Sprintf("TDROC%d", days))
 	start := days
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		if i >= start {
 			current := m.DataRows[i].Get(field)
 			prev := m.DataRows[i-days].Get(field)
@@ -1204,18 +1205,18 @@ func Stochastic(m *Matrix, days, ema int) int {
 	k := m.AddNamedColumn("Stoch-L")
 	d := m.AddNamedColumn("Stoch-D")
 	v := m.AddColumn()
-	total := m.Rows
+	total := m.CandleRows()
 	if total < days {
 		return -1
 	}
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		low := m.FindMinBetween(2, i-days+1, days)
 		high := m.FindMaxBetween(1, i-days+1, days)
 		m.DataRows[i].Set(v, (m.DataRows[i].Get(ADJ_CLOSE)-low)/(high-low)*100.0)
 	}
 	slowData := SMA(m, ema, v)
 	dData := SMA(m, 3, slowData)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(k, m.DataRows[i].Get(slowData))
 		m.DataRows[i].Set(d, m.DataRows[i].Get(dData))
 	}
@@ -1231,18 +1232,18 @@ func StochasticExt(m *Matrix, days, ema, highField, lowField, priceField int) in
 	k := m.AddColumn()
 	d := m.AddColumn()
 	v := m.AddColumn()
-	total := m.Rows
+	total := m.CandleRows()
 	if total < days {
 		return -1
 	}
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		low := m.FindMinBetween(lowField, i-days+1, days)
 		high := m.FindMaxBetween(highField, i-days+1, days)
 		m.DataRows[i].Set(v, (m.DataRows[i].Get(priceField)-low)/(high-low)*100.0)
 	}
 	slowData := SMA(m, ema, v)
 	dData := SMA(m, 3, slowData)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(k, m.DataRows[i].Get(slowData))
 		m.DataRows[i].Set(d, m.DataRows[i].Get(dData))
 	}
@@ -1258,12 +1259,12 @@ func StochasticExt(m *Matrix, days, ema, highField, lowField, priceField int) in
 func Stoch(m *Matrix, days, field int) int {
 	// 0 = K
 	k := m.AddColumn()
-	total := m.Rows
+	total := m.CandleRows()
 	if total < days {
 		return -1
 	}
 	// 100 * (close - lowest(low, length)) / (highest(high, length) - lowest(low, length)).
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		low := m.FindMinBetween(field, i-days+1, days)
 		high := m.FindMaxBetween(field, i-days+1, days)
 		d := high - low
@@ -1300,7 +1301,7 @@ func StochasticRSI(m *Matrix, rsi, stoch, smoothK, smoothD int) int {
 	sr := StochasticExt(m, stoch, smoothK, ri, ri, ri)
 	k := SMA(m, smoothK, sr)
 	d := SMA(m, smoothD, k)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ki, m.DataRows[i].Get(k))
 		m.DataRows[i].Set(di, m.DataRows[i].Get(d))
 	}
@@ -1324,12 +1325,12 @@ func RSS(m *Matrix, slow, fast, rsi, smoothing int) int {
 	spread := m.AddColumn()
 	emaFast := EMA(m, fast, 4)
 	emaSlow := EMA(m, slow, 4)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(spread, m.DataRows[i].Get(emaSlow)-m.DataRows[i].Get(emaFast))
 	}
 	rd := RSI(m, rsi, spread)
 	rss := SMA(m, smoothing, rd)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(rss))
 	}
 	m.RemoveColumn()
@@ -1351,7 +1352,7 @@ func PPO(m *Matrix, short, long, signal int) int {
 	emaShort := EMA(m, short, 4)
 	emaLong := EMA(m, long, 4)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		vl := m.DataRows[i].Get(emaLong)
 		if vl != 0.0 {
 			vs := m.DataRows[i].Get(emaShort)
@@ -1360,7 +1361,7 @@ func PPO(m *Matrix, short, long, signal int) int {
 		}
 	}
 	signalPairs := EMA(m, signal, line)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(signalIdx, m.DataRows[i].Get(signalPairs))
 		m.DataRows[i].Set(diff, m.DataRows[i].Get(line)-m.DataRows[i].Get(signalIdx))
 	}
@@ -1374,8 +1375,8 @@ func PPO(m *Matrix, short, long, signal int) int {
 func standardAbbreviation(m *Matrix, v float64, offset, cnt int) float64 {
 	sum := 0.0
 	end := offset + cnt
-	if end > m.Rows {
-		end = m.Rows
+	if end > m.CandleRows() {
+		end = m.CandleRows()
 	}
 	for i := offset; i < end; i++ {
 		d := m.DataRows[i].Get(ADJ_CLOSE) - v
@@ -1394,7 +1395,7 @@ func BollingerBand(m *Matrix, ema int, upper, lower float64) int {
 	midIdx := m.AddNamedColumn("BB-MID")
 	sma := SMA(m, ema, 4)
 	std := m.StdDev(4, ema)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		sa := m.DataRows[i].Get(std)
 		m.DataRows[i].Set(upIdx, m.DataRows[i].Get(sma)+sa*upper)
 		m.DataRows[i].Set(lowIdx, m.DataRows[i].Get(sma)-sa*lower)
@@ -1412,7 +1413,7 @@ func BollingerBand_Price_Relation(m *Matrix, ema int, upper, lower float64) int
 	// 0 = BBPR
 	ret := m.AddColumn()
 	bb := BollingerBand(m, ema, upper, lower)
-	for i := ema; i < m.Rows; i++ {
+	for i := ema; i < m.CandleRows(); i++ {
 		cb := m.DataRows[i]
 		d := cb.Get(bb) - cb.Get(bb+1)
 		if d != 0.0 {
@@ -1432,7 +1433,7 @@ func BollingerBand_Price_Relation(m *Matrix, ema int, upper, lower float64) int
 func ChannelPriceRelation(m *Matrix, upperIndex, lowerIndex int) int {
 	// 0 = BBPR
 	ret := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cb := m.DataRows[i]
 		d := cb.Get(upperIndex) - cb.Get(lowerIndex)
 		if d != 0.0 {
@@ -1453,7 +1454,7 @@ func ESDBand(m *Matrix, ema int, upper, lower float64) int {
 	midIdx := m.AddColumn()
 	sma := EMA(m, ema, 4)
 	std := m.StdDev(4, ema)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		sa := m.DataRows[i].Get(std)
 		m.DataRows[i].Set(upIdx, m.DataRows[i].Get(sma)+sa*upper)
 		m.DataRows[i].Set(lowIdx, m.DataRows[i].Get(sma)-sa*lower)
@@ -1471,7 +1472,7 @@ func EMAChannelPriceRelation(m *Matrix, ema int, upper, lower float64) int {
 	midIdx := m.AddColumn()
 	sma := EMA(m, ema, 4)
 	std := m.StdDev(4, ema)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		sa := m.DataRows[i].Get(std)
 		m.DataRows[i].Set(upIdx, m.DataRows[i].Get(sma)+sa*upper)
 		m.DataRows[i].Set(lowIdx, m.DataRows[i].Get(sma)-sa*lower)
@@ -1492,7 +1493,7 @@ func BollingerBandExt(m *Matrix, field, ema int, upper, lower float64) int {
 	midIdx := m.AddColumn()
 	sma := SMA(m, ema, field)
 	std := m.StdDev(field, ema)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		sa := m.DataRows[i].Get(std)
 		m.DataRows[i].Set(upIdx, m.DataRows[i].Get(sma)+sa*upper)
 		m.DataRows[i].Set(lowIdx, m.DataRows[i].Get(sma)-sa*lower)
@@ -1510,7 +1511,7 @@ func BollingerBandSqueeze(m *Matrix, ema int, upper, lower float64, period int)
 	ret := m.AddColumn()
 	bb := BollingerBandWidth(m, ema, upper, lower)
 	si := m.Stochastic(period, bb)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(si))
 	}
 	m.RemoveColumn()
@@ -1524,7 +1525,7 @@ func BollingerBandSqueeze(m *Matrix, ema int, upper, lower float64, period int)
 func VolatilityIndex(m *Matrix, ema int, upper, lower float64) int {
 	ret := m.AddColumn()
 	bb := BollingerBand(m, ema, upper, lower)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		m.DataRows[i].Set(ret, (c.Get(bb)-c.Get(bb+1))/c.Get(bb))
 	}
@@ -1540,7 +1541,7 @@ func ElderBars(m *Matrix) int {
 	ret := m.AddColumn()
 	ei := EMA(m, 13, ADJ_CLOSE)
 	mi := MACD(m, 12, 26, 9)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		if c.Get(ei) > p.Get(ei) && c.Get(mi+2) > p.Get(mi+2) {
@@ -1560,7 +1561,7 @@ func ElderBars(m *Matrix) int {
 func BollingerBandWidth(m *Matrix, ema int, upper, lower float64) int {
 	ret := m.AddColumn()
 	bb := BollingerBand(m, ema, upper, lower)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cb := m.DataRows[i]
 		if cb.Get(bb+2) != 0.0 {
 			m.DataRows[i].Set(ret, (cb.Get(bb)-cb.Get(bb+1))/cb.Get(bb+2)*100.0)
@@ -1585,7 +1586,7 @@ func BollingerBandWidthRatio(m *Matrix, ema int, upper, lower float64, avg int)
 		return 0.0
 	})
 	si := EMA(m, avg, bw)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		c := &m.DataRows[i]
 		if c.Get(si) != 0.0 {
 			c.Set(ret, c.Get(bw)/c.Get(si))
@@ -1601,7 +1602,7 @@ func BollingerBandWidthRatio(m *Matrix, ema int, upper, lower float64, avg int)
 func BollingerBandPercentage(m *Matrix, ema int, upper, lower float64) int {
 	ret := m.AddColumn()
 	bb := BollingerBand(m, ema, upper, lower)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cb := m.DataRows[i]
 		if cb.Get(bb+2) != 0.0 {
 			m.DataRows[i].Set(ret, (cb.Get(4)-cb.Get(bb+1))/(cb.Get(bb)-cb.Get(bb+1)))
@@ -1628,7 +1629,7 @@ func KEnvelope(m *Matrix, days int) int {
 	ret := SMA(m, days, 1)
 	SMA(m, days, 2)
 	midIdx := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(midIdx, m.DataRows[i].Get(ADJ_CLOSE))
 	}
 	m.RemoveColumn()
@@ -1637,7 +1638,7 @@ func KEnvelope(m *Matrix, days int) int {
 
 func TrueRange(cn *Matrix) int {
 	ret := cn.AddColumn()
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		highLow := c.High() - c.Low()
@@ -1659,13 +1660,13 @@ func Keltner(m *Matrix, ema, atrLength int, multiplier float64) int {
 	lowIdx := m.AddNamedColumn("Lower")
 	midIdx := m.AddNamedColumn("Mid")
 	tp := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i]
 		m.DataRows[i].Set(tp, (cur.Get(HIGH)+cur.Get(2)+cur.Get(ADJ_CLOSE))/3.0)
 	}
 	ed := EMA(m, ema, tp)
 	ad := ATR(m, atrLength)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(upIdx, m.DataRows[i].Get(ed)+multiplier*m.DataRows[i].Get(ad))
 		m.DataRows[i].Set(lowIdx, m.DataRows[i].Get(ed)-multiplier*m.DataRows[i].Get(ad))
 		m.DataRows[i].Set(midIdx, m.DataRows[i].Get(ed))
@@ -1684,7 +1685,7 @@ func DonchianChannel(m *Matrix, days int) int {
 	upIdx := m.AddNamedColumn("Upper")
 	lowIdx := m.AddNamedColumn("Lower")
 	midIdx := m.AddNamedColumn("Mid")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		h := m.FindMaxBetween(1, i-days, days)
 		l := m.FindMinBetween(2, i-days, days)
 		m.DataRows[i].Set(upIdx, h)
@@ -1699,7 +1700,7 @@ func DonchianChannelExt(m *Matrix, days, field int) int {
 	upIdx := m.AddNamedColumn("Upper")
 	lowIdx := m.AddNamedColumn("Lower")
 	midIdx := m.AddNamedColumn("Mid")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		h := m.FindMaxBetween(field, i-days, days)
 		l := m.FindMinBetween(field, i-days, days)
 		m.DataRows[i].Set(upIdx, h)
@@ -1721,7 +1722,7 @@ func RAR(prices *Matrix, days int) int {
 	tmp := prices.AddColumn()
 	ri := RSI(prices, days, ADJ_CLOSE)
 	ai := ATR(prices, days)
-	for _, p := range prices.DataRows {
+	for _, p := range prices.DataRows[:prices.CandleRows()] {
 		if p.Get(ai) != 0.0 {
 			p.Set(tmp, p.Get(ri)/p.Get(ai))
 		}
@@ -1746,7 +1747,7 @@ func RAR(prices *Matrix, days int) int {
 func WilliamsRange(m *Matrix, days int) int {
 	// 0 = %R
 	ret := m.AddColumn()
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		low := m.FindMinBetween(2, i-days+1, days)
 		high := m.FindMaxBetween(1, i-days+1, days)
 		dl := high - low
@@ -1769,11 +1770,11 @@ func MeanDistance(m *Matrix, lookback int) int {
 	ret := m.AddColumn()
 	d := m.AddColumn()
 	sd := SMA(m, lookback, 4)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(d, m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i].Get(sd))
 	}
 	n := RSI(m, lookback, d)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(n))
 	}
 	m.RemoveColumn()
@@ -1791,7 +1792,7 @@ func PER(m *Matrix, ema, smoothing int) int {
 	sp := m.AddColumn()
 	sm := m.AddColumn()
 	ed := EMA(m, ema, 4)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(ADJ_CLOSE)-m.DataRows[i].Get(ed))
 		if m.DataRows[i].Get(ed) != 0.0 {
 			m.DataRows[i].Set(sp, m.DataRows[i].Get(ret)/m.DataRows[i].Get(ed)*100.0)
@@ -1799,7 +1800,7 @@ func PER(m *Matrix, ema, smoothing int) int {
 	}
 
 	sma := SMA(m, smoothing, ret)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(sm, m.DataRows[i].Get(sma))
 	}
 	m.RemoveColumn()
@@ -1837,7 +1838,7 @@ func Candles(m *Matrix, sizeSmoothening int) int {
 	spreadIdx := m.AddColumn()
 	relSpreadIdx := m.AddColumn()
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		p := m.DataRows[i]
 		top := math.Max(p.Get(OPEN), p.Get(ADJ_CLOSE))
 		bottom := math.Min(p.Get(OPEN), p.Get(ADJ_CLOSE))
@@ -1869,13 +1870,13 @@ func Candles(m *Matrix, sizeSmoothening int) int {
 		m.DataRows[i].Set(bodyPos, (1.0-((p.Get(HIGH)-top)/td))*100.0)
 	}
 	sma := SMA(m, sizeSmoothening, bodyPos)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(sma) != 0.0 {
 			m.DataRows[i].Set(relAvg, m.DataRows[i].Get(bodySize)/m.DataRows[i].Get(sma)*100.0)
 		}
 	}
 	sma = SMA(m, sizeSmoothening, spreadIdx)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(sma) != 0.0 {
 			m.DataRows[i].Set(relSpreadIdx, m.DataRows[i].Get(spreadIdx)/m.DataRows[i].Get(sma)*100.0)
 		}
@@ -1897,7 +1898,7 @@ func CandleWicks(m *Matrix) int {
 	trendIdx := m.AddNamedColumn("Trend")
 	rngIdx := m.AddNamedColumn("Range")
 	bs := m.AddNamedColumn("BodySize")
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		p := m.DataRows[i]
 		top := math.Max(p.Get(OPEN), p.Get(ADJ_CLOSE))
 		bottom := math.Min(p.Get(OPEN), p.Get(ADJ_CLOSE))
@@ -1930,7 +1931,7 @@ func CandleWicks(m *Matrix) int {
 func StochasticBodySize(m *Matrix, days int) int {
 	ret := m.AddColumn()
 	cndIdx := Candles(m, days)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		low := m.FindMinBetween(cndIdx, i-days, days+1)
 		high := m.FindMaxBetween(cndIdx, i-days, days+1)
 		if high != low {
@@ -1959,7 +1960,7 @@ func RelativeVolume(m *Matrix, period int) int {
 	// 0 = normalized stochastic volume
 	ret := m.AddColumn()
 	si := StochasticExt(m, period, 3, VOLUME, VOLUME, VOLUME)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(si)/100.0)
 	}
 	m.RemoveColumn()
@@ -1987,33 +1988,39 @@ func Ichimoku(m *Matrix, short, mid, long int) int {
 	lsaIdx := m.AddColumn()
 	lsbIdx := m.AddColumn()
 	lsIdx := m.AddNamedColumn("Chikou")
+	// projected rows are only filled by the shifted spans
+	candles := m.CandleRows()
 	// Tenkan
-	for i := short; i < m.Rows; i++ {
+	for i := short; i < candles; i++ {
 		low := m.FindMinBetween(2, i-short, short)
 		high := m.FindMaxBetween(1, i-short, short)
 		m.DataRows[i].Set(ret, (high+low)/2.0)
 	}
 	// Kijun
-	for i := mid; i < m.Rows; i++ {
+	for i := mid; i < candles; i++ {
 		low := m.FindMinBetween(2, i-mid, mid)
 		high := m.FindMaxBetween(1, i-mid, mid)
 		m.DataRows[i].Set(midIdx, (high+low)/2.0)
 	}
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < candles; i++ {
 		m.DataRows[i].Set(lsaIdx, (m.DataRows[i].Get(ret)+m.DataRows[i].Get(midIdx))/2.0)
 	}
-	m.Shift(lsaIdx, 26)
+	// Shift adds a new column so the shifted values are copied back
+	m.CopyColumn(m.Shift(lsaIdx, 26), lsaIdx)
+	m.RemoveColumn()
 
-	for i := long; i < m.Rows; i++ {
+	for i := long; i < candles; i++ {
 		low := m.FindMinBetween(2, i-long, long)
 		high := m.FindMaxBetween(1, i-long, long)
 		m.DataRows[i].Set(lsbIdx, (high+low)/2.0)
 	}
-	m.Shift(lsbIdx, 26)
+	m.CopyColumn(m.Shift(lsbIdx, 26), lsbIdx)
+	m.RemoveColumn()
 	// Chikou - shift price 26 periods back
 	m.CopyColumn(4, lsIdx)
-	m.Shift(lsIdx, -26)
+	m.CopyColumn(m.Shift(lsIdx, -26), lsIdx)
+	m.RemoveColumn()
 	//for i := 0; i < m.Rows-mid; i++ {
 	//	m.DataRows[i].Set(lsIdx, m.DataRows[i+mid].Get(ADJ_CLOSE))
 	//}
@@ -2029,7 +2036,7 @@ func Ichimoku(m *Matrix, short, mid, long int) int {
 func WeightedTrendIntensity(m *Matrix, period int) int {
 	ret := m.AddColumn()
 	n := float64(period)
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < period; j++ {
 			if m.DataRows[i-j].Get(ADJ_CLOSE) > m.DataRows[i-j].Get(0) {
@@ -2085,7 +2092,7 @@ func Supertrend(m *Matrix, period int, multiplier float64) int {
 		prevLowerBand = nz(lowerBand[1])
 		prevUpperBand = nz(upperBand[1])
 	*/
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		m.DataRows[i].Set(buIdx, (cp.Get(HIGH)+cp.Get(2))/2.0+multiplier*cp.Get(atrIdx))
 		m.DataRows[i].Set(blIdx, (cp.Get(HIGH)+cp.Get(2))/2.0-multiplier*cp.Get(atrIdx))
@@ -2093,7 +2100,7 @@ func Supertrend(m *Matrix, period int, multiplier float64) int {
 
 	//lowerBand := lowerBand > prevLowerBand or close[1] < prevLowerBand ? lowerBand : prevLowerBand
 	//upperBand := upperBand < prevUpperBand or close[1] > prevUpperBand ? upperBand : prevUpperBand
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		pp := m.DataRows[i-1]
 
@@ -2122,7 +2129,7 @@ func Supertrend(m *Matrix, period int, multiplier float64) int {
 	   		direction := close < lowerBand ? 1 : -1
 	   	superTrend := direction == -1 ? lowerBand : upperBand
 	*/
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		pp := m.DataRows[i-1]
 
@@ -2154,7 +2161,7 @@ func Supertrend(m *Matrix, period int, multiplier float64) int {
 func GAP_ATR(m *Matrix) int {
 	ret := m.AddColumn()
 	atrIdx := ATR(m, 14)
-	for i := 15; i < m.Rows; i++ {
+	for i := 15; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		prev := m.DataRows[i-1]
 		value := (cp.Get(0) - prev.Get(ADJ_CLOSE)) / cp.Get(atrIdx) * 100.0
@@ -2170,7 +2177,7 @@ func GAP(m *Matrix) int {
 	ret := m.AddNamedColumn("GAP")
 	gi := m.AddNamedColumn("GAP/ATR")
 	ai := ATR(m, 14)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		cp := m.DataRows[i]
 		prev := m.DataRows[i-1]
 		value := (cp.Get(0)/prev.Get(ADJ_CLOSE) - 1.0) * 100.0
@@ -2186,7 +2193,7 @@ func GAP(m *Matrix) int {
 func PriceATR(prices *Matrix, period int) int {
 	ret := prices.AddColumn()
 	atrIdx := ATR(prices, period)
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		cp := prices.DataRows[i]
 		prev := prices.DataRows[i-1]
 		sl := m.Abs(cp.Get(ADJ_CLOSE) - prev.Get(ADJ_CLOSE))
@@ -2200,7 +2207,7 @@ func PriceATR(prices *Matrix, period int) int {
 func RangeATR(prices *Matrix, period int) int {
 	ret := prices.AddColumn()
 	atrIdx := ATR(prices, period)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		cp := prices.DataRows[i]
 		value := (1.0 - (cp.Get(HIGH)-cp.Get(LOW))/cp.Get(atrIdx))
 		prices.DataRows[i].Set(ret, value)
@@ -2217,7 +2224,7 @@ func KRI(m *Matrix, period int) int {
 	// 0 = KRI
 	ret := m.AddColumn()
 	si := SMA(m, period, ADJ_CLOSE)
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		sma := m.DataRows[i].Get(si)
 		if sma != 0.0 {
 			d := (m.DataRows[i].Get(ADJ_CLOSE) - sma) / sma * 100.0
@@ -2249,7 +2256,7 @@ func STDChannel(prices *Matrix, days int, std float64) int {
 	ui := prices.AddColumn()
 	li := prices.AddColumn()
 	s := prices.StdDev(4, days)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		prices.DataRows[i].Set(ui, prices.DataRows[i].Get(ADJ_CLOSE)+std*prices.DataRows[i].Get(s))
 		prices.DataRows[i].Set(li, prices.DataRows[i].Get(ADJ_CLOSE)-std*prices.DataRows[i].Get(s))
 	}
@@ -2265,7 +2272,7 @@ func SupportResistanceChannel(prices *Matrix, days int, std float64) int {
 	// 0 = Upper 1 = Lower
 	ma := prices.AddColumn()
 	mi := prices.AddColumn()
-	for i := days; i < prices.Rows; i++ {
+	for i := days; i < prices.CandleRows(); i++ {
 		lm := prices.FindMinBetween(LOW, i-days, days)
 		r := prices.FindMaxBetween(HIGH, i-days, days) - lm
 		upper := lm + r*(1.0-std)
@@ -2296,7 +2303,7 @@ func DeMark(candles *Matrix) int {
 	count := candles.AddColumn()
 	ct := 0
 	cur := 1
-	for i := 4; i < candles.Rows; i++ {
+	for i := 4; i < candles.CandleRows(); i++ {
 		c := candles.DataRows[i].Get(ADJ_CLOSE)
 		p := candles.DataRows[i-4].Get(ADJ_CLOSE)
 		t := 0
@@ -2334,7 +2341,7 @@ func DeMarker(m *Matrix, period int) int {
 	deMax := make([]float64, m.Rows)
 	deMin := make([]float64, m.Rows)
 
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		dh := m.DataRows[i].High() - m.DataRows[i-1].High()
 		dl := m.DataRows[i-1].Low() - m.DataRows[i].Low()
 		if dh > 0 {
@@ -2366,7 +2373,7 @@ func BullishBearish(m *Matrix, period int) int {
 	ti := m.AddColumn()
 	hi := m.AddColumn()
 	li := m.AddColumn()
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		bu := 0
 		tr := 0
 		h := 0
@@ -2405,11 +2412,11 @@ func BullishBearish(m *Matrix, period int) int {
 func OBV(m *Matrix, scale float64) int {
 	// 0 = OBV
 	ret := m.AddColumn()
-	if m.Rows > 2 {
+	if m.CandleRows() > 2 {
 		prev := m.DataRows[0]
 		obv := prev.Get(5)
 		sign := 1.0
-		for i, p := range m.DataRows {
+		for i, p := range m.DataRows[:m.CandleRows()] {
 			if i > 0 {
 				if p.Get(ADJ_CLOSE) > prev.Get(ADJ_CLOSE) {
 					sign = 1.0
@@ -2441,7 +2448,7 @@ func Aroon(m *Matrix, days int) int {
 	//upper = 100 * (ta.highestbars(high, length + 1) + length)/length
 	//lower = 100 * (ta.lowestbars(low, length + 1) + length)/length
 	n := float64(days)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		h := m.FindHighestIndex(i, days)
 		l := m.FindLowestIndex(i, days)
 		m.DataRows[i].Set(ui, (float64(h)+n)/n*100.0)
@@ -2462,7 +2469,7 @@ func TrendIntensity(m *Matrix, days int) int {
 	// 0 = TS
 	ret := m.AddColumn()
 	sma := SMA(m, days, 4)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		tu := 0.0
 		tl := 0.0
 		for j := 0; j < days; j++ {
@@ -2495,7 +2502,7 @@ func AD(m *Matrix) int {
 	// ad = ta.cum(close==high and close==low or high==low ? 0 : ((2*close-low-high)/(high-low))*volume)
 	ret := m.AddColumn()
 	ad := 0.0
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		// Acc./Distr. Line =[((C-L) - (H-C))/(H-L) * V] + I
 		cur := m.DataRows[i]
 		high := cur.Get(HIGH)
@@ -2523,7 +2530,7 @@ func TSI(m *Matrix, short, long, signal int) int {
 	di := m.AddNamedColumn("Diff")
 	mi := m.AddColumn()
 	// PC = CCP − PCP
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i]
 		prev := m.DataRows[i-1]
 		m.DataRows[i].Set(mi, cur.Get(ADJ_CLOSE)-prev.Get(ADJ_CLOSE))
@@ -2532,14 +2539,14 @@ func TSI(m *Matrix, short, long, signal int) int {
 	pcds := EMA(m, short, pcs)
 
 	ami := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ami, math.Abs(m.DataRows[i].Get(mi)))
 	}
 
 	apcs := EMA(m, long, ami)
 	apcds := EMA(m, short, apcs)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		tsi := 0.0
 		if m.DataRows[i].Get(apcds) != 0.0 {
 			tsi = 100.0 * (m.DataRows[i].Get(pcds) / m.DataRows[i].Get(apcds))
@@ -2549,7 +2556,7 @@ func TSI(m *Matrix, short, long, signal int) int {
 
 	tsiEMA := EMA(m, signal, ret)
 
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(si, m.DataRows[i].Get(tsiEMA))
 		m.DataRows[i].Set(di, m.DataRows[i].Get(ret)-m.DataRows[i].Get(tsiEMA))
 	}
@@ -2565,7 +2572,7 @@ func TSI(m *Matrix, short, long, signal int) int {
 func Divergence(m *Matrix, first, second, period int) int {
 	// 0 = Divergence (1=bullish -1=bearish)
 	ret := m.AddColumn()
-	for i := period; i < m.Rows-1; i++ {
+	for i := period; i < m.CandleRows()-1; i++ {
 		cur := m.DataRows[i]
 		next := m.DataRows[i+1]
 		mi := m.FindMinBetween(first, i-period, period)
@@ -2609,7 +2616,7 @@ func HighestLowestChannel(m *Matrix, period int) int {
 	// 0 = High 1 = Low
 	hi := m.AddNamedColumn("High")
 	li := m.AddNamedColumn("Low")
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(hi, m.FindMaxBetween(HIGH, i-period, period))
 		m.DataRows[i].Set(li, m.FindMinBetween(LOW, i-period, period))
 	}
@@ -2628,7 +2635,7 @@ func ADX(m *Matrix, lookback int) int {
 	pdi := m.AddNamedColumn("PDI")
 	mdi := m.AddNamedColumn("MDI")
 	di := m.AddNamedColumn("Diff")
-	if m.Rows > 2*lookback {
+	if m.CandleRows() > 2*lookback {
 		plusDM := m.AddColumn()
 		minusDM := m.AddColumn()
 		tr := m.AddColumn()
@@ -2643,7 +2650,7 @@ func ADX(m *Matrix, lookback int) int {
 		fa = append(fa, 0.0)
 		fa = append(fa, 0.0)
 		fa = append(fa, 0.0)
-		for i := 1; i < m.Rows; i++ {
+		for i := 1; i < m.CandleRows(); i++ {
 			c := m.DataRows[i]
 			p := m.DataRows[i-1]
 			// WENN(CH-PH>PL-CL;MAX(CH-PH;0);0)
@@ -2670,7 +2677,7 @@ func ADX(m *Matrix, lookback int) int {
 		}
 
 		m.DataRows[lookback-1].Set(trur, m.PartialSum(tr, 0, lookback))
-		for i, t := range m.DataRows {
+		for i, t := range m.DataRows[:m.CandleRows()] {
 			if i >= lookback {
 				p := m.DataRows[i-1].Get(trur)
 				c := t.Get(tr)
@@ -2682,7 +2689,7 @@ func ADX(m *Matrix, lookback int) int {
 		m.DataRows[lookback-1].Set(pdm14, m.PartialSum(plusDM, 0, lookback))
 		m.DataRows[lookback-1].Set(mdm14, m.PartialSum(minusDM, 0, lookback))
 
-		for i, d := range m.DataRows {
+		for i, d := range m.DataRows[:m.CandleRows()] {
 			if i >= lookback {
 				pp := m.DataRows[i-1].Get(pdm14)
 				pm := m.DataRows[i-1].Get(mdm14)
@@ -2690,7 +2697,7 @@ func ADX(m *Matrix, lookback int) int {
 				m.DataRows[i].Set(mdm14, pm-(pm/lbf)+d.Get(minusDM))
 			}
 		}
-		for i, d := range m.DataRows {
+		for i, d := range m.DataRows[:m.CandleRows()] {
 			if i >= (lookback - 1) {
 				p := 100.0 * (d.Get(pdm14) / d.Get(trur))
 				m.DataRows[i].Set(pd14, p)
@@ -2708,7 +2715,7 @@ func ADX(m *Matrix, lookback int) int {
 		avg := m.PartialSum(dx, 0, 2*lookback+1) / lbf
 		//nr.Set("ADX", avg)
 		pa := avg
-		for i := 26; i < m.Rows; i++ {
+		for i := 26; i < m.CandleRows(); i++ {
 			v := (pa*(lbf-1.0) + m.DataRows[i].Get(dx)) / lbf
 			m.DataRows[i].Set(pdi, m.DataRows[i].Get(pd14))
 			m.DataRows[i].Set(mdi, m.DataRows[i].Get(md14))
@@ -2734,7 +2741,7 @@ func RVI(m *Matrix, lookback int) int {
 	dem := SWMA(m, hl)
 	sn := m.Sum(num, lookback)
 	dn := m.Sum(dem, lookback)
-	for i := lookback; i < m.Rows; i++ {
+	for i := lookback; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(dn) != 0.0 {
 			m.DataRows[i].Set(li, m.DataRows[i].Get(sn)/m.DataRows[i].Get(dn))
 		}
@@ -2760,7 +2767,7 @@ func KD(m *Matrix, period int) int {
 	ret := m.AddColumn()
 	ki := m.AddColumn()
 	di := m.AddColumn()
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i]
 		mi := m.FindMinBetween(4, i-period, period)
 		ma := m.FindMaxBetween(4, i-period, period)
@@ -2787,7 +2794,7 @@ func DPO(m *Matrix, period int) int {
 	ret := m.AddColumn()
 	si := SMA(m, period, 4)
 	k := period/2 + 1
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i]
 		prev := m.DataRows[i-k]
 		m.DataRows[i].Set(ret, prev.Get(ADJ_CLOSE)-cur.Get(si))
@@ -2806,7 +2813,7 @@ func MACD_BB(m *Matrix, short, long, period int, s float64) int {
 	d := m.Subtract(e1, e2)
 	si := SMA(m, period, d)
 	std := m.StdDev(si, period)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i]
 		m.DataRows[i].Set(upper, cur.Get(si)+s*cur.Get(std))
 		m.DataRows[i].Set(lower, cur.Get(si)-s*cur.Get(std))
@@ -2832,7 +2839,7 @@ func CCI(m *Matrix, days, smoothed int) int {
 	*/
 	sma := SMA(m, days, hlc)
 	std := m.StdDev(hlc, days)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		ma := m.DataRows[i].Get(sma)
 		hlc := m.DataRows[i].Get(hlc)
 		s := m.DataRows[i].Get(std)
@@ -2853,11 +2860,11 @@ func VWAP(m *Matrix, days int, std float64) int {
 	upper := m.AddColumn()
 	lower := m.AddColumn()
 	hlc := m.AddColumn()
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		p := m.DataRows[i]
 		m.DataRows[i].Set(hlc, (p.Get(HIGH)+p.Get(2)+p.Get(ADJ_CLOSE))/3.0*p.Get(5))
 	}
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		sumTP := 0.0
 		sumV := 0.0
 		for j := 0; j < days; j++ {
@@ -2868,7 +2875,7 @@ func VWAP(m *Matrix, days int, std float64) int {
 		m.DataRows[i].Set(ret, vw)
 	}
 	si := m.StdDev(ret, days)
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(upper, m.DataRows[i].Get(ret)+std*m.DataRows[i].Get(si))
 		m.DataRows[i].Set(lower, m.DataRows[i].Get(ret)-std*m.DataRows[i].Get(si))
 	}
@@ -2909,12 +2916,12 @@ func DOSC(m *Matrix, r, e1, e2, s, sl int) int {
 	ei2 := EMA(m, e2, ei1)
 	smi := SMA(m, s, ei2)
 	start := e1 + e2 + s
-	for i := start; i < m.Rows; i++ {
+	for i := start; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(ret, m.DataRows[i].Get(ei2)-m.DataRows[i].Get(smi))
 	}
 	si2 := SMA(m, sl, ret)
 	start += sl
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(si, m.DataRows[i].Get(si2))
 	}
 	m.RemoveColumn()
@@ -2943,7 +2950,7 @@ func HeikinAshi(m *Matrix) int {
 	c.Set(ci, 0.25*(c.Get(0)+c.Get(HIGH)+c.Get(2)+c.Get(ADJ_CLOSE)))
 	c.Set(hi, c.Get(HIGH))
 	c.Set(li, c.Get(2))
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c = &m.DataRows[i]
 		prev := m.DataRows[i-1]
 		// Open = [Open (previous bar) + Close (previous bar)] /2
@@ -2974,11 +2981,11 @@ func HMA(prices *Matrix, period, field int) int {
 	wi1 := WMA(prices, period/2, field)
 	wi2 := WMA(prices, period, field)
 	ri := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		prices.DataRows[i].Set(ri, 2.0*prices.DataRows[i].Get(wi1)-prices.DataRows[i].Get(wi2))
 	}
 	ri2 := WMA(prices, int(m.Sqrt(float64(period))), ri)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		prices.DataRows[i].Set(ret, prices.DataRows[i].Get(ri2))
 	}
 	prices.RemoveColumn()
@@ -2995,7 +3002,7 @@ func HMA(prices *Matrix, period, field int) int {
 func COG(m *Matrix, period int) int {
 	// 0 = COG
 	ret := m.AddColumn()
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		s1 := 0.0
 		s2 := 0.0
 		for j := 0; j < period; j++ {
@@ -3016,7 +3023,7 @@ func GRI(prices *Matrix, period int) int {
 	// 0 = GRI
 	ret := prices.AddColumn()
 	n := float64(period)
-	for i := period; i < prices.Rows; i++ {
+	for i := period; i < prices.CandleRows(); i++ {
 		h, l := prices.FindHighestHighLowestLow(i-period, period)
 		v := m.Log(h-l) / m.Log(n)
 		prices.DataRows[i].Set(ret, v)
@@ -3032,7 +3039,7 @@ func CMF(prices *Matrix, period int) int {
 	// 0 = CMF
 	ret := prices.AddColumn()
 	mf := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		cp := prices.DataRows[i]
 		hl := cp.Get(HIGH) - cp.Get(2)
 		if hl != 0.0 {
@@ -3041,7 +3048,7 @@ func CMF(prices *Matrix, period int) int {
 			prices.DataRows[i].Set(mf, m*cp.Get(5))
 		}
 	}
-	for i := period; i < prices.Rows; i++ {
+	for i := period; i < prices.CandleRows(); i++ {
 		s1 := 0.0
 		s2 := 0.0
 		for j := i - period; j < i; j++ {
@@ -3079,7 +3086,7 @@ func STC(prices *Matrix, short, long, cycle, firstLength, secondLength int) int
 	//stc = ema(kd, d2Length)
 	stc := EMA(prices, secondLength, kd)
 
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := &prices.DataRows[i]
 		// stc := max(min(stc, 100), 0)
 		cv := c.Get(stc)
@@ -3117,25 +3124,25 @@ func FindMajorLevels(prices *Matrix, threshold float64) *MajorLevels {
 func FindMajorGaps(prices *Matrix, threshold float64) *MajorLevels {
 	levels := NewMajorLevels(threshold)
 	gi := GAP(prices)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		cp := prices.DataRows[i]
 		if cp.Get(gi) > threshold || cp.Get(gi) < -threshold {
-			levels.Add(cp.Get(gi+1), 1, cp.Key)
-			levels.Add(cp.Get(gi+2), 1, cp.Key)
+			// the gap is between the previous close and the open
+			levels.Add(prices.DataRows[i-1].Get(ADJ_CLOSE), 1, cp.Key)
+			levels.Add(cp.Get(OPEN), 1, cp.Key)
 		}
 	}
-	prices.RemoveColumn()
-	prices.RemoveColumn()
+	// GAP only leaves one column
 	prices.RemoveColumn()
 	return levels
 }
 
 func FindInsideBarMajorLevels(prices *Matrix, threshold float64) *MajorLevels {
 	levels := NewMajorLevels(1.0)
-	for i := prices.Rows - 2; i >= 0; i-- {
+	for i := prices.CandleRows() - 2; i >= 0; i-- {
 		cur := prices.DataRows[i]
 		cnt := 0
-		for j := i + 1; j < prices.Rows; j++ {
+		for j := i + 1; j < prices.CandleRows(); j++ {
 			th := prices.DataRows[j]
 			if cur.Get(HIGH) > th.Get(HIGH) && cur.Get(2) < th.Get(2) {
 				cnt++
@@ -3164,7 +3171,7 @@ func FindInsideBarMajorLevels(prices *Matrix, threshold float64) *MajorLevels {
 
 func FindFibonacciLevels(prices *Matrix, lookback int) *MajorLevels {
 	// 0 = High 1 = Low 2 = PP 3 = 23.6 4 = 38.2 5 = 61.8 6 = 78.6
-	h, l := prices.FindHighestHighLowestLow(prices.Rows-lookback, lookback)
+	h, l := prices.FindHighestHighLowestLow(prices.CandleRows()-lookback, lookback)
 	levels := NewMajorLevels(0.0)
 	cur := prices.Last().Key
 	pp := (h + l) / 2.0
@@ -3191,7 +3198,7 @@ func Choppiness(prices *Matrix, days int) int {
 	ret := prices.AddColumn()
 	atr := ATR(prices, 1)
 	// CI14 = 100 * LOG10 [14D ATR1 SUM/(14D HIGHH - 14D LOWL)] / LOG10(14)
-	for i := days; i < prices.Rows; i++ {
+	for i := days; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < days; j++ {
 			cp := prices.DataRows[i-j]
@@ -3213,7 +3220,7 @@ func Choppiness(prices *Matrix, days int) int {
 func Slope(prices *Matrix, days, field int) int {
 	// 0 = Slope
 	ret := prices.AddColumn()
-	for i := days; i < prices.Rows; i++ {
+	for i := days; i < prices.CandleRows(); i++ {
 		cur := prices.DataRows[i].Get(field)
 		prev := prices.DataRows[i-days].Get(field)
 		prices.DataRows[i].Set(ret, cur-prev)
@@ -3224,7 +3231,7 @@ func Slope(prices *Matrix, days, field int) int {
 func Spread(prices *Matrix, lookback int) int {
 	// 0 = Spread 1 = Spread Stoch K 2 = Spread Stoch K
 	ret := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		cur := prices.DataRows[i]
 		prices.DataRows[i].Set(ret, cur.Get(HIGH)-cur.Get(LOW))
 	}
@@ -3247,7 +3254,7 @@ func GMMA(prices *Matrix) int {
 		fe = append(fe, EMA(prices, f, 4))
 	}
 	fa := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < len(fe); j++ {
 			sum += prices.DataRows[i].Get(fe[j])
@@ -3261,7 +3268,7 @@ func GMMA(prices *Matrix) int {
 		se = append(se, EMA(prices, f, 4))
 	}
 	sa := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < len(se); j++ {
 			sum += prices.DataRows[i].Get(se[j])
@@ -3269,7 +3276,7 @@ func GMMA(prices *Matrix) int {
 		prices.DataRows[i].Set(sa, sum)
 	}
 
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		if prices.DataRows[i].Get(sa) != 0.0 {
 			d := (prices.DataRows[i].Get(fa) - prices.DataRows[i].Get(sa)) / prices.DataRows[i].Get(sa) * 100.0
 			prices.DataRows[i].Set(ret, d)
@@ -3290,7 +3297,7 @@ func GMMA(prices *Matrix) int {
 func Volatility(m *Matrix, lookback, field int) int {
 	// 0 = Volatility
 	ret := m.AddColumn()
-	for i := lookback; i < m.Rows; i++ {
+	for i := lookback; i < m.CandleRows(); i++ {
 		s := standardAbbreviation(m, m.DataRows[i].Get(field), i-lookback, lookback)
 		m.DataRows[i].Set(ret, s)
 	}
@@ -3307,21 +3314,21 @@ func ZScore(m *Matrix, lookback, field int) int {
 }
 
 func NormalizeZScore(m *Matrix, field int) int {
-	n := m.Rows
+	n := m.CandleRows()
 	if n == 0 {
 		return -1
 	}
 	ret := m.AddColumn()
 	// Compute mean
 	var sum float64
-	for _, v := range m.DataRows {
+	for _, v := range m.DataRows[:m.CandleRows()] {
 		sum += v.Get(field)
 	}
 	mean := sum / float64(n)
 
 	// Compute standard deviation
 	var variance float64
-	for _, v := range m.DataRows {
+	for _, v := range m.DataRows[:m.CandleRows()] {
 		diff := v.Get(field) - mean
 		variance += diff * diff
 	}
@@ -3334,7 +3341,7 @@ func NormalizeZScore(m *Matrix, field int) int {
 
 	// Normalize
 	//normalized := make([]float64, n)
-	for i, v := range m.DataRows {
+	for i, v := range m.DataRows[:m.CandleRows()] {
 		m.DataRows[i].Set(ret, (v.Get(field)-mean)/stdDev)
 	}
 
@@ -3348,7 +3355,7 @@ func ZNormalization(m *Matrix, lookback, field int) int {
 	ret := m.AddColumn()
 	sma := SMA(m, lookback, field)
 	std := m.StdDev(field, lookback)
-	for i := lookback; i < m.Rows; i++ {
+	for i := lookback; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(std) != 0.0 {
 			s := (m.DataRows[i].Get(field) - m.DataRows[i].Get(sma)) / m.DataRows[i].Get(std)
 			m.DataRows[i].Set(ret, s)
@@ -3364,7 +3371,7 @@ func Normalization(m *Matrix, lookback, field int) int {
 	ret := m.AddColumn()
 	hi := Highest(m, lookback, field)
 	li := Lowest(m, lookback, field)
-	for i := lookback; i < m.Rows; i++ {
+	for i := lookback; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		h := c.Get(hi)
 		l := c.Get(li)
@@ -3412,7 +3419,7 @@ func TripleEMA(m *Matrix, l1, l2, l3 int) int {
 	e1 := EMA(m, l1, 4)
 	e2 := EMA(m, l2, 4)
 	e3 := EMA(m, l3, 4)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		sum := 0.0
@@ -3459,14 +3466,14 @@ func TRIX(prices *Matrix, lookback int) int {
 	// 0 = TRIX 1 = Signal
 	ret := prices.AddColumn()
 	li := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		prices.DataRows[i].Set(li, m.Log(c.Get(ADJ_CLOSE)))
 	}
 	e1 := EMA(prices, lookback, li)
 	e2 := EMA(prices, lookback, e1)
 	e3 := EMA(prices, lookback, e2)
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		if p.Get(e3) != 0.0 {
@@ -3593,7 +3600,7 @@ func SqueezeMomentum(prices *Matrix, length int, std, k1, k2, k3 float64) int {
 	//HighSqz = BB_lower >= KC_lower_high or BB_upper <= KC_upper_high //HIGH COMPRESSION: ORANGE
 
 	//kc := Keltner(prices, keltner, keltner, mulKC)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		//NoSqz = BB_lower < KC_lower_low or BB_upper > KC_upper_low //NO SQUEEZE
 		if c.Get(bb+1) < c.Get(ki3+1) || c.Get(bb) > c.Get(ki3+1) {
@@ -3620,7 +3627,7 @@ func SqueezeMomentum(prices *Matrix, length int, std, k1, k2, k3 float64) int {
 		Step 7: Calculate the delta between the closing price and the mean between the result from step 5 and 6.
 	*/
 	di := prices.AddColumn()
-	for i := length; i < prices.Rows; i++ {
+	for i := length; i < prices.CandleRows(); i++ {
 		h, l := prices.FindHighLowIndex(i-length, length)
 		d := (prices.DataRows[h].Get(ADJ_CLOSE) + prices.DataRows[l].Get(ADJ_CLOSE)) / 2.0
 		prices.DataRows[i].Set(di, d)
@@ -3628,7 +3635,7 @@ func SqueezeMomentum(prices *Matrix, length int, std, k1, k2, k3 float64) int {
 	}
 
 	si := SMA(prices, length, 4)
-	for i := length; i < prices.Rows; i++ {
+	for i := length; i < prices.CandleRows(); i++ {
 		d := prices.DataRows[i].Get(ADJ_CLOSE) - (prices.DataRows[i].Get(di)+prices.DataRows[i].Get(si))/2.0
 		prices.DataRows[i].Set(li, d)
 
@@ -3646,7 +3653,7 @@ func TTMSqueeze(prices *Matrix, length int, std, kc float64) int {
 	bb := BollingerBand(prices, length, std, std)
 	ki := Keltner(prices, length, length, kc)
 
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		if c.Get(bb+1) < c.Get(ki+1) && c.Get(bb) > c.Get(ki) {
 			prices.DataRows[i].Set(ret, 0.0)
@@ -3680,14 +3687,14 @@ plot(slopeD,color=black)
 func SpreadRangeRelation(prices *Matrix, lookback int) int {
 	ret := prices.AddColumn()
 	sri := prices.AddColumn()
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		sr := m.Max(c.Get(HIGH), p.Get(ADJ_CLOSE)) - m.Min(c.Get(2), p.Get(ADJ_CLOSE))
 		prices.DataRows[i].Set(sri, sr)
 	}
 	sui := prices.AddColumn()
-	for i := lookback - 1; i < prices.Rows; i++ {
+	for i := lookback - 1; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < lookback; j++ {
 			sum += prices.DataRows[i-j].Get(sri)
@@ -3695,7 +3702,7 @@ func SpreadRangeRelation(prices *Matrix, lookback int) int {
 		prices.DataRows[i].Set(sui, sum)
 	}
 
-	for i := lookback; i < prices.Rows; i++ {
+	for i := lookback; i < prices.CandleRows(); i++ {
 		h, l := prices.FindHighestHighLowestLow(i-lookback, lookback)
 		lr := h - l
 		r := m.Log(prices.DataRows[i].Get(sui)/lr) / m.Log(float64(lookback))
@@ -3714,12 +3721,12 @@ func HistoricalVolatility(prices *Matrix, lookback int) int {
 	ret := prices.AddColumn()
 	si := SMA(prices, lookback, 4)
 	di := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		sr := c.Get(ADJ_CLOSE) - c.Get(si)
 		prices.DataRows[i].Set(di, sr*sr)
 	}
-	for i := lookback; i < prices.Rows; i++ {
+	for i := lookback; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < lookback; j++ {
 			sum += prices.DataRows[i-j].Get(di)
@@ -3738,10 +3745,10 @@ func MinerviniScore(prices *Matrix) int {
 	sma150 := SMA(prices, 150, 4)
 	sma200 := SMA(prices, 200, 4)
 	rsi := RSI(prices, 14, 4)
-	for i := 20; i < prices.Rows; i++ {
+	for i := 20; i < prices.CandleRows(); i++ {
 		cp := prices.DataRows[i]
 		price := cp.Get(ADJ_CLOSE)
-		low, high := prices.FindMinMaxBetween(4, prices.Rows-250, prices.Rows)
+		low, high := prices.FindMinMaxBetween(4, prices.CandleRows()-250, prices.CandleRows())
 		cnt := 0.0
 		if price > cp.Get(sma150) && price > cp.Get(sma200) {
 			cnt += 1.0
@@ -3781,7 +3788,7 @@ func PriceMovingAverageDistance(prices *Matrix, f MAFunc, length int) int {
 	ret := prices.AddNamedColumn("PMADiff")
 	per := prices.AddNamedColumn("PMADiffPer")
 	si := f(prices, length, ADJ_CLOSE)
-	for i := length; i < prices.Rows; i++ {
+	for i := length; i < prices.CandleRows(); i++ {
 		prices.DataRows[i].Set(ret, prices.DataRows[i].Get(ADJ_CLOSE)-prices.DataRows[i].Get(si))
 		prices.DataRows[i].Set(per, ChangePercentage(prices.DataRows[i].Get(ADJ_CLOSE), prices.DataRows[i].Get(si)))
 	}
@@ -3792,7 +3799,7 @@ func PriceMovingAverageDistance(prices *Matrix, f MAFunc, length int) int {
 func RS(prices *Matrix, index *Matrix) int {
 	// 0 = Strength
 	ret := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		row := index.FindRow(prices.DataRows[i].Key)
 		if row != nil && row.Get(ADJ_CLOSE) != 0.0 {
 			prices.DataRows[i].Set(ret, prices.DataRows[i].Get(ADJ_CLOSE)/row.Get(ADJ_CLOSE)*1000.0)
@@ -3804,7 +3811,7 @@ func RS(prices *Matrix, index *Matrix) int {
 func IntradayIntensityTrend(prices *Matrix) int {
 	// 0 = IIT
 	ret := prices.AddColumn()
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		u := 2.0*c.Get(ADJ_CLOSE) - c.Get(HIGH) - c.Get(LOW)
 		l := (c.Get(HIGH) - c.Get(LOW)) * c.Get(VOLUME)
@@ -3819,7 +3826,7 @@ func IntradayIntensityTrend(prices *Matrix) int {
 func PercentRank(prices *Matrix, period, field int) int {
 	// 0 = PercentRank
 	ret := prices.AddNamedColumn("PercentRank")
-	for i := period; i < prices.Rows; i++ {
+	for i := period; i < prices.CandleRows(); i++ {
 		ref := prices.DataRows[i].Get(field)
 		cnt := 0.0
 		for j := 1; j < period; j++ {
@@ -3836,7 +3843,7 @@ func TSV(prices *Matrix) int {
 	period := 13
 	ret := prices.AddNamedColumn("TSV")
 	tmp := prices.AddColumn()
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		d := c.Get(ADJ_CLOSE) - p.Get(ADJ_CLOSE)
@@ -3846,7 +3853,7 @@ func TSV(prices *Matrix) int {
 		}
 		c.Set(tmp, sig*m.Abs(d)*c.Get(VOLUME))
 	}
-	for i := period; i < prices.Rows; i++ {
+	for i := period; i < prices.CandleRows(); i++ {
 		sum := 0.0
 		for j := 0; j < period; j++ {
 			sum += prices.DataRows[i-j].Get(tmp)
@@ -3888,7 +3895,7 @@ func FisherTransform(prices *Matrix, period int) int {
 	trig := prices.AddNamedColumn("FT-Trigger")
 	tmp := prices.AddColumn()
 	hl2 := HL2(prices)
-	for i := period; i < prices.Rows; i++ {
+	for i := period; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		l, h := prices.FindMinMaxBetween(hl2, i-period, period)
@@ -3914,7 +3921,7 @@ func LaguerreRSI(prices *Matrix, alpha float64) int {
 	l2s := prices.AddColumn()
 	l3s := prices.AddColumn()
 	gamma := 1.0 - alpha
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		// L0 := (1-gamma) * src + gamma * nz(L0[1])
@@ -3975,7 +3982,7 @@ func LaguerreFilter(prices *Matrix, gamma float64) int {
 	l1s := prices.AddColumn()
 	l2s := prices.AddColumn()
 	l3s := prices.AddColumn()
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		c.Set(l0s, (1.0-gamma)*c.Get(ADJ_CLOSE)+gamma*p.Get(l0s))
@@ -4045,7 +4052,7 @@ func ALMA(prices *Matrix, windowSize int, offset, sigma float64) int {
 	mv := offset * float64((windowSize - 1))
 	//m = math.floor(offset * (windowsize - 1)) // Used as m when math.floor=true
 	s := float64(windowSize) / sigma
-	for i := windowSize; i < prices.Rows; i++ {
+	for i := windowSize; i < prices.CandleRows(); i++ {
 		norm := 0.0
 		sum := 0.0
 		for j := 0; j < windowSize; j++ {
@@ -4077,7 +4084,7 @@ func AnalyzeTrend(prices *Matrix, field int) int {
 	ret := prices.AddNamedColumn("Count")
 	ci := prices.AddNamedColumn("Trend")
 	dir := 0
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		cnt := 1
 		if prices.DataRows[i].Get(field) >= 0.0 {
 			dir = 1
@@ -4109,7 +4116,7 @@ func AnalyzeTrendRange(prices *Matrix, field int, lower, upper float64) int {
 	ret := prices.AddNamedColumn("Count")
 	ci := prices.AddNamedColumn("Trend")
 	dir := 0
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		cnt := 1
 		if prices.DataRows[i].Get(field) >= upper {
 			dir = 1
@@ -4146,7 +4153,7 @@ func AnalyzeTrendRange(prices *Matrix, field int, lower, upper float64) int {
 func VPT(prices *Matrix) int {
 	// 0 = VPT
 	ret := prices.AddColumn()
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		// VPT = Previous VPT + Volume x (Today’s Close – Previous Close) / Previous Close
 		c := &prices.DataRows[i]
 		p := prices.DataRows[i-1]
@@ -4219,7 +4226,7 @@ func ParabolicSAR(prices *Matrix) int {
 	af = psarAfStep
 	ep = prices.DataRows[0].Get(LOW)
 
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := &prices.DataRows[i]
 		p := prices.DataRows[i-1]
 		c.Set(psar, p.Get(psar)-(p.Get(psar)-ep)*af)
@@ -4292,7 +4299,7 @@ func ChandelierExit(prices *Matrix, period int, multiplier float64) int {
 	ai := ATR(prices, period)
 	sh := SMA(prices, period, HIGH)
 	lh := SMA(prices, period, LOW)
-	for i := 0; i < prices.Rows; i++ {
+	for i := 0; i < prices.CandleRows(); i++ {
 		c := &prices.DataRows[i]
 		c.Set(l, c.Get(sh)-multiplier*c.Get(ai))
 		c.Set(s, c.Get(lh)+multiplier*c.Get(ai))
@@ -4306,7 +4313,7 @@ func ChandelierExit(prices *Matrix, period int, multiplier float64) int {
 func StratClassification(prices *Matrix) int {
 	// 1 = Inside 2 = 2 down 3 = 2 Up 4 = Outside
 	r := prices.AddNamedColumn("Strat")
-	for i := 1; i < prices.Rows; i++ {
+	for i := 1; i < prices.CandleRows(); i++ {
 		c := &prices.DataRows[i]
 		p := &prices.DataRows[i-1]
 		if c.Get(HIGH) > p.Get(HIGH) {
@@ -4332,7 +4339,7 @@ func StratClassification(prices *Matrix) int {
 func StratPMG(prices *Matrix) int {
 	r := prices.AddNamedColumn("PMG")
 	cnt := 5
-	for i := cnt + 1; i < prices.Rows; i++ {
+	for i := cnt + 1; i < prices.CandleRows(); i++ {
 		cn := 1
 		for j := 0; j < cnt; j++ {
 			idx := i - cnt + j
@@ -4348,7 +4355,7 @@ func StratPMG(prices *Matrix) int {
 			c.SetComment(fmt.Sprintf("PMG UP %d", cn))
 		}
 	}
-	for i := cnt + 1; i < prices.Rows; i++ {
+	for i := cnt + 1; i < prices.CandleRows(); i++ {
 		cn := 1
 		for j := 0; j < cnt; j++ {
 			idx := i - cnt + j
@@ -4387,7 +4394,7 @@ func DMA(candles *Matrix, sma, ema int) int {
 
 func Overlap(candles *Matrix) int {
 	ret := candles.AddNamedColumn("Overlap")
-	for i := 1; i < candles.Rows; i++ {
+	for i := 1; i < candles.CandleRows(); i++ {
 		c := candles.DataRows[i]
 		p := candles.DataRows[i-1]
 		cl := c.Get(LOW)
@@ -4447,7 +4454,7 @@ func Andean(candles *Matrix, period, signal int) int {
 	candles.DataRows[0].Set(up2, candles.CLOSE(0)*candles.CLOSE(0))
 	candles.DataRows[0].Set(dn1, candles.CLOSE(0))
 	candles.DataRows[0].Set(dn2, candles.CLOSE(0)*candles.CLOSE(0))
-	for i := 1; i < candles.Rows; i++ {
+	for i := 1; i < candles.CandleRows(); i++ {
 		c = candles.DataRows[i]
 		p := candles.DataRows[i-1]
 		//C = close
@@ -4462,7 +4469,7 @@ func Andean(candles *Matrix, period, signal int) int {
 		//dn2 := nz(math.min(C*C, O*O, dn2[1]+(C*C-dn2[1])*alpha), C*C)
 		candles.DataRows[i].Set(dn2, myMin(candles.CLOSE(i)*candles.CLOSE(i), candles.OPEN(i)*candles.OPEN(i), p.Get(dn2)+(candles.CLOSE(i)*candles.CLOSE(i)-p.Get(dn2))*alpha))
 	}
-	for i := 0; i < candles.Rows; i++ {
+	for i := 0; i < candles.CandleRows(); i++ {
 		c = candles.DataRows[i]
 		//Components
 		v1 := c.Get(dn2) - c.Get(dn1)*c.Get(dn1)
@@ -4477,7 +4484,7 @@ func Andean(candles *Matrix, period, signal int) int {
 		candles.DataRows[i].Set(be, math.Sqrt(v2))
 	}
 	tmp := candles.AddColumn()
-	for i := 0; i < candles.Rows; i++ {
+	for i := 0; i < candles.CandleRows(); i++ {
 		c = candles.DataRows[i]
 		if c.Get(bu) > c.Get(be) {
 			candles.DataRows[i].Set(tmp, c.Get(bu))
@@ -4520,7 +4527,7 @@ func EDCF(candles *Matrix, period int) int {
 	src := candles.Apply(func(mr MatrixRow) float64 {
 		return (mr.Get(1) + mr.Get(2)) / 2.0
 	})
-	for i := period; i < candles.Rows; i++ {
+	for i := period; i < candles.CandleRows(); i++ {
 
 		srcSum := 0.0
 		coefSum := 0.0
@@ -4552,7 +4559,7 @@ func EDCF(candles *Matrix, period int) int {
 func EfficiencyRatio(m *Matrix, days int) int {
 	// 0 = ER
 	ret := m.AddNamedColumn("ER")
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		direction := math.Abs(m.DataRows[i].Get(4) - m.DataRows[i-days].Get(4))
 		volatility := 0.0
 		for j := 0; j < days; j++ {
@@ -4710,7 +4717,7 @@ func SMI(m *Matrix, k, d int) int {
 	histo := m.AddNamedColumn("SMI-Hist")
 	diff := m.AddColumn()
 	rdiff := m.AddColumn()
-	for i := k; i < m.Rows; i++ {
+	for i := k; i < m.CandleRows(); i++ {
 		// Range Calculation
 		hh := m.FindMaxBetween(1, i-k, k)
 		ll := m.FindMinBetween(2, i-k, k)
@@ -4729,7 +4736,7 @@ func SMI(m *Matrix, k, d int) int {
 	e2 := EMA(m, d, diff)
 	avgdiff := EMA(m, d, e2)
 	// SMI calculations
-	for i := k; i < m.Rows; i++ {
+	for i := k; i < m.CandleRows(); i++ {
 		//SMI = avgdiff != 0 ? (avgrel/(avgdiff/2)*100) : 0
 		if m.DataRows[i].Get(avgdiff) != 0.0 {
 			m.DataRows[i].Set(ret, (m.DataRows[i].Get(avgrel)/(m.DataRows[i].Get(avgdiff)/2.0))*100.0)
@@ -4859,7 +4866,7 @@ func SSLChannel(m *Matrix, period int) int {
 	hlv := m.AddColumn()
 	sh := SMA(m, period, 1)
 	sl := SMA(m, period, 2)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(4) > m.DataRows[i].Get(sh) {
 			m.DataRows[i].Set(hlv, 1.0)
 		} else if m.DataRows[i].Get(4) < m.DataRows[i].Get(sl) {
@@ -4869,7 +4876,7 @@ func SSLChannel(m *Matrix, period int) int {
 		}
 
 	}
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(hlv) < 0.0 {
 			m.DataRows[i].Set(ret, m.DataRows[i].Get(sh))
 			m.DataRows[i].Set(upi, m.DataRows[i].Get(sl))
@@ -4898,13 +4905,13 @@ func WAE(m *Matrix, sensitivity, fast, slow, length int, multiplier float64) int
 	})
 	sens := float64(sensitivity)
 	bb := BollingerBand(m, length, multiplier, multiplier)
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(t1, (m.DataRows[i].Get(di)-m.DataRows[i-1].Get(di))*sens)
 	}
 	m.ApplyRow(upi, func(mr MatrixRow) float64 {
 		return mr.Get(bb) - mr.Get(bb+1)
 	})
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		t := m.DataRows[i].Get(t1)
 		if t >= 0.0 {
 			m.DataRows[i].Set(ret, t)
@@ -4931,7 +4938,7 @@ func SmoothedHeikinAshi(cn *Matrix, len1, len2 int) int {
 	haopen := cn.AddColumn()
 	hahigh := cn.AddColumn()
 	halow := cn.AddColumn()
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		cur := &cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		// = (o+h+l+c)/4
@@ -4980,7 +4987,7 @@ func CDV(cn *Matrix) int {
 	//ret := cn.AddColumn()
 	tmp := cn.AddColumn()
 	delta := cn.AddNamedColumn("Delta")
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		//tw = high - max(open, close)
 		uw := c.Get(1) - m.Max(c.Get(0), c.Get(4))
@@ -5000,7 +5007,7 @@ func CDV(cn *Matrix) int {
 		c.Set(tmp, de)
 		//cumdelta = cum(delta)
 	}
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		if i > 0 {
 			c.Set(delta, cn.DataRows[i-1].Get(tmp)+c.Get(tmp))
@@ -5014,7 +5021,7 @@ func CDV(cn *Matrix) int {
 	ci := cn.AddColumn()
 	aci := cn.AddColumn()
 
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		c.Set(oi, p.Get(delta))
@@ -5036,7 +5043,7 @@ func VWC(cn *Matrix) int {
 	aci := cn.AddColumn()
 	ri := cn.AddColumn()
 	ti := cn.AddColumn()
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		v := 0.0
 		if c.Get(si) != 0.0 {
@@ -5072,7 +5079,7 @@ func CandleSentiments(cn *Matrix, atr, vma int) int {
 	ai := ATR(cn, atr)
 	ei := EMA(cn, vma, 4)
 	vi := SMA(cn, vma, 5)
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		uw := c.Get(1) - m.Max(c.Get(0), c.Get(4))
 		lw := m.Min(c.Get(0), c.Get(4)) - c.Get(2)
@@ -5101,7 +5108,7 @@ func CandleSentiments(cn *Matrix, atr, vma int) int {
 // PVT = [((CurrentClose - PreviousClose) / PreviousClose) x Volume] + PreviousPVT
 func PVT(cn *Matrix, signal int) int {
 	ret := cn.AddNamedColumn("PVT")
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		d := ((c.Get(4)-p.Get(4))/p.Get(4))*c.Get(5) + p.Get(ret)
@@ -5138,7 +5145,7 @@ func RVWAP(candles *Matrix, period int) int {
 func PVR(cn *Matrix) int {
 	// 1 = strong uptrend 0.5 = weak uptrend -0.5 = weak downtrend -1 = strong downtrend
 	ret := cn.AddNamedColumn("PVR")
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		d := 0.0
@@ -5190,7 +5197,7 @@ func UltimateRSI(cn *Matrix, length int) int {
 	//: lower < lower[1] ? -r
 	//: d
 	diff := cn.AddColumn()
-	for i := 1; i < cn.Rows; i++ {
+	for i := 1; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		p := cn.DataRows[i-1]
 		if c.Get(upper) > p.Get(upper) {
@@ -5223,7 +5230,7 @@ func UltimateRSI(cn *Matrix, length int) int {
 func Range(cn *Matrix) int {
 	// 0 = Range
 	ret := cn.AddNamedColumn("Range")
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		c.Set(ret, c.High()-c.Low())
 	}
@@ -5234,11 +5241,11 @@ func Body(cn *Matrix, ema int) int {
 	// 0 = Body 1 = EMA 2 = Relation
 	ret := cn.AddNamedColumn("Body")
 	rel := cn.AddNamedColumn("RelBody")
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		c.Set(ret, ma.Abs(c.Open()-c.Close()))
 	}
-	for i := 0; i < cn.Rows; i++ {
+	for i := 0; i < cn.CandleRows(); i++ {
 		c := &cn.DataRows[i]
 		c.Set(rel, ma.Abs(c.Open()-c.Close())/(c.High()-c.Low())*100.0)
 	}
@@ -5264,7 +5271,7 @@ func TrendMagic(mat *Matrix, cci, atr int, coeff float64) int {
 		return mr.High() + mr.Get(atrIndex)*coeff
 	})
 	prev := 0.0
-	for i := 0; i < mat.Rows; i++ {
+	for i := 0; i < mat.CandleRows(); i++ {
 		c := mat.DataRows[i]
 		magicTrend := 0.0
 		if c.Get(cciIndex) >= 0.0 {
@@ -5298,7 +5305,7 @@ func T3(mat *Matrix, period int, vf float64) int {
 	for i := 1; i < 6; i++ {
 		emas[i] = EMA(mat, period, emas[i-1])
 	}
-	for i := period; i < mat.Rows; i++ {
+	for i := period; i < mat.CandleRows(); i++ {
 		t3 := c1*mat.DataRows[i].Get(emas[5]) + c2*mat.DataRows[i].Get(emas[4]) + c3*mat.DataRows[i].Get(emas[3]) + c4*mat.DataRows[i].Get(emas[2])
 		mat.DataRows[i].Set(ret, t3)
 	}
@@ -5332,7 +5339,7 @@ func ATRStopLoss(mat *Matrix, atr int, sensitivity float64) int {
 	nLoss := mat.Apply(func(mr MatrixRow) float64 {
 		return mr.Get(ai) * sensitivity
 	})
-	for i := 1; i < mat.Rows; i++ {
+	for i := 1; i < mat.CandleRows(); i++ {
 		c := &mat.DataRows[i]
 		p := mat.DataRows[i-1]
 		src := c.Close()
@@ -5350,7 +5357,7 @@ func ATRStopLoss(mat *Matrix, atr int, sensitivity float64) int {
 			}
 		}
 	}
-	for i := 1; i < mat.Rows; i++ {
+	for i := 1; i < mat.CandleRows(); i++ {
 		c := &mat.DataRows[i]
 		p := mat.DataRows[i-1]
 		if p.Close() < p.Get(ret) && c.Close() > c.Get(ret) {
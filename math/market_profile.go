@@ -0,0 +1,321 @@
+package math
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Market profile (TPO)
+//
+// -----------------------------------------------------------------------
+const TPO_LETTERS = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+type TPOConfig struct {
+	// TickSize is the price range of one row of the profile
+	TickSize float64
+	// Period is the number of minutes per letter
+	Period int
+	// InitialBalance is the number of periods of the initial balance
+	InitialBalance int
+	ValueArea      float64
+}
+
+func DefaultTPOConfig(tickSize float64) TPOConfig {
+	return TPOConfig{
+		TickSize:       tickSize,
+		Period:         30,
+		InitialBalance: 2,
+		ValueArea:      0.7,
+	}
+}
+
+type TPOLevel struct {
+	Price   float64
+	Letters string
+}
+
+type TPOProfile struct {
+	Session string
+	// rows from Start up to (not including) End
+	Start int
+	End   int
+	// Levels are sorted by price ascending
+	Levels []TPOLevel
+	Total  int
+	IBHigh float64
+	IBLow  float64
+	POC    float64
+	VAH    float64
+	VAL    float64
+	// single prints inside the profile (tails at the extremes are not included)
+	SinglePrints []float64
+	// a poor high/low has more than one TPO at the extreme
+	PoorHigh bool
+	PoorLow  bool
+}
+
+// tickIndex returns the number of whole ticks in the price. The epsilon keeps
+// prices on a tick boundary like 100.3 / 0.1 from falling into the tick below.
+func tickIndex(price, tick float64) int {
+	return int(math.Floor(price/tick + 1e-9))
+}
+
+func tpoLetter(period int) byte {
+	if period < 0 {
+		period = 0
+	}
+	if period >= len(TPO_LETTERS) {
+		period = len(TPO_LETTERS) - 1
+	}
+	return TPO_LETTERS[period]
+}
+
+// BuildTPOProfile builds the profile of the rows between start and end which
+// should belong to one session
+func BuildTPOProfile(m *Matrix, s Session, start, end int, cfg TPOConfig) *TPOProfile {
+	if end > m.CandleRows() {
+		end = m.CandleRows()
+	}
+	if start < 0 || start >= end || cfg.TickSize <= 0.0 {
+		return nil
+	}
+	if cfg.Period <= 0 {
+		cfg.Period = 30
+	}
+	ret := &TPOProfile{
+		Session: s.SessionDate(m.DataRows[start].Key),
+		Start:   start,
+		End:     end,
+	}
+	hi, lo := m.FindHighestHighLowestLow(start, end-start)
+	first := tickIndex(lo, cfg.TickSize)
+	cnt := tickIndex(hi, cfg.TickSize) - first + 1
+	levelOf := func(price float64) int {
+		return tickIndex(price, cfg.TickSize) - first
+	}
+	ret.Levels = make([]TPOLevel, cnt)
+	for i := range ret.Levels {
+		// dividing by the inverse keeps prices like 100.3 exact for a tick of 0.1
+		ret.Levels[i].Price = float64(first+i) / (1.0 / cfg.TickSize)
+	}
+	ret.IBHigh = -math.MaxFloat64
+	ret.IBLow = math.MaxFloat64
+	for i := start; i < end; i++ {
+		p := &m.DataRows[i]
+		period := s.minutesSinceStart(p.Key) / cfg.Period
+		letter := string(tpoLetter(period))
+		if period < cfg.InitialBalance {
+			ret.IBHigh = math.Max(ret.IBHigh, p.High())
+			ret.IBLow = math.Min(ret.IBLow, p.Low())
+		}
+		for j := max(levelOf(p.Low()), 0); j <= levelOf(p.High()) && j < cnt; j++ {
+			l := &ret.Levels[j]
+			if !strings.Contains(l.Letters, letter) {
+				l.Letters += letter
+				ret.Total++
+			}
+		}
+	}
+	if ret.IBHigh < ret.IBLow {
+		ret.IBHigh = 0.0
+		ret.IBLow = 0.0
+	}
+	ret.findPOC()
+	ret.findValueArea(cfg.ValueArea)
+	ret.findSinglePrints()
+	ret.PoorHigh = cnt > 1 && len(ret.Levels[cnt-1].Letters) > 1
+	ret.PoorLow = cnt > 1 && len(ret.Levels[0].Letters) > 1
+	return ret
+}
+
+// findPOC uses the level with the most TPOs. On a tie the level closest
+// to the middle of the profile wins.
+func (tp *TPOProfile) findPOC() {
+	mid := float64(len(tp.Levels)-1) / 2.0
+	ret := 0
+	for i, l := range tp.Levels {
+		cur := len(tp.Levels[ret].Letters)
+		if len(l.Letters) > cur || (len(l.Letters) == cur && math.Abs(float64(i)-mid) < math.Abs(float64(ret)-mid)) {
+			ret = i
+		}
+	}
+	tp.POC = tp.Levels[ret].Price
+}
+
+func (tp *TPOProfile) pocIndex() int {
+	for i, l := range tp.Levels {
+		if l.Price == tp.POC {
+			return i
+		}
+	}
+	return 0
+}
+
+func (tp *TPOProfile) findValueArea(part float64) {
+	if part <= 0.0 || part > 1.0 {
+		part = 0.7
+	}
+	lower := tp.pocIndex()
+	upper := lower
+	sum := len(tp.Levels[lower].Letters)
+	for float64(sum) < float64(tp.Total)*part && (lower > 0 || upper < len(tp.Levels)-1) {
+		up := -1
+		down := -1
+		if upper < len(tp.Levels)-1 {
+			up = len(tp.Levels[upper+1].Letters)
+		}
+		if lower > 0 {
+			down = len(tp.Levels[lower-1].Letters)
+		}
+		if up >= down {
+			upper++
+			sum += up
+		} else {
+			lower--
+			sum += down
+		}
+	}
+	tp.VAH = tp.Levels[upper].Price
+	tp.VAL = tp.Levels[lower].Price
+}
+
+func (tp *TPOProfile) findSinglePrints() {
+	tp.SinglePrints = make([]float64, 0)
+	// skip the tails at both ends
+	first := 0
+	for first < len(tp.Levels) && len(tp.Levels[first].Letters) == 1 {
+		first++
+	}
+	last := len(tp.Levels) - 1
+	for last >= 0 && len(tp.Levels[last].Letters) == 1 {
+		last--
+	}
+	for i := first; i <= last; i++ {
+		if len(tp.Levels[i].Letters) == 1 {
+			tp.SinglePrints = append(tp.SinglePrints, tp.Levels[i].Price)
+		}
+	}
+}
+
+// MarketProfiles builds one TPO profile for every session
+func MarketProfiles(m *Matrix, s Session, cfg TPOConfig) []*TPOProfile {
+	ret := make([]*TPOProfile, 0)
+	ids := SessionIndices(m, s)
+	start := -1
+	for i := 0; i <= m.CandleRows(); i++ {
+		if i < m.CandleRows() && start != -1 && ids[i] == ids[start] {
+			continue
+		}
+		if start != -1 {
+			if tp := BuildTPOProfile(m, s, start, i, cfg); tp != nil {
+				ret = append(ret, tp)
+			}
+			start = -1
+		}
+		if i < m.CandleRows() && ids[i] != -1 {
+			start = i
+		}
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------
+// TPO renderer
+// -----------------------------------------------------------------------
+type TPORenderer struct {
+	p       *TPOProfile
+	sizes   []int
+	builder strings.Builder
+}
+
+func NewTPORenderer(p *TPOProfile) *TPORenderer {
+	return &TPORenderer{
+		p: p,
+	}
+}
+
+func (tr *TPORenderer) addDelimiter(left, mid, right string) {
+	tr.builder.WriteString(left)
+	for i, s := range tr.sizes {
+		tr.builder.WriteString(strings.Repeat(DefaultBorder.V_LINE, s))
+		if i < len(tr.sizes)-1 {
+			tr.builder.WriteString(mid)
+		}
+	}
+	tr.builder.WriteString(right)
+	tr.builder.WriteString("\n")
+}
+
+func (tr *TPORenderer) addLine(values []string, align []int) {
+	for i, v := range values {
+		tr.builder.WriteString(DefaultBorder.H_LINE)
+		tr.builder.WriteString(AlignStrings(" "+v+" ", tr.sizes[i], align[i]))
+	}
+	tr.builder.WriteString(DefaultBorder.H_LINE)
+	tr.builder.WriteString("\n")
+}
+
+func (tr *TPORenderer) marker(index int) string {
+	l := tr.p.Levels[index]
+	ret := make([]string, 0)
+	if l.Price == tr.p.POC {
+		ret = append(ret, "POC")
+	}
+	if l.Price == tr.p.VAH {
+		ret = append(ret, "VAH")
+	}
+	if l.Price == tr.p.VAL {
+		ret = append(ret, "VAL")
+	}
+	for _, sp := range tr.p.SinglePrints {
+		if sp == l.Price {
+			ret = append(ret, "SP")
+		}
+	}
+	tick := tr.tickSize()
+	first := tickIndex(tr.p.Levels[0].Price, tick)
+	if tr.p.IBHigh > 0.0 && index >= tickIndex(tr.p.IBLow, tick)-first && index <= tickIndex(tr.p.IBHigh, tick)-first {
+		ret = append(ret, "IB")
+	}
+	return strings.Join(ret, " ")
+}
+
+func (tr *TPORenderer) tickSize() float64 {
+	if len(tr.p.Levels) > 1 {
+		return tr.p.Levels[1].Price - tr.p.Levels[0].Price
+	}
+	return 1.0
+}
+
+func (tr *TPORenderer) String() string {
+	if tr.p == nil {
+		return ""
+	}
+	tr.builder.Reset()
+	headers := []string{"Price", "TPO", tr.p.Session}
+	tr.sizes = make([]int, 3)
+	for i, h := range headers {
+		tr.sizes[i] = len(h) + 2
+	}
+	for i, l := range tr.p.Levels {
+		tr.sizes[0] = max(tr.sizes[0], len(fmt.Sprintf("%.2f", l.Price))+2)
+		tr.sizes[1] = max(tr.sizes[1], len(l.Letters)+2)
+		tr.sizes[2] = max(tr.sizes[2], len(tr.marker(i))+2)
+	}
+	tr.addDelimiter(DefaultBorder.TL_CORNER, DefaultBorder.TOP_DEL, DefaultBorder.TR_CORNER)
+	tr.addLine(headers, []int{1, 1, 1})
+	tr.addDelimiter(DefaultBorder.LEFT_DEL, DefaultBorder.CROSS, DefaultBorder.RIGHT_DEL)
+	for i := len(tr.p.Levels) - 1; i >= 0; i-- {
+		l := tr.p.Levels[i]
+		tr.addLine([]string{fmt.Sprintf("%.2f", l.Price), l.Letters, tr.marker(i)}, []int{2, 0, 0})
+	}
+	tr.addDelimiter(DefaultBorder.BL_CORNER, DefaultBorder.BOT_DEL, DefaultBorder.BR_CORNER)
+	return tr.builder.String()
+}
+
+func (tp *TPOProfile) String() string {
+	return NewTPORenderer(tp).String()
+}
@@ -0,0 +1,64 @@
+package math
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildTPOCandles() *Matrix {
+	mat := NewCandleMatrix()
+	add := func(key string, h, l float64) {
+		mat.AddRow(key).Set(OPEN, l).Set(HIGH, h).Set(LOW, l).Set(CLOSE, h).Set(ADJ_CLOSE, h).Set(VOLUME, 100)
+	}
+	add("2024-01-02 09:00", 100.4, 100.0)
+	add("2024-01-02 09:30", 100.5, 100.2)
+	add("2024-01-02 10:00", 100.3, 100.1)
+	add("2024-01-02 10:30", 100.3, 100.3)
+	return mat
+}
+
+func TestMarketProfile(t *testing.T) {
+	mat := buildTPOCandles()
+	s := NewSession("XETRA", "09:00", "17:30")
+	tps := MarketProfiles(mat, s, DefaultTPOConfig(0.1))
+	assert.Equal(t, 1, len(tps))
+	tp := tps[0]
+	letters := make([]string, 0)
+	for _, l := range tp.Levels {
+		letters = append(letters, l.Letters)
+	}
+	// 100.3 must not fall into the level below
+	assert.Equal(t, []string{"A", "AC", "ABC", "ABCD", "AB", "B"}, letters)
+	assert.Equal(t, 100.0, tp.Levels[0].Price)
+	assert.Equal(t, 13, tp.Total)
+	// the initial balance are the first two periods
+	assert.Equal(t, 100.5, tp.IBHigh)
+	assert.Equal(t, 100.0, tp.IBLow)
+	assert.Equal(t, 100.3, tp.POC)
+	// 70% of 13 TPOs: POC 4 + 3 below + 2 above + 2 below
+	assert.Equal(t, 100.4, tp.VAH)
+	assert.Equal(t, 100.1, tp.VAL)
+	assert.Equal(t, 0, len(tp.SinglePrints))
+	assert.False(t, tp.PoorHigh)
+	assert.False(t, tp.PoorLow)
+}
+
+func TestTPORenderer(t *testing.T) {
+	mat := buildTPOCandles()
+	s := NewSession("XETRA", "09:00", "17:30")
+	tp := BuildTPOProfile(mat, s, 0, mat.Rows, DefaultTPOConfig(0.1))
+	tr := NewTPORenderer(tp)
+	txt := tr.String()
+	// rendering twice returns the same table
+	assert.Equal(t, txt, tr.String())
+	lines := strings.Split(strings.TrimSpace(txt), "\n")
+	assert.Equal(t, 10, len(lines))
+	assert.Contains(t, lines[1], "2024-01-02")
+	assert.Contains(t, lines[3], "100.50")
+	assert.Contains(t, lines[5], "ABCD")
+	assert.Contains(t, lines[5], "POC IB")
+	assert.Contains(t, lines[4], "VAH IB")
+	assert.Contains(t, lines[7], "VAL IB")
+}
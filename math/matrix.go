@@ -91,6 +91,10 @@ type SwingPoint struct {
 	Trend     int
 	Delta     float64
 	Broken    bool
+	// Unconfirmed marks a pivot that can still move (the last leg of ZigZag)
+	Unconfirmed bool
+	// ConfirmedIndex is the row where FindZigZag confirmed the pivot (-1 if unconfirmed)
+	ConfirmedIndex int
 }
 
 func (s SwingPointType) String() string {
@@ -215,6 +219,8 @@ type MatrixRow struct {
 	Num     int
 	Values  []float64
 	Comment string
+	// Projected marks synthetic rows after the last candle (see Project)
+	Projected bool
 }
 type Matrix struct {
 	Info     string
@@ -1075,7 +1081,7 @@ func (m *Matrix) Recent(count int) *Matrix {
 }
 
 func (m *Matrix) Subset(start, end int) *Matrix {
-	ret := NewMatrixWithHeaders(m.Cols, m.Headers)
+	ret := NewMatrixWithHeaders(m.Cols, m.Headers[1:])
 	if start < 0 {
 		start = 0
 	}
@@ -1278,6 +1284,9 @@ func SaveMatrix(m *Matrix, fileName string) error {
 	}
 	f.WriteString("\n")
 	for _, p := range m.DataRows {
+		if p.Projected {
+			continue
+		}
 		_, err2 := f.WriteString(p.Key)
 		if err2 != nil {
 			fmt.Println(err2)
@@ -0,0 +1,219 @@
+package math
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	m "math"
+)
+
+const (
+	JSON_RECORDS = "records"
+	JSON_COLUMNS = "columns"
+)
+
+// JSONFloat is a float64 that is encoded as null if it is NaN or infinite
+type JSONFloat float64
+
+func (f JSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if m.IsNaN(v) || m.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (f *JSONFloat) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*f = JSONFloat(m.NaN())
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = JSONFloat(v)
+	return nil
+}
+
+func toJSONFloats(values []float64) []JSONFloat {
+	ret := make([]JSONFloat, len(values))
+	for i, v := range values {
+		ret[i] = JSONFloat(v)
+	}
+	return ret
+}
+
+func fromJSONFloats(values []JSONFloat) []float64 {
+	ret := make([]float64, len(values))
+	for i, v := range values {
+		ret[i] = float64(v)
+	}
+	return ret
+}
+
+type jsonMatrixRecord struct {
+	Key     string      `json:"key"`
+	Values  []JSONFloat `json:"values"`
+	Comment string      `json:"comment,omitempty"`
+	// Projected marks synthetic rows after the last candle
+	Projected bool `json:"projected,omitempty"`
+}
+
+type jsonMatrix struct {
+	Layout string `json:"layout"`
+	Info   string `json:"info,omitempty"`
+	// Cols is the number of value columns. Headers can be shorter (NewMatrix).
+	Cols     int                `json:"cols"`
+	Headers  []string           `json:"headers"`
+	Records  []jsonMatrixRecord `json:"records,omitempty"`
+	Keys     []string           `json:"keys,omitempty"`
+	Comments []string           `json:"comments,omitempty"`
+	Columns  [][]JSONFloat      `json:"columns,omitempty"`
+	// Projected is the number of projected rows at the end (columns layout)
+	Projected int `json:"projected,omitempty"`
+}
+
+// MarshalMatrix encodes the matrix either row oriented (JSON_RECORDS) or column oriented (JSON_COLUMNS)
+func MarshalMatrix(mat *Matrix, layout string) ([]byte, error) {
+	jm := jsonMatrix{
+		Layout:  layout,
+		Info:    mat.Info,
+		Cols:    mat.Cols,
+		Headers: mat.Headers,
+	}
+	switch layout {
+	case JSON_RECORDS:
+		jm.Records = make([]jsonMatrixRecord, mat.Rows)
+		for i := 0; i < mat.Rows; i++ {
+			r := mat.DataRows[i]
+			jm.Records[i] = jsonMatrixRecord{
+				Key:       r.Key,
+				Values:    toJSONFloats(r.Values[:r.Num]),
+				Comment:   r.Comment,
+				Projected: r.Projected,
+			}
+		}
+	case JSON_COLUMNS:
+		jm.Keys = mat.GetKeys()
+		jm.Projected = mat.Rows - mat.CandleRows()
+		jm.Comments = mat.GetCommentColumn()
+		hasComments := false
+		for _, c := range jm.Comments {
+			if c != "" {
+				hasComments = true
+			}
+		}
+		if !hasComments {
+			jm.Comments = nil
+		}
+		jm.Columns = make([][]JSONFloat, mat.Cols)
+		for i := 0; i < mat.Cols; i++ {
+			jm.Columns[i] = toJSONFloats(mat.GetColumn(i))
+		}
+	default:
+		return nil, fmt.Errorf("unknown matrix layout: %s", layout)
+	}
+	return json.Marshal(jm)
+}
+
+// UnmarshalMatrix decodes a matrix in any of the supported layouts
+func UnmarshalMatrix(data []byte) (*Matrix, error) {
+	var jm jsonMatrix
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return nil, err
+	}
+	ret := &Matrix{
+		Info:    jm.Info,
+		Headers: jm.Headers,
+	}
+	if len(ret.Headers) == 0 {
+		ret.Headers = []string{"Key"}
+	}
+	// older documents have no cols and one header per column
+	ret.Cols = jm.Cols
+	if ret.Cols == 0 {
+		ret.Cols = len(ret.Headers) - 1
+	}
+	for len(ret.Headers) < ret.Cols+1 {
+		ret.Headers = append(ret.Headers, "")
+	}
+	switch jm.Layout {
+	case JSON_RECORDS, "":
+		for _, r := range jm.Records {
+			row := ret.ForcedAddRow(r.Key)
+			values := fromJSONFloats(r.Values)
+			if len(values) > ret.Cols {
+				return nil, fmt.Errorf("row %s has %d values but only %d columns", r.Key, len(values), ret.Cols)
+			}
+			copy(row.Values, values)
+			row.Comment = r.Comment
+			row.Projected = r.Projected
+		}
+	case JSON_COLUMNS:
+		if len(jm.Columns) != ret.Cols {
+			return nil, fmt.Errorf("expected %d columns but got %d", ret.Cols, len(jm.Columns))
+		}
+		if jm.Comments != nil && len(jm.Comments) != len(jm.Keys) {
+			return nil, errors.New("number of comments does not match number of keys")
+		}
+		for i, k := range jm.Keys {
+			row := ret.ForcedAddRow(k)
+			for j, c := range jm.Columns {
+				if len(c) != len(jm.Keys) {
+					return nil, fmt.Errorf("column %d has %d values but there are %d keys", j, len(c), len(jm.Keys))
+				}
+				row.Values[j] = float64(c[i])
+			}
+			if jm.Comments != nil {
+				row.Comment = jm.Comments[i]
+			}
+			row.Projected = i >= len(jm.Keys)-jm.Projected
+		}
+	default:
+		return nil, fmt.Errorf("unknown matrix layout: %s", jm.Layout)
+	}
+	return ret, nil
+}
+
+// MarshalJSON uses the row oriented layout
+func (mat Matrix) MarshalJSON() ([]byte, error) {
+	return MarshalMatrix(&mat, JSON_RECORDS)
+}
+
+func (mat *Matrix) UnmarshalJSON(data []byte) error {
+	ret, err := UnmarshalMatrix(data)
+	if err != nil {
+		return err
+	}
+	*mat = *ret
+	return nil
+}
+
+// RenderedValue is the JSON form of the output of an IndicatorValueRenderer
+type RenderedValue struct {
+	Value JSONFloat `json:"value"`
+	Text  string    `json:"text"`
+	State int       `json:"state"`
+}
+
+func NewRenderedValue(renderer IndicatorValueRenderer, v float64) RenderedValue {
+	if renderer == nil {
+		renderer = &DefaultRenderer{}
+	}
+	txt, state := renderer.Convert(v)
+	return RenderedValue{
+		Value: JSONFloat(v),
+		Text:  txt,
+		State: state,
+	}
+}
+
+// RenderColumn converts every value of the column with the given renderer
+func RenderColumn(mat *Matrix, col int, renderer IndicatorValueRenderer) []RenderedValue {
+	ret := make([]RenderedValue, mat.Rows)
+	for i := 0; i < mat.Rows; i++ {
+		ret[i] = NewRenderedValue(renderer, mat.DataRows[i].Get(col))
+	}
+	return ret
+}
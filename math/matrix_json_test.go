@@ -0,0 +1,64 @@
+package math
+
+import (
+	"encoding/json"
+	m "math"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildJSONMatrix() *Matrix {
+	mat := NewMatrixWithHeaders(2, []string{"A", "B"})
+	mat.Info = "test"
+	mat.AddRow("2024-01-02 00:00").Set(0, 1.5).Set(1, m.NaN()).SetComment("first")
+	mat.AddRow("2024-01-03 00:00").Set(0, 2.5).Set(1, 3.0)
+	return mat
+}
+
+func TestMatrixJSONRecords(t *testing.T) {
+	data, err := json.Marshal(buildJSONMatrix())
+	assert.NoError(t, err)
+	var ret Matrix
+	assert.NoError(t, json.Unmarshal(data, &ret))
+	assert.Equal(t, 2, ret.Rows)
+	assert.Equal(t, 2, ret.Cols)
+	assert.Equal(t, "test", ret.Info)
+	assert.Equal(t, []string{"Key", "A", "B"}, ret.Headers)
+	assert.Equal(t, "first", ret.DataRows[0].Comment)
+	assert.True(t, m.IsNaN(ret.DataRows[0].Get(1)))
+	assert.Equal(t, 3.0, ret.DataRows[1].Get(1))
+}
+
+func TestMatrixJSONColumns(t *testing.T) {
+	data, err := MarshalMatrix(buildJSONMatrix(), JSON_COLUMNS)
+	assert.NoError(t, err)
+	ret, err := UnmarshalMatrix(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ret.Rows)
+	assert.Equal(t, "2024-01-03 00:00", ret.DataRows[1].Key)
+	assert.Equal(t, "first", ret.DataRows[0].Comment)
+	assert.True(t, m.IsNaN(ret.DataRows[0].Get(1)))
+	assert.Equal(t, 2.5, ret.DataRows[1].Get(0))
+}
+
+func TestMatrixJSONWithoutHeaders(t *testing.T) {
+	mat := NewMatrix(4)
+	mat.AddRow("2024-01-02 00:00").Set(0, 1.0).Set(3, 4.0)
+	for _, layout := range []string{JSON_RECORDS, JSON_COLUMNS} {
+		data, err := MarshalMatrix(mat, layout)
+		assert.NoError(t, err)
+		ret, err := UnmarshalMatrix(data)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, ret.Cols)
+		assert.Equal(t, 5, len(ret.Headers))
+		assert.Equal(t, 4.0, ret.DataRows[0].Get(3))
+	}
+}
+
+func TestRenderedValueJSON(t *testing.T) {
+	rv := NewRenderedValue(&LowerUpperThresholdRenderer{Lower: 30, Upper: 70}, 75.0)
+	data, err := json.Marshal(rv)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"value":75,"text":"75.00","state":1}`, string(data))
+}
@@ -3,7 +3,7 @@ package math
 func MarketRegime(candles *Matrix, period int) int {
 	ret := candles.AddNamedColumn("MR")
 	e1 := EMA(candles, period, ADJ_CLOSE)
-	for i := 13; i < candles.Rows; i++ {
+	for i := 13; i < candles.CandleRows(); i++ {
 		c := &candles.DataRows[i]
 		p1 := candles.DataRows[i-13]
 		p2 := candles.DataRows[i-8]
@@ -60,7 +60,7 @@ func MFI(m *Matrix, days int) int {
 	})
 
 	// money ratio
-	for i := days + 1; i < m.Rows; i++ {
+	for i := days + 1; i < m.CandleRows(); i++ {
 		mfp := 0.0
 		mfn := 0.0
 		for j := 0; j < days; j++ {
@@ -86,7 +86,7 @@ func LWTI(m *Matrix, days int) int {
 	ret := m.AddNamedColumn("LWTI")
 	//ma = ta.sma(close - nz(close[per]), per)
 	d := m.AddColumn()
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		c := &m.DataRows[i]
 		p := m.DataRows[i-days]
 		c.Set(d, c.Get(4)-p.Get(4))
@@ -95,7 +95,7 @@ func LWTI(m *Matrix, days int) int {
 	//atr = ta.atr(per)
 	a := ATR(m, days)
 	//out = ma/atr * 50 + 50
-	for i := days; i < m.Rows; i++ {
+	for i := days; i < m.CandleRows(); i++ {
 		c := &m.DataRows[i]
 		if c.Get(a) != 0.0 {
 			c.Set(ret, c.Get(s)/c.Get(a)*50.0+50.0)
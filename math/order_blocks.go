@@ -0,0 +1,376 @@
+package math
+
+import (
+	"encoding/json"
+	m "math"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Order blocks
+//
+// -----------------------------------------------------------------------
+type OrderBlockState int
+
+const (
+	// price has not come back to the block
+	OB_ACTIVE OrderBlockState = iota
+	// price traded into the block
+	OB_TOUCHED
+	// price traded through the whole block without closing beyond it
+	OB_MITIGATED
+	// a candle closed beyond the block. The block flipped into a breaker block.
+	OB_BREAKER
+	// the block was not broken within MaxAge bars
+	OB_EXPIRED
+	// a candle closed beyond the far side of the breaker block
+	OB_INVALIDATED
+)
+
+func (s OrderBlockState) String() string {
+	switch s {
+	case OB_ACTIVE:
+		return "Active"
+	case OB_TOUCHED:
+		return "Touched"
+	case OB_MITIGATED:
+		return "Mitigated"
+	case OB_BREAKER:
+		return "Breaker"
+	case OB_EXPIRED:
+		return "Expired"
+	case OB_INVALIDATED:
+		return "Invalidated"
+	}
+	return "Unknown"
+}
+
+// OrderBlock is the last opposite candle before a displacement that left a
+// fair value gap. Index is the candle of the block and Confirmed the row where
+// the gap is known. A bullish block (Type 1) acts as support, a bearish block
+// (Type -1) as resistance. A breaker block has the flipped type.
+type OrderBlock struct {
+	Key       string
+	Upper     float64
+	Mid       float64
+	Lower     float64
+	Type      int
+	Index     int
+	Confirmed int
+	State     OrderBlockState
+	// Mitigation is the deepest move into the block in percent of the block size
+	Mitigation float64
+	// FirstTouch, MitigatedIndex, BreakerIndex and ExpiredIndex are -1 until it happens
+	FirstTouch     int
+	MitigatedIndex int
+	BreakerIndex   int
+	ExpiredIndex   int
+	// BreakerTouch is the first touch after the flip and InvalidatedIndex the
+	// close beyond the breaker block. Both are -1 until it happens.
+	BreakerTouch     int
+	InvalidatedIndex int
+	// Displacement is the move from the block to the end of the gap in ATR units
+	Displacement float64
+	// RelativeVolume is the volume of the block and the displacement candles
+	// compared to the average volume
+	RelativeVolume float64
+	// Score is Displacement * RelativeVolume
+	Score float64
+}
+
+func (ob OrderBlock) Size() float64 {
+	return ob.Upper - ob.Lower
+}
+
+func (ob OrderBlock) IsInside(value float64) bool {
+	return value < ob.Upper && value > ob.Lower
+}
+
+// InitialType is the type before a breaker flip
+func (ob OrderBlock) InitialType() int {
+	if ob.BreakerIndex != -1 {
+		return -ob.Type
+	}
+	return ob.Type
+}
+
+// TypeAt returns the type the block had on the close of the given row
+func (ob OrderBlock) TypeAt(index int) int {
+	if ob.BreakerIndex != -1 && index >= ob.BreakerIndex {
+		return ob.Type
+	}
+	return ob.InitialType()
+}
+
+// Live is true as long as the block or the breaker block is neither
+// invalidated nor expired
+func (ob OrderBlock) Live() bool {
+	return ob.State != OB_EXPIRED && ob.State != OB_INVALIDATED
+}
+
+// StateAt returns the state the block had on the close of the given row
+func (ob OrderBlock) StateAt(index int) OrderBlockState {
+	if ob.InvalidatedIndex != -1 && index >= ob.InvalidatedIndex {
+		return OB_INVALIDATED
+	}
+	if ob.ExpiredIndex != -1 && index >= ob.ExpiredIndex {
+		return OB_EXPIRED
+	}
+	if ob.BreakerIndex != -1 && index >= ob.BreakerIndex {
+		return OB_BREAKER
+	}
+	if ob.MitigatedIndex != -1 && index >= ob.MitigatedIndex {
+		return OB_MITIGATED
+	}
+	if ob.FirstTouch != -1 && index >= ob.FirstTouch {
+		return OB_TOUCHED
+	}
+	return OB_ACTIVE
+}
+
+type OrderBlockConfig struct {
+	FVG FVGConfig
+	// Lookback is the number of candles before the gap that are searched for the block
+	Lookback int
+	// MaxAge is the number of bars after the confirmation until an unbroken block
+	// and after the flip until a breaker block expires (0 = never)
+	MaxAge       int
+	ATRPeriod    int
+	VolumePeriod int
+}
+
+func DefaultOrderBlockConfig() OrderBlockConfig {
+	return OrderBlockConfig{
+		FVG:          DefaultFVGConfig(),
+		Lookback:     5,
+		MaxAge:       100,
+		ATRPeriod:    14,
+		VolumePeriod: 20,
+	}
+}
+
+// trackOrderBlock walks the candles after the confirmation and records the
+// lifecycle. After the flip the breaker block is tracked until it is
+// invalidated or expires.
+func trackOrderBlock(candles *Matrix, ob *OrderBlock, maxAge int) {
+	ob.State = OB_ACTIVE
+	ob.Mitigation = 0.0
+	ob.FirstTouch = -1
+	ob.MitigatedIndex = -1
+	ob.BreakerIndex = -1
+	ob.ExpiredIndex = -1
+	ob.BreakerTouch = -1
+	ob.InvalidatedIndex = -1
+	size := ob.Size()
+	start := ob.Confirmed
+	for i := ob.Confirmed + 1; i < candles.CandleRows(); i++ {
+		if maxAge > 0 && i-start > maxAge {
+			ob.ExpiredIndex = i
+			ob.State = OB_EXPIRED
+			return
+		}
+		c := &candles.DataRows[i]
+		breaker := ob.BreakerIndex != -1
+		depth := 0.0
+		if ob.Type == 1 {
+			depth = ob.Upper - c.Low()
+		} else {
+			depth = c.High() - ob.Lower
+		}
+		if depth > 0.0 && breaker {
+			if ob.BreakerTouch == -1 {
+				ob.BreakerTouch = i
+			}
+		} else if depth > 0.0 {
+			if ob.FirstTouch == -1 {
+				ob.FirstTouch = i
+				ob.State = OB_TOUCHED
+			}
+			if size > 0.0 {
+				ob.Mitigation = m.Max(ob.Mitigation, m.Min(depth/size*100.0, 100.0))
+			}
+			if depth >= size && ob.MitigatedIndex == -1 {
+				ob.MitigatedIndex = i
+				ob.State = OB_MITIGATED
+			}
+		}
+		if (ob.Type == 1 && c.Close() < ob.Lower) || (ob.Type == -1 && c.Close() > ob.Upper) {
+			if breaker {
+				ob.InvalidatedIndex = i
+				ob.State = OB_INVALIDATED
+				return
+			}
+			ob.BreakerIndex = i
+			ob.State = OB_BREAKER
+			ob.Type = -ob.Type
+			start = i
+		}
+	}
+}
+
+// FindOrderBlocks finds the order blocks behind the fair value gaps and tracks them
+// over all candles
+func FindOrderBlocks(candles *Matrix, cfg OrderBlockConfig) []OrderBlock {
+	ret := make([]OrderBlock, 0)
+	gaps := FindFairValueGaps(candles, cfg.FVG)
+	if len(gaps) == 0 {
+		return ret
+	}
+	atr := ATR(candles, cfg.ATRPeriod)
+	last := -1
+	for _, g := range gaps {
+		if g.Inversion {
+			continue
+		}
+		// the last opposite candle up to the first candle of the gap
+		idx := -1
+		for j := g.Index; j >= 0 && j > g.Index-cfg.Lookback; j-- {
+			c := &candles.DataRows[j]
+			if (g.Type == 1 && c.IsRed()) || (g.Type == -1 && c.IsGreen()) {
+				idx = j
+				break
+			}
+		}
+		if idx == -1 || idx == last {
+			continue
+		}
+		last = idx
+		c := &candles.DataRows[idx]
+		end := &candles.DataRows[g.Start()]
+		ob := OrderBlock{
+			Key:       c.Key,
+			Upper:     c.High(),
+			Lower:     c.Low(),
+			Mid:       c.Low() + (c.High()-c.Low())/2.0,
+			Type:      g.Type,
+			Index:     idx,
+			Confirmed: g.Start(),
+		}
+		if a := end.Get(atr); a > 0.0 {
+			if g.Type == 1 {
+				ob.Displacement = (end.High() - c.Low()) / a
+			} else {
+				ob.Displacement = (c.High() - end.Low()) / a
+			}
+		}
+		sum := 0.0
+		for j := idx; j <= g.Start(); j++ {
+			sum += candles.DataRows[j].Get(VOLUME)
+		}
+		avg := 0.0
+		start := max(idx-cfg.VolumePeriod+1, 0)
+		for j := start; j <= idx; j++ {
+			avg += candles.DataRows[j].Get(VOLUME)
+		}
+		avg /= float64(idx - start + 1)
+		if avg > 0.0 {
+			ob.RelativeVolume = sum / float64(g.Start()-idx+1) / avg
+		}
+		ob.Score = ob.Displacement * ob.RelativeVolume
+		trackOrderBlock(candles, &ob, cfg.MaxAge)
+		ret = append(ret, ob)
+	}
+	candles.RemoveColumn()
+	return ret
+}
+
+// GetOrderBlocks returns the order blocks and breaker blocks that are not
+// invalidated or expired on the last candle
+func GetOrderBlocks(candles *Matrix) []OrderBlock {
+	ret := make([]OrderBlock, 0)
+	for _, ob := range FindOrderBlocks(candles, DefaultOrderBlockConfig()) {
+		if ob.Live() {
+			ret = append(ret, ob)
+		}
+	}
+	return ret
+}
+
+// OrderBlocks adds the most recent live order block or breaker block at every
+// row. OBType is the type on that row. OBUpper and OBLower are 0 if there is
+// no live block.
+func OrderBlocks(candles *Matrix) int {
+	upper := candles.AddNamedColumn("OBUpper")
+	lower := candles.AddNamedColumn("OBLower")
+	tp := candles.AddNamedColumn("OBType")
+	state := candles.AddNamedColumn("OBState")
+	// 0 = Upper 1 = Lower 2 = Type 3 = State
+	blocks := FindOrderBlocks(candles, DefaultOrderBlockConfig())
+	for i := 0; i < candles.CandleRows(); i++ {
+		c := &candles.DataRows[i]
+		for j := len(blocks) - 1; j >= 0; j-- {
+			ob := blocks[j]
+			if ob.Confirmed > i {
+				continue
+			}
+			st := ob.StateAt(i)
+			if st != OB_EXPIRED && st != OB_INVALIDATED {
+				c.Set(upper, ob.Upper)
+				c.Set(lower, ob.Lower)
+				c.Set(tp, float64(ob.TypeAt(i)))
+				c.Set(state, float64(st))
+				break
+			}
+		}
+	}
+	return upper
+}
+
+type jsonOrderBlock struct {
+	Key        string    `json:"key"`
+	Confirmed  string    `json:"confirmed"`
+	Upper      JSONFloat `json:"upper"`
+	Lower      JSONFloat `json:"lower"`
+	Type       int       `json:"type"`
+	State      string    `json:"state"`
+	Mitigation JSONFloat `json:"mitigation"`
+	FirstTouch string    `json:"firstTouch,omitempty"`
+	Mitigated  string    `json:"mitigated,omitempty"`
+	Breaker    string    `json:"breaker,omitempty"`
+	// BreakerTouch is the first touch after the flip
+	BreakerTouch string `json:"breakerTouch,omitempty"`
+	Invalidated  string `json:"invalidated,omitempty"`
+	Expired      string `json:"expired,omitempty"`
+	// End is the last key the block should be drawn to
+	End   string    `json:"end"`
+	Score JSONFloat `json:"score"`
+}
+
+func orderBlockKey(candles *Matrix, index int) string {
+	if index < 0 || index >= candles.Rows {
+		return ""
+	}
+	return candles.DataRows[index].Key
+}
+
+// MarshalOrderBlocks encodes the blocks for chart overlays. All positions are
+// written as row keys of the matrix.
+func MarshalOrderBlocks(candles *Matrix, blocks []OrderBlock) ([]byte, error) {
+	ret := make([]jsonOrderBlock, 0, len(blocks))
+	for _, ob := range blocks {
+		end := candles.CandleRows() - 1
+		for _, idx := range []int{ob.InvalidatedIndex, ob.ExpiredIndex} {
+			if idx != -1 {
+				end = idx
+			}
+		}
+		ret = append(ret, jsonOrderBlock{
+			Key:          ob.Key,
+			Confirmed:    orderBlockKey(candles, ob.Confirmed),
+			Upper:        JSONFloat(ob.Upper),
+			Lower:        JSONFloat(ob.Lower),
+			Type:         ob.Type,
+			State:        ob.State.String(),
+			Mitigation:   JSONFloat(ob.Mitigation),
+			FirstTouch:   orderBlockKey(candles, ob.FirstTouch),
+			Mitigated:    orderBlockKey(candles, ob.MitigatedIndex),
+			Breaker:      orderBlockKey(candles, ob.BreakerIndex),
+			BreakerTouch: orderBlockKey(candles, ob.BreakerTouch),
+			Invalidated:  orderBlockKey(candles, ob.InvalidatedIndex),
+			Expired:      orderBlockKey(candles, ob.ExpiredIndex),
+			End:          orderBlockKey(candles, end),
+			Score:        JSONFloat(ob.Score),
+		})
+	}
+	return json.Marshal(ret)
+}
@@ -0,0 +1,91 @@
+package math
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindOrderBlocks(t *testing.T) {
+	mat := candleMatrix([][4]float64{
+		{100, 101, 99, 100},
+		{100, 101, 98, 99},
+		{99, 108, 99, 107},
+		{107, 110, 104, 109},
+		{109, 111, 106, 110},
+		{110, 110, 100, 101},
+		{101, 102, 95, 96},
+		{96, 97, 94, 95},
+		// retest and invalidation of the first breaker, break of the second block
+		{95, 99, 95, 98.5},
+		{98.5, 103, 98, 102},
+		{102, 113, 102, 112.5},
+	})
+	cols := mat.Cols
+	blocks := FindOrderBlocks(mat, DefaultOrderBlockConfig())
+	assert.Equal(t, cols, mat.Cols)
+	// the second block is the bearish block in front of the sell off
+	assert.Equal(t, 2, len(blocks))
+	ob := blocks[0]
+	assert.Equal(t, 1, ob.Index)
+	assert.Equal(t, 3, ob.Confirmed)
+	assert.Equal(t, 101.0, ob.Upper)
+	assert.Equal(t, 98.0, ob.Lower)
+	assert.Equal(t, 5, ob.FirstTouch)
+	assert.Equal(t, 6, ob.MitigatedIndex)
+	assert.Equal(t, 6, ob.BreakerIndex)
+	assert.Equal(t, 100.0, ob.Mitigation)
+	// the breaker is tracked after the flip
+	assert.Equal(t, 8, ob.BreakerTouch)
+	assert.Equal(t, 9, ob.InvalidatedIndex)
+	assert.Equal(t, OB_INVALIDATED, ob.State)
+	assert.Equal(t, -1, ob.Type)
+	assert.Equal(t, 1, ob.InitialType())
+	assert.Equal(t, 1, ob.TypeAt(5))
+	assert.Equal(t, -1, ob.TypeAt(8))
+	assert.Equal(t, OB_TOUCHED, ob.StateAt(5))
+	assert.Equal(t, OB_BREAKER, ob.StateAt(8))
+	assert.Equal(t, OB_INVALIDATED, ob.StateAt(9))
+	assert.Equal(t, 10, blocks[1].BreakerIndex)
+	assert.Equal(t, OB_BREAKER, blocks[1].State)
+	// the first touch of the second block closes through it
+	assert.Equal(t, 10, blocks[1].FirstTouch)
+	assert.Equal(t, []EventSignal{{Index: 5, Direction: 1}}, SignalsFromOrderBlockTouches(mat, blocks))
+	assert.Equal(t, 1.0, ob.RelativeVolume)
+	assert.True(t, ob.Score > 0.0)
+	assert.Equal(t, 1, len(GetOrderBlocks(mat)))
+
+	cfg := DefaultOrderBlockConfig()
+	cfg.MaxAge = 1
+	blocks = FindOrderBlocks(mat, cfg)
+	assert.Equal(t, OB_EXPIRED, blocks[0].State)
+	assert.Equal(t, 5, blocks[0].ExpiredIndex)
+
+	data, err := MarshalOrderBlocks(mat, FindOrderBlocks(mat, DefaultOrderBlockConfig()))
+	assert.NoError(t, err)
+	var decoded []struct {
+		State        string `json:"state"`
+		Breaker      string `json:"breaker"`
+		BreakerTouch string `json:"breakerTouch"`
+		Invalidated  string `json:"invalidated"`
+		End          string `json:"end"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "Invalidated", decoded[0].State)
+	assert.Equal(t, mat.DataRows[6].Key, decoded[0].Breaker)
+	assert.Equal(t, mat.DataRows[8].Key, decoded[0].BreakerTouch)
+	assert.Equal(t, mat.DataRows[9].Key, decoded[0].Invalidated)
+	assert.Equal(t, mat.DataRows[9].Key, decoded[0].End)
+	assert.Equal(t, "Breaker", decoded[1].State)
+
+	upper := OrderBlocks(mat)
+	assert.Equal(t, 1.0, mat.DataRows[5].Get(upper+2))
+	assert.Equal(t, float64(OB_TOUCHED), mat.DataRows[5].Get(upper+3))
+	assert.Equal(t, 111.0, mat.DataRows[6].Get(upper))
+	// the second block flipped into a support breaker
+	assert.Equal(t, 111.0, mat.DataRows[10].Get(upper))
+	assert.Equal(t, 106.0, mat.DataRows[10].Get(upper+1))
+	assert.Equal(t, 1.0, mat.DataRows[10].Get(upper+2))
+	assert.Equal(t, float64(OB_BREAKER), mat.DataRows[10].Get(upper+3))
+}
@@ -0,0 +1,51 @@
+package math
+
+import (
+	"errors"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Forward projection
+//
+// -----------------------------------------------------------------------
+// Project appends n synthetic rows after the last row. The keys are created
+// by the calendar so that weekends and holidays are skipped. The rows are
+// flagged as projected. Price based indicators stop at the last candle and
+// displaced indicators like Ichimoku or ShiftedSMA fill the projected rows.
+// SaveMatrix and the CandleStore ignore them.
+// Returns the index of the first projected row.
+func (m *Matrix) Project(cal *TradingCalendar, n int, interval time.Duration) (int, error) {
+	if m.Rows == 0 {
+		return -1, errors.New("cannot project an empty matrix")
+	}
+	keys, err := cal.FutureKeys(m.DataRows[m.Rows-1].Key, n, interval)
+	if err != nil {
+		return -1, err
+	}
+	ret := m.Rows
+	for _, k := range keys {
+		m.ForcedAddRow(k).Projected = true
+	}
+	return ret, nil
+}
+
+// CandleRows returns the number of rows without the projected rows at the end
+func (m *Matrix) CandleRows() int {
+	ret := m.Rows
+	for ret > 0 && m.DataRows[ret-1].Projected {
+		ret--
+	}
+	return ret
+}
+
+func (m *Matrix) HasProjection() bool {
+	return m.CandleRows() != m.Rows
+}
+
+// RemoveProjection drops all projected rows
+func (m *Matrix) RemoveProjection() {
+	m.Rows = m.CandleRows()
+	m.DataRows = m.DataRows[:m.Rows]
+}
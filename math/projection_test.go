@@ -0,0 +1,78 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestProjectIchimoku(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	cal := WeekdayCalendar()
+	for i := 0; i < 80; i++ {
+		v := 100.0 + float64(i)
+		mat.AddRow(day.Format(KEY_FORMAT)).Set(OPEN, v).Set(HIGH, v+1).Set(LOW, v-1).Set(CLOSE, v).Set(ADJ_CLOSE, v)
+		day = cal.NextBusinessDay(day)
+	}
+	first, err := mat.Project(cal, 26, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 80, first)
+	assert.Equal(t, 80, mat.CandleRows())
+	assert.Equal(t, "2024-04-23 00:00", mat.DataRows[80].Key)
+	sma := ShiftedSMA(mat, 5, 10, CLOSE)
+	assert.Equal(t, 177.0, mat.DataRows[89].Get(sma))
+	assert.Equal(t, 0.0, mat.DataRows[90].Get(sma))
+	ich := Ichimoku(mat, 9, 26, 52)
+	assert.Equal(t, 0.0, mat.DataRows[80].Get(ich))
+	assert.Equal(t, ich+5, mat.Cols)
+	// the Senkou spans of the candles 54 to 79 end up in the projected rows
+	for i := 80; i < 106; i++ {
+		assert.NotEqual(t, 0.0, mat.DataRows[i].Get(ich+2), "row %d", i)
+		assert.NotEqual(t, 0.0, mat.DataRows[i].Get(ich+3), "row %d", i)
+	}
+	assert.Equal(t, 0.0, mat.DataRows[25].Get(ich+2))
+	assert.Equal(t, 169.75, mat.DataRows[105].Get(ich+2))
+	assert.Equal(t, 152.5, mat.DataRows[105].Get(ich+3))
+	// the Chikou span of the last 26 candles is not known yet
+	assert.Equal(t, 179.0, mat.DataRows[53].Get(ich+4))
+	assert.Equal(t, 0.0, mat.DataRows[54].Get(ich+4))
+	mat.RemoveProjection()
+	assert.Equal(t, 80, mat.Rows)
+	assert.False(t, mat.HasProjection())
+}
+
+func TestProjectedRowsStayEmpty(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	cal := WeekdayCalendar()
+	for i := 0; i < 60; i++ {
+		v := 100.0 + float64(i%7)*2.0
+		mat.AddRow(day.Format(KEY_FORMAT)).Set(OPEN, v).Set(HIGH, v+1).Set(LOW, v-1).Set(CLOSE, v).Set(ADJ_CLOSE, v)
+		day = cal.NextBusinessDay(day)
+	}
+	_, err := mat.Project(cal, 10, 24*time.Hour)
+	assert.NoError(t, err)
+	rsi := RSI(mat, 14, ADJ_CLOSE)
+	macd := MACD(mat, 12, 26, 9)
+	bb := BollingerBand(mat, 20, 2.0, 2.0)
+	assert.NotEqual(t, 0.0, mat.DataRows[59].Get(rsi))
+	assert.NotEqual(t, 0.0, mat.DataRows[59].Get(bb))
+	for i := mat.CandleRows(); i < mat.Rows; i++ {
+		for c := rsi; c < mat.Cols; c++ {
+			assert.Equal(t, 0.0, mat.DataRows[i].Get(c), "row %d column %d", i, c)
+		}
+	}
+	assert.True(t, macd > rsi && bb > macd)
+}
+
+func TestProjectedRowsNoSwings(t *testing.T) {
+	mat := candleMatrix(closeBars([]float64{100, 101, 102, 103, 104, 105, 106, 107, 108, 109}, 0.5))
+	_, err := mat.Project(WeekdayCalendar(), 5, 24*time.Hour)
+	assert.NoError(t, err)
+	// the empty projected rows do not turn the last candle into a swing high
+	assert.Equal(t, 0, len(mat.FindSwingPoints()))
+	assert.Equal(t, 0, len(mat.FindSwingPointsByField(ADJ_CLOSE)))
+	assert.Equal(t, 0, len(mat.Fractals(5)))
+}
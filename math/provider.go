@@ -0,0 +1,620 @@
+package math
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const KEY_FORMAT = "2006-01-02 15:04"
+
+var CANDLE_HEADERS = []string{"Open", "High", "Low", "Close", "AdjClose", "Volume"}
+
+// Provider loads candles for a symbol and timeframe. from and to are keys
+// in KEY_FORMAT (or only the date part) and an empty string is open ended.
+type Provider interface {
+	Fetch(symbol, timeframe, from, to string) (*Matrix, error)
+}
+
+var ErrInvalidName = errors.New("symbol and timeframe must not be empty or contain path separators")
+
+// checkName makes sure a symbol or timeframe can not leave the data directory
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// NewCandleMatrix returns an empty matrix with the OPEN ... VOLUME columns
+func NewCandleMatrix() *Matrix {
+	return NewMatrixWithHeaders(len(CANDLE_HEADERS), CANDLE_HEADERS)
+}
+
+// normalizeRange extends a date only to so it includes all rows of that day
+func normalizeRange(from, to string) (string, string) {
+	if to != "" && len(to) == 10 {
+		to += " 23:59"
+	}
+	return from, to
+}
+
+// sortedCandles sorts by key and keeps only the rows inside from/to
+func sortedCandles(candles *Matrix, from, to string) *Matrix {
+	sort.SliceStable(candles.DataRows, func(i, j int) bool {
+		return candles.DataRows[i].Key < candles.DataRows[j].Key
+	})
+	from, to = normalizeRange(from, to)
+	ret := NewMatrixWithHeaders(candles.Cols, candles.Headers[1:])
+	ret.Info = candles.Info
+	for _, r := range candles.DataRows {
+		if (from == "" || r.Key >= from) && (to == "" || r.Key <= to) {
+			ret.DataRows = append(ret.DataRows, r)
+			ret.Rows++
+		}
+	}
+	return ret
+}
+
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	KEY_FORMAT,
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"01/02/2006",
+}
+
+// ConvertTimestamp converts a date, date time or unix timestamp into KEY_FORMAT
+func ConvertTimestamp(ts string) (string, error) {
+	ts = strings.TrimSpace(ts)
+	if v, err := strconv.ParseInt(ts, 10, 64); err == nil && len(ts) > 8 {
+		return time.Unix(v, 0).UTC().Format(KEY_FORMAT), nil
+	}
+	for _, l := range timestampLayouts {
+		if t, err := time.Parse(l, ts); err == nil {
+			return t.Format(KEY_FORMAT), nil
+		}
+	}
+	return "", fmt.Errorf("unknown timestamp format: %s", ts)
+}
+
+// -----------------------------------------------------------------------
+//
+//	CSVProvider
+//
+// -----------------------------------------------------------------------
+// Reads <Dir>/<symbol>/<timeframe>.csv. The first line must contain the column
+// names. Known names are date/time/timestamp/key, open, high, low, close,
+// adj close and volume (case insensitive). Missing adj close is taken from close.
+type CSVProvider struct {
+	Dir       string
+	Separator rune
+	FileName  func(symbol, timeframe string) string
+}
+
+func NewCSVProvider(dir string) *CSVProvider {
+	return &CSVProvider{
+		Dir:       dir,
+		Separator: ',',
+	}
+}
+
+func (p *CSVProvider) path(symbol, timeframe string) (string, error) {
+	if err := checkName(symbol); err != nil {
+		return "", err
+	}
+	if err := checkName(timeframe); err != nil {
+		return "", err
+	}
+	if p.FileName != nil {
+		return filepath.Join(p.Dir, p.FileName(symbol, timeframe)), nil
+	}
+	return filepath.Join(p.Dir, symbol, timeframe+".csv"), nil
+}
+
+func csvColumn(name string) int {
+	n := strings.ToLower(strings.TrimSpace(name))
+	n = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(n)
+	switch n {
+	case "date", "time", "timestamp", "datetime", "key":
+		return -1
+	case "open", "o":
+		return OPEN
+	case "high", "h":
+		return HIGH
+	case "low", "l":
+		return LOW
+	case "close", "c":
+		return CLOSE
+	case "adjclose", "adjustedclose":
+		return ADJ_CLOSE
+	case "volume", "vol", "v":
+		return VOLUME
+	}
+	return -2
+}
+
+// ParseCSVCandles reads candles from a CSV stream with a header line
+func ParseCSVCandles(r io.Reader, separator rune) (*Matrix, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = separator
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty file")
+	}
+	mapping := make([]int, len(records[0]))
+	keyIdx := -1
+	hasAdj := false
+	for i, h := range records[0] {
+		mapping[i] = csvColumn(h)
+		if mapping[i] == -1 && keyIdx == -1 {
+			keyIdx = i
+		}
+		if mapping[i] == ADJ_CLOSE {
+			hasAdj = true
+		}
+	}
+	if keyIdx == -1 {
+		return nil, errors.New("no date column found")
+	}
+	ret := NewCandleMatrix()
+	for _, rec := range records[1:] {
+		if len(rec) <= keyIdx {
+			continue
+		}
+		key, err := ConvertTimestamp(rec[keyIdx])
+		if err != nil {
+			return nil, err
+		}
+		row := ret.ForcedAddRow(key)
+		for i, v := range rec {
+			if i >= len(mapping) || mapping[i] < 0 {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s' at %s", v, key)
+			}
+			row.Set(mapping[i], f)
+		}
+		if !hasAdj {
+			row.Set(ADJ_CLOSE, row.Get(CLOSE))
+		}
+	}
+	return ret, nil
+}
+
+func (p *CSVProvider) Fetch(symbol, timeframe, from, to string) (*Matrix, error) {
+	fileName, err := p.path(symbol, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	candles, err := ParseCSVCandles(f, p.Separator)
+	if err != nil {
+		return nil, err
+	}
+	return sortedCandles(candles, from, to), nil
+}
+
+// -----------------------------------------------------------------------
+//
+//	Yahoo chart JSON
+//
+// -----------------------------------------------------------------------
+type yahooChart struct {
+	Chart struct {
+		Result []struct {
+			Meta struct {
+				Symbol          string `json:"symbol"`
+				DataGranularity string `json:"dataGranularity"`
+				GmtOffset       int64  `json:"gmtoffset"`
+			} `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Open   []*float64 `json:"open"`
+					High   []*float64 `json:"high"`
+					Low    []*float64 `json:"low"`
+					Close  []*float64 `json:"close"`
+					Volume []*float64 `json:"volume"`
+				} `json:"quote"`
+				AdjClose []struct {
+					AdjClose []*float64 `json:"adjclose"`
+				} `json:"adjclose"`
+			} `json:"indicators"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+func isDailyGranularity(g string) bool {
+	return g == "1d" || g == "5d" || g == "1wk" || g == "1mo" || g == "3mo"
+}
+
+func yahooValue(values []*float64, idx int) (float64, bool) {
+	if idx >= len(values) || values[idx] == nil {
+		return 0.0, false
+	}
+	return *values[idx], true
+}
+
+// ParseYahooChart converts the response of the Yahoo v8 chart API. Bars with
+// missing prices are skipped.
+func ParseYahooChart(data []byte) (*Matrix, error) {
+	var yc yahooChart
+	if err := json.Unmarshal(data, &yc); err != nil {
+		return nil, err
+	}
+	if yc.Chart.Error != nil {
+		return nil, fmt.Errorf("yahoo: %s - %s", yc.Chart.Error.Code, yc.Chart.Error.Description)
+	}
+	if len(yc.Chart.Result) == 0 {
+		return nil, errors.New("yahoo: empty result")
+	}
+	res := yc.Chart.Result[0]
+	if len(res.Indicators.Quote) == 0 {
+		return nil, errors.New("yahoo: no quotes")
+	}
+	q := res.Indicators.Quote[0]
+	var adj []*float64
+	if len(res.Indicators.AdjClose) > 0 {
+		adj = res.Indicators.AdjClose[0].AdjClose
+	}
+	daily := isDailyGranularity(res.Meta.DataGranularity)
+	ret := NewCandleMatrix()
+	ret.Info = res.Meta.Symbol
+	for i, ts := range res.Timestamp {
+		o, ok1 := yahooValue(q.Open, i)
+		h, ok2 := yahooValue(q.High, i)
+		l, ok3 := yahooValue(q.Low, i)
+		c, ok4 := yahooValue(q.Close, i)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			continue
+		}
+		v, _ := yahooValue(q.Volume, i)
+		ac, ok := yahooValue(adj, i)
+		if !ok {
+			ac = c
+		}
+		t := time.Unix(ts+res.Meta.GmtOffset, 0).UTC()
+		key := t.Format(KEY_FORMAT)
+		if daily {
+			key = t.Format("2006-01-02") + " 00:00"
+		}
+		ret.ForcedAddRow(key).Set(OPEN, o).Set(HIGH, h).Set(LOW, l).Set(CLOSE, c).Set(ADJ_CLOSE, ac).Set(VOLUME, v)
+	}
+	return ret, nil
+}
+
+// -----------------------------------------------------------------------
+//
+//	Alpha Vantage time series JSON
+//
+// -----------------------------------------------------------------------
+// ParseAlphaVantage converts any of the TIME_SERIES_* responses (daily,
+// daily adjusted, weekly, monthly and intraday)
+func ParseAlphaVantage(data []byte) (*Matrix, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"Error Message", "Information", "Note"} {
+		if msg, ok := raw[k]; ok {
+			var txt string
+			json.Unmarshal(msg, &txt)
+			return nil, fmt.Errorf("alphavantage: %s", txt)
+		}
+	}
+	var series map[string]map[string]string
+	for k, v := range raw {
+		if strings.HasPrefix(k, "Time Series") || strings.HasSuffix(k, "Time Series") {
+			if err := json.Unmarshal(v, &series); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if series == nil {
+		return nil, errors.New("alphavantage: no time series found")
+	}
+	ret := NewCandleMatrix()
+	for ts, values := range series {
+		key, err := ConvertTimestamp(ts)
+		if err != nil {
+			return nil, err
+		}
+		row := ret.ForcedAddRow(key)
+		hasAdj := false
+		for name, value := range values {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("alphavantage: invalid value '%s' at %s", value, ts)
+			}
+			// names look like "1. open" or "5. adjusted close"
+			idx := strings.Index(name, ". ")
+			if idx != -1 {
+				name = name[idx+2:]
+			}
+			col := csvColumn(name)
+			if col >= 0 {
+				row.Set(col, f)
+			}
+			if col == ADJ_CLOSE {
+				hasAdj = true
+			}
+		}
+		if !hasAdj {
+			row.Set(ADJ_CLOSE, row.Get(CLOSE))
+		}
+	}
+	return sortedCandles(ret, "", ""), nil
+}
+
+// -----------------------------------------------------------------------
+//
+//	JSON providers
+//
+// -----------------------------------------------------------------------
+// JSONFileProvider reads <Dir>/<symbol>/<timeframe>.json and converts it with Parse
+type JSONFileProvider struct {
+	Dir      string
+	Parse    func(data []byte) (*Matrix, error)
+	FileName func(symbol, timeframe string) string
+}
+
+func NewJSONFileProvider(dir string, parse func(data []byte) (*Matrix, error)) *JSONFileProvider {
+	return &JSONFileProvider{
+		Dir:   dir,
+		Parse: parse,
+	}
+}
+
+func (p *JSONFileProvider) Fetch(symbol, timeframe, from, to string) (*Matrix, error) {
+	if err := checkName(symbol); err != nil {
+		return nil, err
+	}
+	if err := checkName(timeframe); err != nil {
+		return nil, err
+	}
+	fileName := filepath.Join(p.Dir, symbol, timeframe+".json")
+	if p.FileName != nil {
+		fileName = filepath.Join(p.Dir, p.FileName(symbol, timeframe))
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	candles, err := p.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return sortedCandles(candles, from, to), nil
+}
+
+// HTTPProvider requests the URL built by BuildURL with Client and converts the body with Parse
+type HTTPProvider struct {
+	Client   *http.Client
+	BuildURL func(symbol, timeframe, from, to string) (string, error)
+	Parse    func(data []byte) (*Matrix, error)
+}
+
+func (p *HTTPProvider) Fetch(symbol, timeframe, from, to string) (*Matrix, error) {
+	url, err := p.BuildURL(symbol, timeframe, from, to)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	candles, err := p.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return sortedCandles(candles, from, to), nil
+}
+
+func keyToUnix(key string, fallback int64) (int64, error) {
+	if key == "" {
+		return fallback, nil
+	}
+	if len(key) == 10 {
+		key += " 00:00"
+	}
+	t, err := time.Parse(KEY_FORMAT, key)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// NewYahooProvider uses the v8 chart API. baseURL is e.g. https://query1.finance.yahoo.com
+func NewYahooProvider(client *http.Client, baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		Client: client,
+		Parse:  ParseYahooChart,
+		BuildURL: func(symbol, timeframe, from, to string) (string, error) {
+			p1, err := keyToUnix(from, 0)
+			if err != nil {
+				return "", err
+			}
+			p2, err := keyToUnix(to, time.Now().Unix())
+			if err != nil {
+				return "", err
+			}
+			if to != "" {
+				p2 += 24 * 60 * 60
+			}
+			return fmt.Sprintf("%s/v8/finance/chart/%s?period1=%d&period2=%d&interval=%s&events=div%%2Csplit",
+				strings.TrimSuffix(baseURL, "/"), url.PathEscape(symbol), p1, p2, url.QueryEscape(timeframe)), nil
+		},
+	}
+}
+
+func alphaVantageFunction(timeframe string) (string, string, error) {
+	switch timeframe {
+	case "1d":
+		return "TIME_SERIES_DAILY_ADJUSTED", "", nil
+	case "1wk", "1w":
+		return "TIME_SERIES_WEEKLY_ADJUSTED", "", nil
+	case "1mo", "1M":
+		return "TIME_SERIES_MONTHLY_ADJUSTED", "", nil
+	case "1m":
+		return "TIME_SERIES_INTRADAY", "1min", nil
+	case "5m":
+		return "TIME_SERIES_INTRADAY", "5min", nil
+	case "15m":
+		return "TIME_SERIES_INTRADAY", "15min", nil
+	case "30m":
+		return "TIME_SERIES_INTRADAY", "30min", nil
+	case "60m", "1h":
+		return "TIME_SERIES_INTRADAY", "60min", nil
+	}
+	return "", "", fmt.Errorf("alphavantage: unsupported timeframe %s", timeframe)
+}
+
+// NewAlphaVantageProvider uses the TIME_SERIES_* functions. baseURL is e.g. https://www.alphavantage.co
+func NewAlphaVantageProvider(client *http.Client, baseURL, apiKey string) *HTTPProvider {
+	return &HTTPProvider{
+		Client: client,
+		Parse:  ParseAlphaVantage,
+		BuildURL: func(symbol, timeframe, from, to string) (string, error) {
+			fn, interval, err := alphaVantageFunction(timeframe)
+			if err != nil {
+				return "", err
+			}
+			ret := fmt.Sprintf("%s/query?function=%s&symbol=%s&outputsize=full&apikey=%s",
+				strings.TrimSuffix(baseURL, "/"), fn, url.QueryEscape(symbol), url.QueryEscape(apiKey))
+			if interval != "" {
+				ret += "&interval=" + interval
+			}
+			return ret, nil
+		},
+	}
+}
+
+// -----------------------------------------------------------------------
+//
+//	CachingProvider
+//
+// -----------------------------------------------------------------------
+// CachingProvider keeps the results of Source in memory for TTL (0 = forever).
+// If Store is set every result is also written to the store and the store is
+// used as fallback if the source fails.
+type CachingProvider struct {
+	Source  Provider
+	TTL     time.Duration
+	Store   *CandleStore
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	candles *Matrix
+	created time.Time
+}
+
+func NewCachingProvider(source Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		Source:  source,
+		TTL:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func copyCandles(candles *Matrix) *Matrix {
+	ret := NewMatrixWithHeaders(candles.Cols, candles.Headers[1:])
+	ret.Info = candles.Info
+	for _, r := range candles.DataRows {
+		nr := r
+		nr.Values = append([]float64(nil), r.Values...)
+		ret.DataRows = append(ret.DataRows, nr)
+		ret.Rows++
+	}
+	return ret
+}
+
+func (p *CachingProvider) Fetch(symbol, timeframe, from, to string) (*Matrix, error) {
+	from, to = normalizeRange(from, to)
+	key := strings.Join([]string{symbol, timeframe, from, to}, "|")
+	p.mu.Lock()
+	if p.entries == nil {
+		p.entries = make(map[string]cacheEntry)
+	}
+	e, ok := p.entries[key]
+	p.mu.Unlock()
+	if ok && (p.TTL == 0 || time.Since(e.created) < p.TTL) {
+		// callers add indicator columns so they always get their own copy
+		return copyCandles(e.candles), nil
+	}
+	candles, err := p.Source.Fetch(symbol, timeframe, from, to)
+	if err != nil {
+		if p.Store != nil {
+			if stored, serr := p.Store.ReadRange(symbol, timeframe, from, to); serr == nil && stored.Rows > 0 {
+				return stored, nil
+			}
+		}
+		return nil, err
+	}
+	if p.Store != nil {
+		if err := p.Store.Upsert(symbol, timeframe, candles); err != nil {
+			return nil, err
+		}
+	}
+	p.mu.Lock()
+	p.entries[key] = cacheEntry{
+		candles: copyCandles(candles),
+		created: time.Now(),
+	}
+	p.mu.Unlock()
+	return candles, nil
+}
+
+// Invalidate removes all cached entries of a symbol
+func (p *CachingProvider) Invalidate(symbol string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k := range p.entries {
+		if strings.HasPrefix(k, symbol+"|") {
+			delete(p.entries, k)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package math
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+const yahooResponse = `{"chart":{"result":[{"meta":{"symbol":"SAP.DE","dataGranularity":"1d","gmtoffset":3600},
+"timestamp":[1704182400,1704268800,1704355200],
+"indicators":{"quote":[{"open":[10,11,null],"high":[12,13,null],"low":[9,10,null],"close":[11,12,null],"volume":[100,200,null]}],
+"adjclose":[{"adjclose":[10.5,11.5,null]}]}}],"error":null}}`
+
+const alphaVantageResponse = `{"Meta Data":{"2. Symbol":"IBM"},"Time Series (Daily)":{
+"2024-01-03":{"1. open":"11.0","2. high":"13.0","3. low":"10.0","4. close":"12.0","5. volume":"200"},
+"2024-01-02":{"1. open":"10.0","2. high":"12.0","3. low":"9.0","4. close":"11.0","5. volume":"100"}}}`
+
+func TestYahooProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, yahooResponse)
+	}))
+	defer srv.Close()
+	p := NewYahooProvider(srv.Client(), srv.URL)
+	mat, err := p.Fetch("SAP.DE", "1d", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mat.Rows)
+	assert.Equal(t, "2024-01-02 00:00", mat.DataRows[0].Key)
+	assert.Equal(t, 10.5, mat.DataRows[0].Get(ADJ_CLOSE))
+	assert.Equal(t, 11.0, mat.DataRows[0].Get(CLOSE))
+}
+
+func TestAlphaVantageProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, alphaVantageResponse)
+	}))
+	defer srv.Close()
+	p := NewAlphaVantageProvider(srv.Client(), srv.URL, "demo")
+	mat, err := p.Fetch("IBM", "1d", "2024-01-03", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mat.Rows)
+	assert.Equal(t, 12.0, mat.DataRows[0].Get(ADJ_CLOSE))
+	assert.Equal(t, 200.0, mat.DataRows[0].Get(VOLUME))
+}
+
+func TestCSVProviderWithCache(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "SAP"), 0o755))
+	csv := "Date,Open,High,Low,Close,Adj Close,Volume\n2024-01-03,11,13,10,12,11.5,200\n2024-01-02,10,12,9,11,10.5,100\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "SAP", "1d.csv"), []byte(csv), 0o644))
+	p := NewCachingProvider(NewCSVProvider(dir), 0)
+	mat, err := p.Fetch("SAP", "1d", "", "2024-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mat.Rows)
+	assert.Equal(t, 10.5, mat.DataRows[0].Get(ADJ_CLOSE))
+	// served from the cache even though the file is gone
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "SAP")))
+	mat, err = p.Fetch("SAP", "1d", "", "2024-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mat.Rows)
+}
+
+func TestCachingProviderStoreFallback(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "SAP"), 0o755))
+	csv := "Date,Open,High,Low,Close,Adj Close,Volume\n2024-01-03,11,13,10,12,11.5,200\n2024-01-02,10,12,9,11,10.5,100\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "SAP", "1d.csv"), []byte(csv), 0o644))
+	store, err := NewCandleStore(t.TempDir())
+	assert.NoError(t, err)
+	p := NewCachingProvider(NewCSVProvider(dir), 0)
+	p.Store = store
+	mat, err := p.Fetch("SAP", "1d", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mat.Rows)
+	// the source is gone so the store answers with the same range
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "SAP")))
+	mat, err = p.Fetch("SAP", "1d", "2024-01-02", "2024-01-03")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, mat.Rows)
+	assert.Equal(t, "2024-01-03 00:00", mat.DataRows[1].Key)
+}
+
+func TestProviderSymbolEscape(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewCSVProvider(dir).Fetch("../SAP", "1d", "", "")
+	assert.IsError(t, err, ErrInvalidName)
+	_, err = NewJSONFileProvider(dir, ParseYahooChart).Fetch("SAP", "/1d", "", "")
+	assert.IsError(t, err, ErrInvalidName)
+	path := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		fmt.Fprint(w, yahooResponse)
+	}))
+	defer srv.Close()
+	_, err = NewYahooProvider(srv.Client(), srv.URL).Fetch("../SAP", "1d", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v8/finance/chart/..%2FSAP", path)
+}
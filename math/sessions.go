@@ -0,0 +1,264 @@
+package math
+
+import (
+	"math"
+	"time"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Trading sessions
+//
+// -----------------------------------------------------------------------
+// Session defines the trading hours as "15:04" in the time of the keys. If
+// End is before Start the session crosses midnight and belongs to the day
+// it started.
+type Session struct {
+	Name  string
+	Start string
+	End   string
+}
+
+func NewSession(name, start, end string) Session {
+	return Session{
+		Name:  name,
+		Start: start,
+		End:   end,
+	}
+}
+
+// SessionFromCalendar uses the regular trading hours of the calendar
+func SessionFromCalendar(cal *TradingCalendar) Session {
+	return NewSession(cal.Name, cal.Open, cal.Close)
+}
+
+func (s Session) overnight() bool {
+	return s.End < s.Start
+}
+
+func keyTime(key string) string {
+	if len(key) < 16 {
+		return "00:00"
+	}
+	return key[11:16]
+}
+
+// Contains checks if the time of the key is inside the session
+func (s Session) Contains(key string) bool {
+	tm := keyTime(key)
+	if s.overnight() {
+		return tm >= s.Start || tm < s.End
+	}
+	return tm >= s.Start && tm < s.End
+}
+
+// SessionDate returns the date of the session the key belongs to or an
+// empty string if the key is outside the session
+func (s Session) SessionDate(key string) string {
+	if !s.Contains(key) {
+		return ""
+	}
+	if s.overnight() && keyTime(key) < s.End {
+		t, err := parseKey(key)
+		if err != nil {
+			return ""
+		}
+		return t.AddDate(0, 0, -1).Format(DATE_FORMAT)
+	}
+	return key[:10]
+}
+
+// minutesSinceStart returns the minutes between the session start and the key
+func (s Session) minutesSinceStart(key string) int {
+	st, err1 := time.Parse("15:04", s.Start)
+	kt, err2 := time.Parse("15:04", keyTime(key))
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	d := int(kt.Sub(st).Minutes())
+	if d < 0 {
+		d += 24 * 60
+	}
+	return d
+}
+
+// SessionIndices returns the number of the session for every row. Rows
+// outside of the session get -1.
+func SessionIndices(m *Matrix, s Session) []int {
+	ret := make([]int, m.Rows)
+	cur := -1
+	last := ""
+	for i := 0; i < m.Rows; i++ {
+		sd := s.SessionDate(m.DataRows[i].Key)
+		if sd == "" {
+			ret[i] = -1
+			continue
+		}
+		if sd != last {
+			cur++
+			last = sd
+		}
+		ret[i] = cur
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------
+// Session VWAP
+// -----------------------------------------------------------------------
+// SessionVWAP is the volume weighted average price anchored at the start of
+// every session. The bands use the volume weighted standard deviation of the
+// typical price since the session start.
+func SessionVWAP(m *Matrix, s Session, std float64) int {
+	// 0 = VWAP 1 = Upper 2 = Lower
+	ret := m.AddNamedColumn("SessionVWAP")
+	upper := m.AddNamedColumn("Upper")
+	lower := m.AddNamedColumn("Lower")
+	ids := SessionIndices(m, s)
+	start := -1
+	for i := 0; i <= m.CandleRows(); i++ {
+		if i < m.CandleRows() && start != -1 && ids[i] == ids[start] {
+			continue
+		}
+		if start != -1 {
+			accumulateVWAP(m, start, i, ret, upper, lower, std)
+			start = -1
+		}
+		if i < m.CandleRows() && ids[i] != -1 {
+			start = i
+		}
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------
+// Opening range
+// -----------------------------------------------------------------------
+type OpeningRangeInfo struct {
+	Session string
+	// first and last row of the opening range
+	Start int
+	End   int
+	High  float64
+	Low   float64
+	// index of the first close above the high / below the low or -1
+	BreakoutUp   int
+	BreakoutDown int
+	// highest high and lowest low of the session after the opening range
+	// measured in multiples of the opening range width
+	ExtensionUp   float64
+	ExtensionDown float64
+}
+
+func (ori *OpeningRangeInfo) Width() float64 {
+	return ori.High - ori.Low
+}
+
+// FindOpeningRanges returns the range of the first minutes of every session
+func FindOpeningRanges(m *Matrix, s Session, minutes int) []OpeningRangeInfo {
+	ret := make([]OpeningRangeInfo, 0)
+	ids := SessionIndices(m, s)
+	var cur *OpeningRangeInfo
+	for i := 0; i < m.CandleRows(); i++ {
+		if ids[i] == -1 {
+			continue
+		}
+		p := &m.DataRows[i]
+		if cur == nil || cur.Session != s.SessionDate(p.Key) {
+			ret = append(ret, OpeningRangeInfo{
+				Session:      s.SessionDate(p.Key),
+				Start:        i,
+				End:          i,
+				High:         p.High(),
+				Low:          p.Low(),
+				BreakoutUp:   -1,
+				BreakoutDown: -1,
+			})
+			cur = &ret[len(ret)-1]
+			continue
+		}
+		if s.minutesSinceStart(p.Key) < minutes {
+			cur.End = i
+			cur.High = math.Max(cur.High, p.High())
+			cur.Low = math.Min(cur.Low, p.Low())
+			continue
+		}
+		if cur.BreakoutUp == -1 && p.Close() > cur.High {
+			cur.BreakoutUp = i
+		}
+		if cur.BreakoutDown == -1 && p.Close() < cur.Low {
+			cur.BreakoutDown = i
+		}
+		if w := cur.Width(); w > 0.0 {
+			cur.ExtensionUp = math.Max(cur.ExtensionUp, (p.High()-cur.High)/w)
+			cur.ExtensionDown = math.Max(cur.ExtensionDown, (cur.Low-p.Low())/w)
+		}
+	}
+	return ret
+}
+
+// OpeningRange adds the opening range of the current session to every row
+// after the range is complete
+func OpeningRange(m *Matrix, s Session, minutes int) int {
+	// 0 = High 1 = Low 2 = Position of close in range (0 = low 1 = high) 3 = State (1 = above -1 = below 0 = inside)
+	ret := m.AddNamedColumn("ORHigh")
+	lowIdx := m.AddNamedColumn("ORLow")
+	posIdx := m.AddNamedColumn("ORPos")
+	stateIdx := m.AddNamedColumn("ORState")
+	ranges := FindOpeningRanges(m, s, minutes)
+	ids := SessionIndices(m, s)
+	for i, or := range ranges {
+		end := m.CandleRows()
+		if i+1 < len(ranges) {
+			end = ranges[i+1].Start
+		}
+		for j := or.End + 1; j < end; j++ {
+			if ids[j] == -1 {
+				continue
+			}
+			p := &m.DataRows[j]
+			p.Set(ret, or.High)
+			p.Set(lowIdx, or.Low)
+			if w := or.Width(); w > 0.0 {
+				p.Set(posIdx, (p.Close()-or.Low)/w)
+			}
+			if p.Close() > or.High {
+				p.Set(stateIdx, 1.0)
+			} else if p.Close() < or.Low {
+				p.Set(stateIdx, -1.0)
+			}
+		}
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------
+// Relative volume by time of day
+// -----------------------------------------------------------------------
+// TimeOfDayRelativeVolume compares the volume with the average volume of the
+// same time of day in the previous sessions. Rows without enough history are 0.
+func TimeOfDayRelativeVolume(m *Matrix, s Session, sessions int) int {
+	// 0 = relative volume
+	ret := m.AddNamedColumn("RelVolToD")
+	ids := SessionIndices(m, s)
+	history := make(map[string][]float64)
+	for i := 0; i < m.CandleRows(); i++ {
+		if ids[i] == -1 {
+			continue
+		}
+		p := &m.DataRows[i]
+		tm := keyTime(p.Key)
+		prev := history[tm]
+		if len(prev) >= sessions {
+			sum := 0.0
+			for _, v := range prev[len(prev)-sessions:] {
+				sum += v
+			}
+			if sum > 0.0 {
+				p.Set(ret, p.Get(VOLUME)/(sum/float64(sessions)))
+			}
+		}
+		history[tm] = append(prev, p.Get(VOLUME))
+	}
+	return ret
+}
@@ -0,0 +1,57 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildSessionCandles() *Matrix {
+	mat := NewCandleMatrix()
+	add := func(key string, h, l, c, v float64) {
+		mat.AddRow(key).Set(OPEN, c).Set(HIGH, h).Set(LOW, l).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, v)
+	}
+	add("2024-01-02 09:00", 11, 9, 10, 100)
+	add("2024-01-02 09:30", 12, 10, 11, 100)
+	add("2024-01-02 10:00", 14, 12, 13, 200)
+	add("2024-01-02 18:00", 14, 12, 13, 200)
+	add("2024-01-03 09:00", 21, 19, 20, 300)
+	add("2024-01-03 09:30", 20, 18, 19, 100)
+	add("2024-01-03 10:00", 19, 15, 16, 100)
+	return mat
+}
+
+func TestSessionVWAP(t *testing.T) {
+	mat := buildSessionCandles()
+	s := NewSession("XETRA", "09:00", "17:30")
+	assert.Equal(t, []int{0, 0, 0, -1, 1, 1, 1}, SessionIndices(mat, s))
+	vw := SessionVWAP(mat, s, 2.0)
+	// typical prices 10 and 11 with the same volume
+	assert.Equal(t, 10.5, mat.DataRows[1].Get(vw))
+	assert.Equal(t, 11.5, mat.DataRows[1].Get(vw+1))
+	assert.Equal(t, 9.5, mat.DataRows[1].Get(vw+2))
+	// (10 * 100 + 11 * 100 + 13 * 200) / 400
+	assert.Equal(t, 11.75, mat.DataRows[2].Get(vw))
+	assert.Equal(t, 0.0, mat.DataRows[3].Get(vw))
+	// reset on the next session
+	assert.Equal(t, 20.0, mat.DataRows[4].Get(vw))
+	assert.Equal(t, 20.0, mat.DataRows[4].Get(vw+1))
+}
+
+func TestOpeningRange(t *testing.T) {
+	mat := buildSessionCandles()
+	s := NewSession("XETRA", "09:00", "17:30")
+	ranges := FindOpeningRanges(mat, s, 60)
+	assert.Equal(t, 2, len(ranges))
+	assert.Equal(t, 12.0, ranges[0].High)
+	assert.Equal(t, 9.0, ranges[0].Low)
+	assert.Equal(t, 2, ranges[0].BreakoutUp)
+	assert.Equal(t, 6, ranges[1].BreakoutDown)
+	or := OpeningRange(mat, s, 60)
+	assert.Equal(t, 12.0, mat.DataRows[2].Get(or))
+	assert.Equal(t, 1.0, mat.DataRows[2].Get(or+3))
+	assert.Equal(t, -1.0, mat.DataRows[6].Get(or+3))
+	rv := TimeOfDayRelativeVolume(mat, s, 1)
+	assert.Equal(t, 3.0, mat.DataRows[4].Get(rv))
+	assert.Equal(t, 0.5, mat.DataRows[6].Get(rv))
+}
@@ -0,0 +1,130 @@
+package math
+
+import "sort"
+
+// -----------------------------------------------------------------------
+//
+//	Market structure - break of structure and change of character
+//
+// -----------------------------------------------------------------------
+type StructureEventType int
+
+const (
+	// BOS - break of structure in the direction of the trend
+	BOS StructureEventType = iota + 1
+	// CHOCH - change of character breaks against the trend
+	CHOCH
+)
+
+func (st StructureEventType) String() string {
+	switch st {
+	case BOS:
+		return "BOS"
+	case CHOCH:
+		return "CHoCH"
+	}
+	return "-"
+}
+
+type StructureEvent struct {
+	Type StructureEventType
+	// Index and Key of the candle closing beyond the level
+	Index int
+	Key   string
+	Level float64
+	// Direction is 1 for a bullish and -1 for a bearish break
+	Direction int
+	// Swing is the index of the broken swing point in the SwingPoints
+	Swing int
+}
+
+// AnalyzeMarketStructure walks through the candles and checks if a close breaks
+// the last swing high or low. A swing point is only used lag bars after it
+// was formed (2 for FindSwingPoints) so there is no look ahead. The swing points
+// are updated: Broken is set on the broken ones and Trend is the structure trend
+// at the time the swing point was confirmed.
+func AnalyzeMarketStructure(m *Matrix, sps SwingPoints, lag int) []StructureEvent {
+	ret := make([]StructureEvent, 0)
+	order := make([]int, len(sps))
+	for i := range order {
+		order[i] = i
+		sps[i].Broken = false
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sps[order[i]].Index < sps[order[j]].Index
+	})
+	trend := 0
+	next := 0
+	lastHigh := -1
+	lastLow := -1
+	for i := 0; i < m.CandleRows(); i++ {
+		for next < len(order) && sps[order[next]].Index+lag <= i {
+			sp := &sps[order[next]]
+			sp.Trend = trend
+			if sp.BaseType == High {
+				lastHigh = order[next]
+			} else {
+				lastLow = order[next]
+			}
+			next++
+		}
+		c := &m.DataRows[i]
+		if lastHigh != -1 && !sps[lastHigh].Broken && c.Close() > sps[lastHigh].Value {
+			ev := StructureEvent{
+				Type:      BOS,
+				Index:     i,
+				Key:       c.Key,
+				Level:     sps[lastHigh].Value,
+				Direction: 1,
+				Swing:     lastHigh,
+			}
+			if trend == -1 {
+				ev.Type = CHOCH
+			}
+			sps[lastHigh].Broken = true
+			trend = 1
+			ret = append(ret, ev)
+		}
+		if lastLow != -1 && !sps[lastLow].Broken && c.Close() < sps[lastLow].Value {
+			ev := StructureEvent{
+				Type:      BOS,
+				Index:     i,
+				Key:       c.Key,
+				Level:     sps[lastLow].Value,
+				Direction: -1,
+				Swing:     lastLow,
+			}
+			if trend == 1 {
+				ev.Type = CHOCH
+			}
+			sps[lastLow].Broken = true
+			trend = -1
+			ret = append(ret, ev)
+		}
+	}
+	return ret
+}
+
+// MarketStructure adds the structure state and the events of AnalyzeMarketStructure
+// based on FindSwingPoints
+func MarketStructure(m *Matrix) int {
+	// 0 = State (1 = bullish -1 = bearish 0 = undefined) 1 = Event (1 = BOS 2 = CHoCH negative if bearish) 2 = Level of the event
+	ret := m.AddNamedColumn("Structure")
+	evIdx := m.AddNamedColumn("StructEvent")
+	lvlIdx := m.AddNamedColumn("StructLevel")
+	sps := m.FindSwingPoints()
+	events := AnalyzeMarketStructure(m, sps, 2)
+	state := 0.0
+	cur := 0
+	for i := 0; i < m.CandleRows(); i++ {
+		for cur < len(events) && events[cur].Index == i {
+			ev := events[cur]
+			state = float64(ev.Direction)
+			m.DataRows[i].Set(evIdx, float64(int(ev.Type)*ev.Direction))
+			m.DataRows[i].Set(lvlIdx, ev.Level)
+			cur++
+		}
+		m.DataRows[i].Set(ret, state)
+	}
+	return ret
+}
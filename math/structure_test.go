@@ -0,0 +1,36 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestMarketStructure(t *testing.T) {
+	// HH/HL up to 120 then a lower low below the last higher low at 108
+	mat := candleMatrix(closeBars(pathCloses([]float64{100, 110, 105, 115, 108, 120, 102, 96}, 4), 0.5))
+	sps := mat.FindSwingPoints()
+	assert.Equal(t, []int{4, 8, 12, 16, 20}, []int{sps[0].Index, sps[1].Index, sps[2].Index, sps[3].Index, sps[4].Index})
+	events := AnalyzeMarketStructure(mat, sps, 2)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, StructureEvent{Type: BOS, Index: 11, Key: mat.DataRows[11].Key, Level: 110.5, Direction: 1, Swing: 0}, events[0])
+	assert.Equal(t, StructureEvent{Type: BOS, Index: 19, Key: mat.DataRows[19].Key, Level: 115.5, Direction: 1, Swing: 2}, events[1])
+	assert.Equal(t, StructureEvent{Type: CHOCH, Index: 23, Key: mat.DataRows[23].Key, Level: 107.5, Direction: -1, Swing: 3}, events[2])
+	// the trend of a swing point is the structure when it was confirmed
+	assert.Equal(t, 0, sps[1].Trend)
+	assert.Equal(t, 1, sps[4].Trend)
+	assert.True(t, sps[3].Broken)
+	assert.False(t, sps[1].Broken)
+
+	st := MarketStructure(mat)
+	assert.Equal(t, 0.0, mat.DataRows[10].Get(st))
+	assert.Equal(t, 1.0, mat.DataRows[11].Get(st))
+	assert.Equal(t, 1.0, mat.DataRows[11].Get(st+1))
+	assert.Equal(t, 110.5, mat.DataRows[11].Get(st+2))
+	assert.Equal(t, 1.0, mat.DataRows[19].Get(st+1))
+	assert.Equal(t, 1.0, mat.DataRows[22].Get(st))
+	assert.Equal(t, -1.0, mat.DataRows[23].Get(st))
+	assert.Equal(t, -2.0, mat.DataRows[23].Get(st+1))
+	assert.Equal(t, 107.5, mat.DataRows[23].Get(st+2))
+	assert.Equal(t, 0.0, mat.DataRows[24].Get(st+1))
+}
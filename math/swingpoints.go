@@ -4,7 +4,7 @@ func (m *Matrix) FindSwingPoints() SwingPoints {
 	var tmp SwingPoints
 	lv := 0.0
 	hv := 0.0
-	for i := 2; i < m.Rows-2; i++ {
+	for i := 2; i < m.CandleRows()-2; i++ {
 		p1 := m.DataRows[i-2]
 		p2 := m.DataRows[i-1]
 		pc := m.DataRows[i]
@@ -58,7 +58,7 @@ func (m *Matrix) FindSwingPoints() SwingPoints {
 	}
 	for i := 0; i < len(tmp); i++ {
 		c := &tmp[i]
-		for j := c.Index; j < m.Rows; j++ {
+		for j := c.Index; j < m.CandleRows(); j++ {
 			if c.BaseType == High && m.DataRows[j].High() > c.Value {
 				c.Broken = true
 			}
@@ -74,7 +74,7 @@ func (m *Matrix) FindTurningPoints(field int) SwingPoints {
 	var tmp SwingPoints
 	lv := 0.0
 	hv := 0.0
-	for i := 1; i < m.Rows-1; i++ {
+	for i := 1; i < m.CandleRows()-1; i++ {
 		p := m.DataRows[i-1]
 		c := m.DataRows[i]
 		n := m.DataRows[i+1]
@@ -123,7 +123,7 @@ func (m *Matrix) Fractals(size int) SwingPoints {
 	dist := size / 2
 	lv := 0.0
 	hv := 0.0
-	for i := dist; i < m.Rows-dist; i++ {
+	for i := dist; i < m.CandleRows()-dist; i++ {
 		c := m.DataRows[i]
 		ch := 0
 		cl := 0
@@ -191,7 +191,7 @@ func (m *Matrix) Fractals(size int) SwingPoints {
 	}
 	for i := 0; i < len(tmp); i++ {
 		c := &tmp[i]
-		for j := c.Index; j < m.Rows; j++ {
+		for j := c.Index; j < m.CandleRows(); j++ {
 			if c.BaseType == High && m.DataRows[j].High() > c.Value {
 				c.Broken = true
 			}
@@ -207,7 +207,7 @@ func (m *Matrix) FindSwingPointsByField(field int) SwingPoints {
 	var tmp SwingPoints
 	lv := 0.0
 	hv := 0.0
-	for i := 2; i < m.Rows-2; i++ {
+	for i := 2; i < m.CandleRows()-2; i++ {
 		p1 := m.DataRows[i-2]
 		p2 := m.DataRows[i-1]
 		pc := m.DataRows[i]
@@ -252,3 +252,51 @@ func (m *Matrix) FindSwingPointsByField(field int) SwingPoints {
 	}
 	return tmp
 }
+
+// FindPivots finds swing points with lookback bars on each side. A pivot high
+// in highField is higher than the bars to the left and not lower than the bars
+// to the right. Use the same field for both to find pivots of an oscillator.
+func (m *Matrix) FindPivots(highField, lowField, lookback int) SwingPoints {
+	var tmp SwingPoints
+	if lookback < 1 {
+		lookback = 1
+	}
+	rows := m.CandleRows()
+	for i := lookback; i < rows-lookback; i++ {
+		pc := &m.DataRows[i]
+		isHigh := true
+		isLow := true
+		for j := 1; j <= lookback; j++ {
+			l := &m.DataRows[i-j]
+			r := &m.DataRows[i+j]
+			if l.Get(highField) >= pc.Get(highField) || r.Get(highField) > pc.Get(highField) {
+				isHigh = false
+			}
+			if l.Get(lowField) <= pc.Get(lowField) || r.Get(lowField) < pc.Get(lowField) {
+				isLow = false
+			}
+		}
+		if isHigh {
+			tmp = append(tmp, SwingPoint{
+				Timestamp: pc.Key,
+				Type:      High,
+				BaseType:  High,
+				Value:     pc.Get(highField),
+				Price:     pc.Get(ADJ_CLOSE),
+				Index:     i,
+			})
+		}
+		if isLow {
+			tmp = append(tmp, SwingPoint{
+				Timestamp: pc.Key,
+				Type:      Low,
+				BaseType:  Low,
+				Value:     pc.Get(lowField),
+				Price:     pc.Get(ADJ_CLOSE),
+				Index:     i,
+			})
+		}
+	}
+	labelSwingPoints(tmp)
+	return tmp
+}
@@ -23,7 +23,7 @@ func PVI(candles *Matrix, period int) int {
 	// 0 = PVI 1 = Signal
 	ret := candles.AddNamedColumn("PVI")
 	candles.DataRows[0].Set(ret, (candles.DataRows[1].Get(4)-candles.DataRows[0].Get(4))/candles.DataRows[0].Get(4))
-	for i := 1; i < candles.Rows; i++ {
+	for i := 1; i < candles.CandleRows(); i++ {
 		c := &candles.DataRows[i]
 		p := candles.DataRows[i-1]
 		if c.Get(5) > p.Get(5) {
@@ -45,7 +45,7 @@ func ElderRayIndex(candles *Matrix, period int) int {
 	bup := candles.AddNamedColumn("BUP")
 	bep := candles.AddNamedColumn("BEP")
 	ei := EMA(candles, period, ADJ_CLOSE)
-	for i := 0; i < candles.Rows; i++ {
+	for i := 0; i < candles.CandleRows(); i++ {
 		c := &candles.DataRows[i]
 		c.Set(bup, c.Get(1)-c.Get(ei))
 		c.Set(bep, c.Get(2)-c.Get(ei))
@@ -104,7 +104,7 @@ func ParkinsonEstimator(candles *Matrix, period int) int {
 	})
 	e := 1.0 / (4.0 * float64(period) * m.Ln2)
 	si := candles.Sum(li, period)
-	for i := 0; i < candles.Rows; i++ {
+	for i := 0; i < candles.CandleRows(); i++ {
 		s := e * candles.DataRows[i].Get(si)
 		candles.DataRows[i].Set(ret, m.Sqrt(s))
 	}
@@ -120,7 +120,7 @@ func EMATrend(mat *Matrix, emas ...int) int {
 		eis = append(eis, EMA(mat, e, 4))
 	}
 	recent := emas[len(emas)-1]
-	for i := recent + 1; i < mat.Rows; i++ {
+	for i := recent + 1; i < mat.CandleRows(); i++ {
 		sum := 0.0
 		div := 0.0
 		// close above / below
@@ -159,7 +159,7 @@ func Pivots(candles *Matrix) int {
 	s1i := candles.AddNamedColumn("S1")
 	s2i := candles.AddNamedColumn("S2")
 
-	for i := 0; i < candles.Rows-1; i++ {
+	for i := 0; i < candles.CandleRows()-1; i++ {
 		cur := &candles.DataRows[i]
 		n := &candles.DataRows[i+1]
 		pp := (cur.Get(1) + cur.Get(2) + cur.Get(4)) / 3.0
@@ -176,7 +176,7 @@ func HHLL(candles *Matrix) int {
 	// 0 = Higher Highs 1 = Lower Lows
 	ret := candles.AddNamedColumn("HH")
 	lr := candles.AddNamedColumn("LL")
-	for i := 1; i < candles.Rows; i++ {
+	for i := 1; i < candles.CandleRows(); i++ {
 		c := &candles.DataRows[i]
 		p := candles.DataRows[i-1]
 		if c.Get(1) > p.Get(1) {
@@ -199,12 +199,12 @@ func UpDown(m *Matrix, days int) int {
 	downCol := m.AddColumn()
 	sumCol := m.AddColumn()
 	delta := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		p := m.DataRows[i-1].Close()
 		c := m.DataRows[i].Close()
 		m.DataRows[i].Set(delta, c-p)
 	}
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		up := 0
 		down := 0
 		sum := 0.0
@@ -291,11 +291,11 @@ func applySavGolFilter(m *Matrix, coeffs []float64) int {
 	ret := m.AddColumn()
 	//smoothed := make([]float64, len(data))
 
-	for i := range m.DataRows {
+	for i := range m.DataRows[:m.CandleRows()] {
 		var sum float64
 		for j := -halfWindow; j <= halfWindow; j++ {
 			idx := i + j
-			if idx >= 0 && idx < m.Rows {
+			if idx >= 0 && idx < m.CandleRows() {
 				sum += m.DataRows[idx].Get(4) * coeffs[j+halfWindow]
 			}
 		}
@@ -352,15 +352,15 @@ func StableATR(m *Matrix, period int) float64 {
 	// Step 1: Compute True Range (TR)
 	tr := TrueRange(m)
 	// Step 2: Take last N TR values
-	mn := m.DataRows[m.Rows-period].Get(tr)
-	for i := m.Rows - period + 1; i < m.Rows; i++ {
+	mn := m.DataRows[m.CandleRows()-period].Get(tr)
+	for i := m.CandleRows() - period + 1; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(tr) < mn {
 			mn = m.DataRows[i].Get(tr)
 		}
 	}
 	// Step 3: Outlier check
 	cnt := 0
-	for i := m.Rows - period; i < m.Rows; i++ {
+	for i := m.CandleRows() - period; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(tr) <= 2.0*mn {
 			cnt++
 		}
@@ -374,7 +374,7 @@ func StableATR(m *Matrix, period int) float64 {
 	md := SMA(m, period, tr)
 	median := m.Last().Get(md)
 	filtered := make([]float64, 0)
-	for i := m.Rows - period; i < m.Rows; i++ {
+	for i := m.CandleRows() - period; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(tr) >= 0.5*median && m.DataRows[i].Get(tr) <= 1.5*median {
 			filtered = append(filtered, m.DataRows[i].Get(tr))
 		}
@@ -404,7 +404,7 @@ type TrendAggregate struct {
 func myTrend(m *Matrix, field int) int {
 	// 0 = Trend
 	ret := m.AddNamedColumn("Trend")
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		val := p.Get(ret)
@@ -431,7 +431,7 @@ func AggregateTrend(m *Matrix) []TrendAggregate {
 	trend := myTrend(m, 4)
 	start := 0
 	ret := make([]TrendAggregate, 1)
-	for i := 2; i < m.Rows; i++ {
+	for i := 2; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(trend)
 		p := m.DataRows[i-1].Get(trend)
 
@@ -454,14 +454,14 @@ func AggregateTrend(m *Matrix) []TrendAggregate {
 			start = i
 		}
 	}
-	if start != m.Rows-1 {
+	if start != m.CandleRows()-1 {
 		dir := 1
 		if m.Last().Get(trend) < 0.0 {
 			dir = -1
 		}
 		ret = append(ret, TrendAggregate{
 			Start:     start,
-			End:       m.Rows - 1,
+			End:       m.CandleRows() - 1,
 			Direction: dir,
 			Count:     int(ma.Abs(m.Last().Get(trend))),
 		})
@@ -473,7 +473,7 @@ func AggregateTrend(m *Matrix) []TrendAggregate {
 func PercentageChange(m *Matrix, field int) int {
 	// 0 = change percentage
 	ret := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(field)
 		p := m.DataRows[i-1].Get(field)
 		m.DataRows[i].Set(ret, (c-p)/p*100.0)
@@ -484,7 +484,7 @@ func PercentageChange(m *Matrix, field int) int {
 // calculateReturns computes percentage change between consecutive closes.
 func calculateReturns(m *Matrix) int {
 	ret := m.AddColumn()
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		m.DataRows[i-1].Set(ret, (m.DataRows[i].Close()-m.DataRows[i-1].Close())/m.DataRows[i-1].Close())
 	}
 	return ret
@@ -535,7 +535,7 @@ func internalShannonEntropy(values []float64, bins int) float64 {
 func ShannonEntropy(m *Matrix, windowSize int, bins int) int {
 	returns := calculateReturns(m)
 	entropies := m.AddNamedColumn("Shannon Entropy")
-	for i := 0; i < m.Rows-windowSize; i++ {
+	for i := 0; i < m.CandleRows()-windowSize; i++ {
 		window := m.GetPartialColumn(returns, i, i+windowSize)
 		m.DataRows[i+windowSize].Set(entropies, internalShannonEntropy(window, bins))
 	}
@@ -562,7 +562,7 @@ const (
 func RBD(m *Matrix) int {
 	// 0 = RBD Type
 	tmp := m.AddNamedColumn("RBDType")
-	for i := 1; i < m.Rows; i++ {
+	for i := 1; i < m.CandleRows(); i++ {
 		c := m.DataRows[i]
 		p := m.DataRows[i-1]
 		gc := c.Close() > c.Open()
@@ -587,7 +587,7 @@ func RBD(m *Matrix) int {
 		m.DataRows[i].Set(tmp, tp)
 	}
 	ret := m.AddNamedColumn("RBD")
-	for i := 2; i < m.Rows; i++ {
+	for i := 2; i < m.CandleRows(); i++ {
 		c := m.DataRows[i].Get(tmp)
 		p1 := m.DataRows[i-1].Get(tmp)
 		p2 := m.DataRows[i-2].Get(tmp)
@@ -612,7 +612,7 @@ func NRX(m *Matrix, period int) int {
 	// 0 = 1 if NR
 	ret := m.AddColumn()
 	rng := Range(m)
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		cur := m.DataRows[i].Get(rng)
 		cnt := 0
 		for j := range period - 1 {
@@ -637,7 +637,7 @@ func HLBand(m *Matrix, period int) int {
 	hlAvg := m.Apply(func(mr MatrixRow) float64 {
 		return mr.Get(high) - mr.Get(low)
 	})
-	for i := range m.Rows {
+	for i := range m.CandleRows() {
 		c := m.DataRows[i]
 		m.DataRows[i].Set(upper, c.Get(high))
 		m.DataRows[i].Set(lower, c.Get(high)-c.Get(hlAvg))
@@ -669,7 +669,7 @@ func Wave(m *Matrix, period, fast, slow int) int {
 	deltaWaves := m.Apply(func(mr MatrixRow) float64 {
 		return mr.Get(fe) - mr.Get(se)
 	})
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		m.DataRows[i].Set(fc, m.DataRows[i].Get(fe))
 		m.DataRows[i].Set(sc, m.DataRows[i].Get(se))
 		m.DataRows[i].Set(delta, m.DataRows[i].Get(deltaWaves))
@@ -682,7 +682,7 @@ func Correlation(m *Matrix, period, first, second int) int {
 	ret := m.AddColumn()
 	n := float64(period)
 	var sumX, sumY, sumXY, sumX2, sumY2 float64
-	for i := period; i < m.Rows; i++ {
+	for i := period; i < m.CandleRows(); i++ {
 		sumX, sumY, sumXY, sumX2, sumY2 = 0.0, 0.0, 0.0, 0.0, 0.0
 		for j := range period {
 			idx := i - period + j
@@ -708,7 +708,7 @@ func StretchMove(m *Matrix, atrPeriod, lookback int) int {
 	lo := m.AddColumn()
 	hi := m.AddColumn()
 	atr := ATR(m, atrPeriod)
-	for i := lookback; i < m.Rows; i++ {
+	for i := lookback; i < m.CandleRows(); i++ {
 		// low
 		idx := m.FindLowestIndex(i-lookback, lookback)
 		cur := m.DataRows[i].Close()
@@ -729,7 +729,7 @@ func ATRRegime(m *Matrix, atrPeriod, lookback int) int {
 	// 	vol_threshold = df['ATR'].rolling(100).quantile(0.7)
 	q := RollingQuantile(m, atr, lookback, 0.7)
 	//df['regime'] = (df['ATR'] < vol_threshold).astype(int)
-	for i := range m.Rows {
+	for i := range m.CandleRows() {
 		c := m.DataRows[i]
 		if c.Get(atr) >= c.Get(q) {
 			m.DataRows[i].Set(ret, 1.0)
@@ -0,0 +1,291 @@
+package math
+
+import (
+	"math"
+	"sort"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Automatic trendlines
+//
+// -----------------------------------------------------------------------
+const (
+	// support line through swing lows
+	TRENDLINE_SUPPORT = 1
+	// resistance line through swing highs
+	TRENDLINE_RESISTANCE = -1
+)
+
+// a swing point of FindSwingPoints is known two bars later
+const trendLineLag = 2
+
+type TrendLineConfig struct {
+	// Tolerance in ATR units for a swing point to count as a touch and for a
+	// close to count as a break
+	Tolerance float64
+	ATRPeriod int
+	// MinTouches is the minimum number of swing points on the line
+	MinTouches int
+	// MaxAge is the number of bars after the last touch the line is valid
+	MaxAge int
+	// RetestWindow is the number of bars after a break a retest is searched
+	RetestWindow int
+	// MaxLines is the number of lines per type that are kept
+	MaxLines int
+}
+
+func DefaultTrendLineConfig() TrendLineConfig {
+	return TrendLineConfig{
+		Tolerance:    0.25,
+		ATRPeriod:    14,
+		MinTouches:   3,
+		MaxAge:       50,
+		RetestWindow: 10,
+		MaxLines:     3,
+	}
+}
+
+// AutoTrendLine is a trendline through swing points. The line is valid from
+// ConfirmedIndex to ValidTo which is either the break or the last touch plus MaxAge.
+type AutoTrendLine struct {
+	TrendLine
+	// Type is TRENDLINE_SUPPORT or TRENDLINE_RESISTANCE
+	Type int
+	// Touches are the row indices of the swing points on the line up to the break
+	Touches []int
+	// ConfirmedIndex is the row where the swing point of the MinTouches-th
+	// touch is known. The line does not exist before.
+	ConfirmedIndex int
+	ValidTo        int
+	// BreakIndex is the first close beyond the line after the confirmation or -1.
+	// A support line breaks down and a resistance line breaks out.
+	BreakIndex int
+	// RetestIndex is the first candle after the break that comes back to the
+	// line and closes on the break side or -1
+	RetestIndex int
+	// Quality is the number of touches plus a bonus for the length minus the
+	// mean distance of the touches in units of the tolerance
+	Quality float64
+}
+
+// ValidAt returns true if the line is valid at the row index
+func (tl AutoTrendLine) ValidAt(index int) bool {
+	return index >= tl.ConfirmedIndex && index <= tl.ValidTo
+}
+
+func (tl AutoTrendLine) lastTouch() int {
+	return tl.Touches[len(tl.Touches)-1]
+}
+
+// PriceChannel is a support and a resistance line with about the same slope
+type PriceChannel struct {
+	Upper   AutoTrendLine
+	Lower   AutoTrendLine
+	Quality float64
+}
+
+// Width returns the distance between both lines at the row index
+func (pc PriceChannel) Width(index int) float64 {
+	return pc.Upper.ValueAt(index) - pc.Lower.ValueAt(index)
+}
+
+func buildTrendLine(m *Matrix, pts SwingPoints, first, second, tp, atr int, cfg TrendLineConfig) (AutoTrendLine, bool) {
+	ret := AutoTrendLine{
+		TrendLine:   NewTrendLine(pts, first, second),
+		Type:        tp,
+		BreakIndex:  -1,
+		RetestIndex: -1,
+	}
+	devs := make([]float64, 0)
+	for k := first; k < len(pts); k++ {
+		sp := pts[k]
+		tol := m.DataRows[sp.Index].Get(atr) * cfg.Tolerance
+		d := math.Abs(sp.Value - ret.ValueAt(sp.Index))
+		if d <= tol {
+			ret.Touches = append(ret.Touches, sp.Index)
+			if tol > 0.0 {
+				devs = append(devs, d/tol)
+			} else {
+				devs = append(devs, 0.0)
+			}
+		}
+	}
+	if len(ret.Touches) < cfg.MinTouches || cfg.MinTouches < 1 {
+		return ret, false
+	}
+	rows := m.CandleRows()
+	beyond := func(i int) bool {
+		c := &m.DataRows[i]
+		return float64(tp)*(ret.ValueAt(i)-c.Close()) > c.Get(atr)*cfg.Tolerance
+	}
+	ret.ConfirmedIndex = min(ret.Touches[cfg.MinTouches-1]+trendLineLag, rows-1)
+	// no close beyond the line until the line is confirmed
+	for i := ret.StartIndex; i < ret.ConfirmedIndex; i++ {
+		if beyond(i) {
+			return ret, false
+		}
+	}
+	// every touch that is known before the break extends the line
+	next := cfg.MinTouches
+	ret.ValidTo = min(ret.Touches[next-1]+cfg.MaxAge, rows-1)
+	for i := ret.ConfirmedIndex; i <= ret.ValidTo; i++ {
+		for next < len(ret.Touches) && ret.Touches[next]+trendLineLag <= i {
+			ret.ValidTo = min(ret.Touches[next]+cfg.MaxAge, rows-1)
+			next++
+		}
+		if beyond(i) {
+			ret.BreakIndex = i
+			ret.ValidTo = i
+			break
+		}
+	}
+	ret.Touches = ret.Touches[:next]
+	last := ret.lastTouch()
+	ret.EndIndex = last
+	ret.End = m.DataRows[last].Key
+	if ret.BreakIndex != -1 {
+		for i := ret.BreakIndex + 1; i <= ret.BreakIndex+cfg.RetestWindow && i < rows; i++ {
+			c := &m.DataRows[i]
+			tol := c.Get(atr) * cfg.Tolerance
+			v := ret.ValueAt(i)
+			if tp == TRENDLINE_RESISTANCE && c.Low() <= v+tol && c.Close() > v {
+				ret.RetestIndex = i
+				break
+			}
+			if tp == TRENDLINE_SUPPORT && c.High() >= v-tol && c.Close() < v {
+				ret.RetestIndex = i
+				break
+			}
+		}
+	}
+	dev := 0.0
+	for _, d := range devs[:next] {
+		dev += d
+	}
+	span := float64(last - ret.StartIndex)
+	ret.Quality = float64(len(ret.Touches)) + math.Min(span/100.0, 1.0) - dev/float64(len(ret.Touches))
+	return ret, true
+}
+
+func containsAll(all, sub []int) bool {
+	j := 0
+	for _, v := range all {
+		if j < len(sub) && v == sub[j] {
+			j++
+		}
+	}
+	return j == len(sub)
+}
+
+func findTrendLines(m *Matrix, pts SwingPoints, tp, atr int, cfg TrendLineConfig) []AutoTrendLine {
+	candidates := make([]AutoTrendLine, 0)
+	for i := 0; i < len(pts); i++ {
+		for j := i + 1; j < len(pts); j++ {
+			if tl, ok := buildTrendLine(m, pts, i, j, tp, atr, cfg); ok {
+				candidates = append(candidates, tl)
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Quality > candidates[j].Quality
+	})
+	// skip lines that only use touches of a better line
+	ret := make([]AutoTrendLine, 0)
+	for _, c := range candidates {
+		dup := false
+		for _, r := range ret {
+			if containsAll(r.Touches, c.Touches) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			ret = append(ret, c)
+			if len(ret) == cfg.MaxLines {
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// FindTrendLines returns the support and resistance lines ranked by quality
+func FindTrendLines(m *Matrix, cfg TrendLineConfig) ([]AutoTrendLine, []AutoTrendLine) {
+	sps := m.FindSwingPoints()
+	atr := ATR(m, cfg.ATRPeriod)
+	support := findTrendLines(m, sps.FilterByType(Low), TRENDLINE_SUPPORT, atr, cfg)
+	resistance := findTrendLines(m, sps.FilterByType(High), TRENDLINE_RESISTANCE, atr, cfg)
+	m.RemoveColumn()
+	return support, resistance
+}
+
+// FindPriceChannels pairs support and resistance lines that overlap in time and
+// whose slopes differ by less than maxDiff percent. The channels are ranked by
+// the quality of both lines.
+func FindPriceChannels(support, resistance []AutoTrendLine, maxDiff float64) []PriceChannel {
+	ret := make([]PriceChannel, 0)
+	for _, s := range support {
+		for _, r := range resistance {
+			if s.ConfirmedIndex > r.ValidTo || r.ConfirmedIndex > s.ValidTo {
+				continue
+			}
+			idx := max(s.ConfirmedIndex, r.ConfirmedIndex)
+			if r.ValueAt(idx) <= s.ValueAt(idx) {
+				continue
+			}
+			ref := math.Max(math.Abs(s.Slope), math.Abs(r.Slope))
+			if ref > 0.0 && math.Abs(s.Slope-r.Slope)/ref*100.0 > maxDiff {
+				continue
+			}
+			ret = append(ret, PriceChannel{
+				Upper:   r,
+				Lower:   s,
+				Quality: s.Quality + r.Quality,
+			})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Quality > ret[j].Quality
+	})
+	return ret
+}
+
+func bestTrendLine(lines []AutoTrendLine, index int) int {
+	for i, l := range lines {
+		if l.ValidAt(index) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TrendLines adds the best valid support and resistance line at every row and
+// the events. TLEvent is 1 for a breakout, -1 for a breakdown, 2 for the retest
+// after a breakout and -2 for the retest after a breakdown.
+func TrendLines(m *Matrix, cfg TrendLineConfig) int {
+	support, resistance := FindTrendLines(m, cfg)
+	ret := m.AddNamedColumn("TLSupport")
+	res := m.AddNamedColumn("TLResistance")
+	ev := m.AddNamedColumn("TLEvent")
+	// 0 = Support 1 = Resistance 2 = Event
+	for i := 0; i < m.CandleRows(); i++ {
+		c := &m.DataRows[i]
+		if idx := bestTrendLine(support, i); idx != -1 {
+			c.Set(ret, support[idx].ValueAt(i))
+		}
+		if idx := bestTrendLine(resistance, i); idx != -1 {
+			c.Set(res, resistance[idx].ValueAt(i))
+		}
+	}
+	for _, l := range append(support, resistance...) {
+		dir := -float64(l.Type)
+		if l.BreakIndex != -1 {
+			m.DataRows[l.BreakIndex].Set(ev, dir)
+		}
+		if l.RetestIndex != -1 {
+			m.DataRows[l.RetestIndex].Set(ev, dir*2.0)
+		}
+	}
+	return ret
+}
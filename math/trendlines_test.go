@@ -0,0 +1,47 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindTrendLines(t *testing.T) {
+	// ascending triangle with a breakout above 110 and a retest
+	mat := candleMatrix(closeBars(pathCloses([]float64{105, 110, 100, 110, 102, 110, 104, 110, 103.5, 116, 111, 118}, 4), 0.5))
+	cols := mat.Cols
+	support, resistance := FindTrendLines(mat, DefaultTrendLineConfig())
+	assert.Equal(t, cols, mat.Cols)
+	assert.NotEqual(t, 0, len(support))
+	assert.NotEqual(t, 0, len(resistance))
+	r := resistance[0]
+	assert.Equal(t, 4, len(r.Touches))
+	assert.Equal(t, 0.0, r.Slope)
+	assert.Equal(t, 110.5, r.ValueAt(50))
+	assert.NotEqual(t, -1, r.BreakIndex)
+	assert.NotEqual(t, -1, r.RetestIndex)
+	s := support[0]
+	assert.Equal(t, 0.25, s.Slope)
+	assert.Equal(t, 3, len(s.Touches))
+	// the last low undercuts the rising lows
+	assert.NotEqual(t, -1, s.BreakIndex)
+	tl := TrendLines(mat, DefaultTrendLineConfig())
+	assert.Equal(t, 1.0, mat.DataRows[r.BreakIndex].Get(tl+2))
+	assert.Equal(t, 2.0, mat.DataRows[r.RetestIndex].Get(tl+2))
+	// the line exists once the third touch at row 20 is confirmed
+	assert.Equal(t, 22, r.ConfirmedIndex)
+	assert.False(t, r.ValidAt(21))
+	assert.Equal(t, 0.0, mat.DataRows[21].Get(tl+1))
+	assert.Equal(t, 110.5, mat.DataRows[22].Get(tl+1))
+}
+
+func TestFindPriceChannels(t *testing.T) {
+	support := AutoTrendLine{TrendLine: TrendLine{StartIndex: 0, Value: 100, Slope: 0.5}, Type: TRENDLINE_SUPPORT, ConfirmedIndex: 30, ValidTo: 40}
+	resistance := AutoTrendLine{TrendLine: TrendLine{StartIndex: 0, Value: 110, Slope: 0.5}, Type: TRENDLINE_RESISTANCE, ConfirmedIndex: 10, ValidTo: 25}
+	// both lines start at 0 but the support line is only known after the resistance line broke
+	assert.Equal(t, 0, len(FindPriceChannels([]AutoTrendLine{support}, []AutoTrendLine{resistance}, 10.0)))
+	resistance.ValidTo = 35
+	channels := FindPriceChannels([]AutoTrendLine{support}, []AutoTrendLine{resistance}, 10.0)
+	assert.Equal(t, 1, len(channels))
+	assert.Equal(t, 10.0, channels[0].Width(30))
+}
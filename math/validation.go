@@ -0,0 +1,655 @@
+package math
+
+import (
+	"fmt"
+	m "math"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------
+//
+//	OHLCV validation and cleaning
+//
+// -----------------------------------------------------------------------
+type CandleIssue int
+
+const (
+	HIGH_BELOW_LOW CandleIssue = iota + 1
+	OPEN_OUTSIDE_RANGE
+	CLOSE_OUTSIDE_RANGE
+	NON_POSITIVE_PRICE
+	DUPLICATE_KEY
+	NON_MONOTONIC_KEY
+	PRICE_SPIKE
+	PRICE_OUTLIER
+)
+
+func (ci CandleIssue) String() string {
+	switch ci {
+	case HIGH_BELOW_LOW:
+		return "High < Low"
+	case OPEN_OUTSIDE_RANGE:
+		return "Open outside range"
+	case CLOSE_OUTSIDE_RANGE:
+		return "Close outside range"
+	case NON_POSITIVE_PRICE:
+		return "Price <= 0"
+	case DUPLICATE_KEY:
+		return "Duplicate key"
+	case NON_MONOTONIC_KEY:
+		return "Non monotonic key"
+	case PRICE_SPIKE:
+		return "Spike"
+	case PRICE_OUTLIER:
+		return "Outlier"
+	}
+	return "-"
+}
+
+type Repair int
+
+const (
+	NO_REPAIR Repair = iota
+	DROP_ROW
+	CLIP_VALUE
+	FORWARD_FILL
+	HAMPEL_FILTER
+)
+
+func (r Repair) String() string {
+	switch r {
+	case DROP_ROW:
+		return "drop"
+	case CLIP_VALUE:
+		return "clip"
+	case FORWARD_FILL:
+		return "forward fill"
+	case HAMPEL_FILTER:
+		return "hampel"
+	}
+	return "none"
+}
+
+type CleaningConfig struct {
+	// a move of more than SpikeThreshold (0.5 = 50%) from the previous close and back is a spike
+	SpikeThreshold float64
+	// window and number of scaled MADs for the Hampel filter. A window of 0 disables outlier detection
+	HampelWindow int
+	HampelSigma  float64
+	// the column used as close. The default is the raw CLOSE because the
+	// adjusted close does not have to be inside the raw high and low. ADJ_CLOSE
+	// is still checked for non positive values, spikes and outliers.
+	CloseField int
+	Repairs    map[CandleIssue]Repair
+}
+
+func DefaultCleaningConfig() CleaningConfig {
+	return CleaningConfig{
+		SpikeThreshold: 0.5,
+		HampelWindow:   0,
+		HampelSigma:    3.0,
+		CloseField:     CLOSE,
+		Repairs: map[CandleIssue]Repair{
+			HIGH_BELOW_LOW:      CLIP_VALUE,
+			OPEN_OUTSIDE_RANGE:  CLIP_VALUE,
+			CLOSE_OUTSIDE_RANGE: CLIP_VALUE,
+			NON_POSITIVE_PRICE:  FORWARD_FILL,
+			DUPLICATE_KEY:       DROP_ROW,
+			NON_MONOTONIC_KEY:   CLIP_VALUE,
+			PRICE_SPIKE:         FORWARD_FILL,
+			PRICE_OUTLIER:       HAMPEL_FILTER,
+		},
+	}
+}
+
+type CandleIssueEntry struct {
+	Index int
+	Key   string
+	Issue CandleIssue
+	Field int
+	Value float64
+}
+
+type ValidationReport struct {
+	Rows   int
+	Issues []CandleIssueEntry
+}
+
+func (vr *ValidationReport) add(index int, key string, issue CandleIssue, field int, value float64) {
+	vr.Issues = append(vr.Issues, CandleIssueEntry{
+		Index: index,
+		Key:   key,
+		Issue: issue,
+		Field: field,
+		Value: value,
+	})
+}
+
+func (vr *ValidationReport) IsValid() bool {
+	return len(vr.Issues) == 0
+}
+
+func (vr *ValidationReport) Count(issue CandleIssue) int {
+	cnt := 0
+	for _, i := range vr.Issues {
+		if i.Issue == issue {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// IssuesAt returns all issues found in the given row
+func (vr *ValidationReport) IssuesAt(index int) []CandleIssueEntry {
+	ret := make([]CandleIssueEntry, 0)
+	for _, i := range vr.Issues {
+		if i.Index == index {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+func (vr *ValidationReport) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("rows: %d issues: %d\n", vr.Rows, len(vr.Issues)))
+	for issue := HIGH_BELOW_LOW; issue <= PRICE_OUTLIER; issue++ {
+		if cnt := vr.Count(issue); cnt > 0 {
+			sb.WriteString(fmt.Sprintf("  %-20s %d\n", issue.String(), cnt))
+		}
+	}
+	return sb.String()
+}
+
+// priceFields returns the fields that must be positive. ADJ_CLOSE is checked
+// as well because Close() and most indicators read it. It is not compared to
+// the high and low since the adjustment moves it outside the raw range.
+func priceFields(cfg CleaningConfig) []int {
+	if cfg.CloseField == ADJ_CLOSE {
+		return []int{OPEN, HIGH, LOW, cfg.CloseField}
+	}
+	return []int{OPEN, HIGH, LOW, cfg.CloseField, ADJ_CLOSE}
+}
+
+// fillValue returns the value of the previous row used to fill the field.
+// ADJ_CLOSE is filled from the previous adjusted close, all other fields from
+// the previous close.
+func fillValue(prev *MatrixRow, field int, cfg CleaningConfig) float64 {
+	if field == ADJ_CLOSE {
+		return prev.Get(ADJ_CLOSE)
+	}
+	return prev.Get(cfg.CloseField)
+}
+
+func validateRow(vr *ValidationReport, index int, r *MatrixRow, cfg CleaningConfig) {
+	for _, f := range priceFields(cfg) {
+		if r.Get(f) <= 0.0 {
+			vr.add(index, r.Key, NON_POSITIVE_PRICE, f, r.Get(f))
+		}
+	}
+	if r.High() < r.Low() {
+		vr.add(index, r.Key, HIGH_BELOW_LOW, HIGH, r.High())
+		return
+	}
+	if r.Open() > r.High() || r.Open() < r.Low() {
+		vr.add(index, r.Key, OPEN_OUTSIDE_RANGE, OPEN, r.Open())
+	}
+	c := r.Get(cfg.CloseField)
+	if c > r.High() || c < r.Low() {
+		vr.add(index, r.Key, CLOSE_OUTSIDE_RANGE, cfg.CloseField, c)
+	}
+}
+
+// isSpike checks for a move of the field of more than the threshold that is
+// reverted by the next bar
+func isSpike(candles *Matrix, index, field int, cfg CleaningConfig) bool {
+	if index == 0 || cfg.SpikeThreshold <= 0.0 {
+		return false
+	}
+	p := candles.DataRows[index-1].Get(field)
+	c := candles.DataRows[index].Get(field)
+	if p <= 0.0 || c <= 0.0 {
+		return false
+	}
+	move := c/p - 1.0
+	if m.Abs(move) <= cfg.SpikeThreshold {
+		return false
+	}
+	if index == candles.Rows-1 {
+		return true
+	}
+	n := candles.DataRows[index+1].Get(field)
+	back := n/c - 1.0
+	return m.Abs(back) > cfg.SpikeThreshold/(1.0+cfg.SpikeThreshold) && m.Signbit(back) != m.Signbit(move)
+}
+
+// spikeField returns the close field with a spike at the index or -1. The
+// adjusted close is only checked on its own if the close has no spike.
+func spikeField(candles *Matrix, index int, cfg CleaningConfig) int {
+	if isSpike(candles, index, cfg.CloseField, cfg) {
+		return cfg.CloseField
+	}
+	if cfg.CloseField != ADJ_CLOSE && isSpike(candles, index, ADJ_CLOSE, cfg) {
+		return ADJ_CLOSE
+	}
+	return -1
+}
+
+func rollingMedianMAD(values []float64, index, window int) (float64, float64) {
+	half := window / 2
+	start := max(index-half, 0)
+	end := min(index+half+1, len(values))
+	win := make([]float64, 0, end-start)
+	win = append(win, values[start:end]...)
+	med := medianOf(win)
+	dev := make([]float64, len(win))
+	for i, v := range win {
+		dev[i] = m.Abs(v - med)
+	}
+	// 1.4826 scales the MAD to the standard deviation of a normal distribution
+	return med, 1.4826 * medianOf(dev)
+}
+
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	tmp := append([]float64(nil), values...)
+	sort.Float64s(tmp)
+	n := len(tmp)
+	if n%2 == 1 {
+		return tmp[n/2]
+	}
+	return (tmp[n/2-1] + tmp[n/2]) / 2.0
+}
+
+func isHampelOutlier(values []float64, index int, cfg CleaningConfig) (bool, float64) {
+	med, sigma := rollingMedianMAD(values, index, cfg.HampelWindow)
+	if sigma == 0.0 {
+		return false, med
+	}
+	return m.Abs(values[index]-med) > cfg.HampelSigma*sigma, med
+}
+
+// ValidateCandles reports all issues per row without changing the matrix
+func ValidateCandles(candles *Matrix, cfg CleaningConfig) *ValidationReport {
+	vr := &ValidationReport{
+		Rows: candles.Rows,
+	}
+	seen := make(map[string]int)
+	for i := 0; i < candles.Rows; i++ {
+		r := &candles.DataRows[i]
+		if _, ok := seen[r.Key]; ok {
+			vr.add(i, r.Key, DUPLICATE_KEY, -1, 0.0)
+		}
+		seen[r.Key] = i
+		if i > 0 && r.Key < candles.DataRows[i-1].Key {
+			vr.add(i, r.Key, NON_MONOTONIC_KEY, -1, 0.0)
+		}
+		validateRow(vr, i, r, cfg)
+		if f := spikeField(candles, i, cfg); f != -1 {
+			vr.add(i, r.Key, PRICE_SPIKE, f, r.Get(f))
+		}
+	}
+	if cfg.HampelWindow > 0 {
+		for _, f := range priceFields(cfg) {
+			values := candles.GetColumn(f)
+			for i := range values {
+				if out, _ := isHampelOutlier(values, i, cfg); out {
+					vr.add(i, candles.DataRows[i].Key, PRICE_OUTLIER, f, values[i])
+				}
+			}
+		}
+	}
+	return vr
+}
+
+type CleaningChange struct {
+	Key    string
+	Issue  CandleIssue
+	Repair Repair
+	Field  int
+	Old    float64
+	New    float64
+}
+
+type CleaningReport struct {
+	Found     *ValidationReport
+	Remaining *ValidationReport
+	Changes   []CleaningChange
+	Dropped   []string
+}
+
+func (cr *CleaningReport) change(key string, issue CandleIssue, repair Repair, field int, old, new float64) {
+	cr.Changes = append(cr.Changes, CleaningChange{
+		Key:    key,
+		Issue:  issue,
+		Repair: repair,
+		Field:  field,
+		Old:    old,
+		New:    new,
+	})
+}
+
+func (cr *CleaningReport) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("found ")
+	sb.WriteString(cr.Found.String())
+	sb.WriteString(fmt.Sprintf("changed values: %d dropped rows: %d\n", len(cr.Changes), len(cr.Dropped)))
+	for _, c := range cr.Changes {
+		sb.WriteString(fmt.Sprintf("  %s %-20s %-12s field %d: %.2f -> %.2f\n", c.Key, c.Issue.String(), c.Repair.String(), c.Field, c.Old, c.New))
+	}
+	for _, d := range cr.Dropped {
+		sb.WriteString(fmt.Sprintf("  %s dropped\n", d))
+	}
+	sb.WriteString("remaining ")
+	sb.WriteString(cr.Remaining.String())
+	return sb.String()
+}
+
+func copyRow(r MatrixRow) MatrixRow {
+	r.Values = append([]float64(nil), r.Values...)
+	return r
+}
+
+// CleanCandles returns a repaired copy of the matrix. The repairs for every kind
+// of issue are taken from the config. Issues without a repair are only reported.
+func CleanCandles(candles *Matrix, cfg CleaningConfig) (*Matrix, *CleaningReport) {
+	cr := &CleaningReport{
+		Found: ValidateCandles(candles, cfg),
+	}
+	rows := make([]MatrixRow, 0, candles.Rows)
+	for _, r := range candles.DataRows {
+		rows = append(rows, copyRow(r))
+	}
+	// keys: sort and remove duplicates (the last row wins)
+	if cfg.Repairs[NON_MONOTONIC_KEY] == DROP_ROW {
+		tmp := make([]MatrixRow, 0, len(rows))
+		for _, r := range rows {
+			if len(tmp) > 0 && r.Key < tmp[len(tmp)-1].Key {
+				cr.Dropped = append(cr.Dropped, r.Key)
+				continue
+			}
+			tmp = append(tmp, r)
+		}
+		rows = tmp
+	} else if cfg.Repairs[NON_MONOTONIC_KEY] != NO_REPAIR {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].Key < rows[j].Key
+		})
+	}
+	if cfg.Repairs[DUPLICATE_KEY] != NO_REPAIR {
+		last := make(map[string]int)
+		for i, r := range rows {
+			last[r.Key] = i
+		}
+		tmp := make([]MatrixRow, 0, len(rows))
+		for i, r := range rows {
+			if last[r.Key] != i {
+				cr.Dropped = append(cr.Dropped, r.Key)
+				continue
+			}
+			tmp = append(tmp, r)
+		}
+		rows = tmp
+	}
+	ret := NewMatrixWithHeaders(candles.Cols, candles.Headers[1:])
+	ret.Info = candles.Info
+	drop := func(r *MatrixRow) {
+		cr.Dropped = append(cr.Dropped, r.Key)
+	}
+	for i := range rows {
+		r := &rows[i]
+		var prev *MatrixRow
+		if ret.Rows > 0 {
+			prev = &ret.DataRows[ret.Rows-1]
+		}
+		if !cleanRow(rows, i, prev, cfg, cr) {
+			drop(r)
+			continue
+		}
+		ret.DataRows = append(ret.DataRows, *r)
+		ret.Rows++
+	}
+	cleanSpikes(ret, cfg, cr)
+	if cfg.HampelWindow > 0 && cfg.Repairs[PRICE_OUTLIER] != NO_REPAIR {
+		cleanOutliers(ret, cfg, cr)
+	}
+	cr.Remaining = ValidateCandles(ret, cfg)
+	return ret, cr
+}
+
+func forwardFill(r *MatrixRow, prev *MatrixRow, issue CandleIssue, cfg CleaningConfig, cr *CleaningReport) bool {
+	if prev == nil {
+		return false
+	}
+	for _, f := range priceFields(cfg) {
+		if nv := fillValue(prev, f, cfg); r.Get(f) != nv {
+			cr.change(r.Key, issue, FORWARD_FILL, f, r.Get(f), nv)
+			r.Set(f, nv)
+		}
+	}
+	return true
+}
+
+// positiveMedian returns the median of the positive values of the field in a
+// window around the index or 0 if there is none
+func positiveMedian(rows []MatrixRow, index, field, window int) float64 {
+	half := window / 2
+	values := make([]float64, 0, window)
+	for i := max(index-half, 0); i <= min(index+half, len(rows)-1); i++ {
+		if v := rows[i].Get(field); v > 0.0 {
+			values = append(values, v)
+		}
+	}
+	return medianOf(values)
+}
+
+// cleanRow repairs the row at index in place and returns false if it should
+// be dropped. HAMPEL_FILTER clips the issues that only concern a single row.
+func cleanRow(rows []MatrixRow, index int, prev *MatrixRow, cfg CleaningConfig, cr *CleaningReport) bool {
+	r := &rows[index]
+	for _, f := range priceFields(cfg) {
+		if r.Get(f) > 0.0 {
+			continue
+		}
+		switch cfg.Repairs[NON_POSITIVE_PRICE] {
+		case DROP_ROW:
+			return false
+		case FORWARD_FILL:
+			if prev == nil {
+				return false
+			}
+			cr.change(r.Key, NON_POSITIVE_PRICE, FORWARD_FILL, f, r.Get(f), fillValue(prev, f, cfg))
+			r.Set(f, fillValue(prev, f, cfg))
+		case CLIP_VALUE:
+			// clip to the lowest valid price of the row
+			nv := 0.0
+			for _, o := range priceFields(cfg) {
+				if v := r.Get(o); v > 0.0 && (nv == 0.0 || v < nv) {
+					nv = v
+				}
+			}
+			if nv == 0.0 {
+				return false
+			}
+			cr.change(r.Key, NON_POSITIVE_PRICE, CLIP_VALUE, f, r.Get(f), nv)
+			r.Set(f, nv)
+		case HAMPEL_FILTER:
+			med := positiveMedian(rows, index, f, max(cfg.HampelWindow, 5))
+			if med == 0.0 {
+				return false
+			}
+			cr.change(r.Key, NON_POSITIVE_PRICE, HAMPEL_FILTER, f, r.Get(f), med)
+			r.Set(f, med)
+		}
+	}
+	if r.High() < r.Low() {
+		switch cfg.Repairs[HIGH_BELOW_LOW] {
+		case DROP_ROW:
+			return false
+		case FORWARD_FILL:
+			if !forwardFill(r, prev, HIGH_BELOW_LOW, cfg, cr) {
+				return false
+			}
+		case CLIP_VALUE, HAMPEL_FILTER:
+			h, l := r.High(), r.Low()
+			cr.change(r.Key, HIGH_BELOW_LOW, CLIP_VALUE, HIGH, h, l)
+			cr.change(r.Key, HIGH_BELOW_LOW, CLIP_VALUE, LOW, l, h)
+			r.Set(HIGH, l)
+			r.Set(LOW, h)
+		}
+	}
+	checks := []struct {
+		field int
+		issue CandleIssue
+	}{
+		{OPEN, OPEN_OUTSIDE_RANGE},
+		{cfg.CloseField, CLOSE_OUTSIDE_RANGE},
+	}
+	for _, c := range checks {
+		v := r.Get(c.field)
+		if v <= r.High() && v >= r.Low() {
+			continue
+		}
+		switch cfg.Repairs[c.issue] {
+		case DROP_ROW:
+			return false
+		case FORWARD_FILL:
+			if !forwardFill(r, prev, c.issue, cfg, cr) {
+				return false
+			}
+		case CLIP_VALUE, HAMPEL_FILTER:
+			nv := m.Max(m.Min(v, r.High()), r.Low())
+			cr.change(r.Key, c.issue, CLIP_VALUE, c.field, v, nv)
+			r.Set(c.field, nv)
+		}
+	}
+	return true
+}
+
+func cleanSpikes(candles *Matrix, cfg CleaningConfig, cr *CleaningReport) {
+	repair := cfg.Repairs[PRICE_SPIKE]
+	if repair == NO_REPAIR {
+		return
+	}
+	dropped := make(map[int]bool)
+	for i := 1; i < candles.Rows; i++ {
+		// a spike of the close repairs the whole bar, a spike of only the
+		// adjusted close just this field
+		sf := spikeField(candles, i, cfg)
+		if sf == -1 {
+			continue
+		}
+		fields := priceFields(cfg)
+		if sf == ADJ_CLOSE && sf != cfg.CloseField {
+			fields = []int{ADJ_CLOSE}
+		}
+		r := &candles.DataRows[i]
+		p := candles.DataRows[i-1]
+		switch repair {
+		case DROP_ROW:
+			dropped[i] = true
+		case FORWARD_FILL:
+			for _, f := range fields {
+				if nv := fillValue(&p, f, cfg); r.Get(f) != nv {
+					cr.change(r.Key, PRICE_SPIKE, FORWARD_FILL, f, r.Get(f), nv)
+					r.Set(f, nv)
+				}
+			}
+		case CLIP_VALUE:
+			// limit the move to the threshold
+			for _, f := range fields {
+				pc := fillValue(&p, f, cfg)
+				nv := m.Max(m.Min(r.Get(f), pc*(1.0+cfg.SpikeThreshold)), pc*(1.0-cfg.SpikeThreshold))
+				if nv != r.Get(f) {
+					cr.change(r.Key, PRICE_SPIKE, CLIP_VALUE, f, r.Get(f), nv)
+					r.Set(f, nv)
+				}
+			}
+		case HAMPEL_FILTER:
+			window := max(cfg.HampelWindow, 5)
+			for _, f := range fields {
+				values := candles.GetColumn(f)
+				med, _ := rollingMedianMAD(values, i, window)
+				cr.change(r.Key, PRICE_SPIKE, HAMPEL_FILTER, f, r.Get(f), med)
+				r.Set(f, med)
+			}
+		}
+	}
+	if len(dropped) > 0 {
+		rows := make([]MatrixRow, 0, candles.Rows)
+		for i, r := range candles.DataRows {
+			if dropped[i] {
+				cr.Dropped = append(cr.Dropped, r.Key)
+				continue
+			}
+			rows = append(rows, r)
+		}
+		candles.DataRows = rows
+		candles.Rows = len(rows)
+	}
+}
+
+func cleanOutliers(candles *Matrix, cfg CleaningConfig, cr *CleaningReport) {
+	repair := cfg.Repairs[PRICE_OUTLIER]
+	dropped := make(map[int]bool)
+	for _, f := range priceFields(cfg) {
+		// detection runs on the original values so that repairs do not cascade
+		values := candles.GetColumn(f)
+		for i := range values {
+			out, med := isHampelOutlier(values, i, cfg)
+			if !out {
+				continue
+			}
+			r := &candles.DataRows[i]
+			switch repair {
+			case DROP_ROW:
+				dropped[i] = true
+			case FORWARD_FILL:
+				if i > 0 {
+					cr.change(r.Key, PRICE_OUTLIER, FORWARD_FILL, f, r.Get(f), values[i-1])
+					r.Set(f, values[i-1])
+				}
+			case CLIP_VALUE:
+				_, sigma := rollingMedianMAD(values, i, cfg.HampelWindow)
+				nv := m.Max(m.Min(values[i], med+cfg.HampelSigma*sigma), med-cfg.HampelSigma*sigma)
+				cr.change(r.Key, PRICE_OUTLIER, CLIP_VALUE, f, r.Get(f), nv)
+				r.Set(f, nv)
+			case HAMPEL_FILTER:
+				cr.change(r.Key, PRICE_OUTLIER, HAMPEL_FILTER, f, r.Get(f), med)
+				r.Set(f, med)
+			}
+		}
+	}
+	// replacing single fields can break the candle again
+	for i := range candles.DataRows {
+		if dropped[i] {
+			continue
+		}
+		r := &candles.DataRows[i]
+		hi := m.Max(r.High(), m.Max(r.Open(), r.Get(cfg.CloseField)))
+		lo := m.Min(r.Low(), m.Min(r.Open(), r.Get(cfg.CloseField)))
+		if hi != r.High() {
+			cr.change(r.Key, PRICE_OUTLIER, CLIP_VALUE, HIGH, r.High(), hi)
+			r.Set(HIGH, hi)
+		}
+		if lo != r.Low() {
+			cr.change(r.Key, PRICE_OUTLIER, CLIP_VALUE, LOW, r.Low(), lo)
+			r.Set(LOW, lo)
+		}
+	}
+	if len(dropped) > 0 {
+		rows := make([]MatrixRow, 0, candles.Rows)
+		for i, r := range candles.DataRows {
+			if dropped[i] {
+				cr.Dropped = append(cr.Dropped, r.Key)
+				continue
+			}
+			rows = append(rows, r)
+		}
+		candles.DataRows = rows
+		candles.Rows = len(rows)
+	}
+}
@@ -0,0 +1,104 @@
+package math
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func buildDirtyCandles() *Matrix {
+	mat := NewCandleMatrix()
+	add := func(key string, o, h, l, c float64) {
+		mat.ForcedAddRow(key).Set(OPEN, o).Set(HIGH, h).Set(LOW, l).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, 100)
+	}
+	add("2024-01-02 00:00", 10, 11, 9, 10)
+	add("2024-01-03 00:00", 10, 9, 11, 10)
+	add("2024-01-04 00:00", 10, 11, 9, 12)
+	add("2024-01-05 00:00", 10, 31, 9, 30)
+	add("2024-01-06 00:00", 10, 11, 9, 10)
+	add("2024-01-06 00:00", 10, 11, 9, 10.5)
+	add("2024-01-08 00:00", 0, 11, 9, 10)
+	return mat
+}
+
+func TestValidateCandles(t *testing.T) {
+	vr := ValidateCandles(buildDirtyCandles(), DefaultCleaningConfig())
+	assert.Equal(t, 1, vr.Count(HIGH_BELOW_LOW))
+	assert.Equal(t, 1, vr.Count(CLOSE_OUTSIDE_RANGE))
+	assert.Equal(t, 1, vr.Count(PRICE_SPIKE))
+	assert.Equal(t, 1, vr.Count(DUPLICATE_KEY))
+	assert.Equal(t, 1, vr.Count(NON_POSITIVE_PRICE))
+	assert.Equal(t, PRICE_SPIKE, vr.IssuesAt(3)[0].Issue)
+}
+
+func TestCleanCandles(t *testing.T) {
+	ret, cr := CleanCandles(buildDirtyCandles(), DefaultCleaningConfig())
+	assert.True(t, cr.Remaining.IsValid())
+	assert.Equal(t, 6, ret.Rows)
+	assert.Equal(t, 11.0, ret.DataRows[1].High())
+	assert.Equal(t, 11.0, ret.DataRows[2].Get(CLOSE))
+	assert.Equal(t, 11.0, ret.DataRows[3].Get(CLOSE))
+	assert.Equal(t, 10.5, ret.DataRows[4].Close())
+	assert.Equal(t, []string{"2024-01-06 00:00"}, cr.Dropped)
+}
+
+func TestCleanCandlesAdjustedClose(t *testing.T) {
+	mat := NewCandleMatrix()
+	// a dividend adjusted close below the raw low is no issue
+	mat.ForcedAddRow("2024-01-02 00:00").Set(OPEN, 10).Set(HIGH, 11).Set(LOW, 9).Set(CLOSE, 10).Set(ADJ_CLOSE, 8).Set(VOLUME, 100)
+	assert.True(t, ValidateCandles(mat, DefaultCleaningConfig()).IsValid())
+}
+
+func TestCleanCandlesAdjustedCloseSpike(t *testing.T) {
+	mat := NewCandleMatrix()
+	adj := []float64{9.5, 9.6, 200, 9.7, 9.8}
+	for i, a := range adj {
+		key := fmt.Sprintf("2024-01-%02d 00:00", i+2)
+		mat.ForcedAddRow(key).Set(OPEN, 10).Set(HIGH, 11).Set(LOW, 9).Set(CLOSE, 10).Set(ADJ_CLOSE, a).Set(VOLUME, 100)
+	}
+	ret, cr := CleanCandles(mat, DefaultCleaningConfig())
+	assert.Equal(t, 1, cr.Found.Count(PRICE_SPIKE))
+	assert.Equal(t, ADJ_CLOSE, cr.Found.IssuesAt(2)[0].Field)
+	assert.True(t, cr.Remaining.IsValid())
+	// only the adjusted close is filled from the previous adjusted close
+	assert.Equal(t, []CleaningChange{{Key: "2024-01-04 00:00", Issue: PRICE_SPIKE, Repair: FORWARD_FILL, Field: ADJ_CLOSE, Old: 200, New: 9.6}}, cr.Changes)
+	assert.Equal(t, 9.6, ret.DataRows[2].Close())
+	assert.Equal(t, 10.0, ret.DataRows[2].Get(CLOSE))
+}
+
+func changeFor(cr *CleaningReport, issue CandleIssue) CleaningChange {
+	for _, c := range cr.Changes {
+		if c.Issue == issue {
+			return c
+		}
+	}
+	return CleaningChange{}
+}
+
+func TestCleanCandlesNonPositiveRepairs(t *testing.T) {
+	cfg := DefaultCleaningConfig()
+	cfg.Repairs[NON_POSITIVE_PRICE] = CLIP_VALUE
+	_, cr := CleanCandles(buildDirtyCandles(), cfg)
+	assert.Equal(t, CleaningChange{Key: "2024-01-08 00:00", Issue: NON_POSITIVE_PRICE, Repair: CLIP_VALUE, Field: OPEN, Old: 0, New: 9}, changeFor(cr, NON_POSITIVE_PRICE))
+
+	cfg.Repairs[NON_POSITIVE_PRICE] = HAMPEL_FILTER
+	_, cr = CleanCandles(buildDirtyCandles(), cfg)
+	assert.Equal(t, CleaningChange{Key: "2024-01-08 00:00", Issue: NON_POSITIVE_PRICE, Repair: HAMPEL_FILTER, Field: OPEN, Old: 0, New: 10}, changeFor(cr, NON_POSITIVE_PRICE))
+}
+
+func TestCleanOutliersRecordsRange(t *testing.T) {
+	mat := NewCandleMatrix()
+	opens := []float64{10, 9.5, 10.5, 9.2, 8.8, 11, 9.6, 10.4}
+	lows := []float64{9, 9.1, 8.9, 9, 1, 9.1, 9, 8.9}
+	for i := range opens {
+		key := fmt.Sprintf("2024-01-%02d 00:00", i+2)
+		mat.ForcedAddRow(key).Set(OPEN, opens[i]).Set(HIGH, 11.5).Set(LOW, lows[i]).Set(CLOSE, opens[i]).Set(ADJ_CLOSE, opens[i]).Set(VOLUME, 100)
+	}
+	cfg := DefaultCleaningConfig()
+	cfg.HampelWindow = 5
+	ret, cr := CleanCandles(mat, cfg)
+	assert.Equal(t, 8.8, ret.DataRows[4].Low())
+	last := cr.Changes[len(cr.Changes)-1]
+	assert.Equal(t, CleaningChange{Key: "2024-01-06 00:00", Issue: PRICE_OUTLIER, Repair: CLIP_VALUE, Field: LOW, Old: 9, New: 8.8}, last)
+}
@@ -0,0 +1,197 @@
+package math
+
+// -----------------------------------------------------------------------
+//
+//	Volatility contraction pattern
+//
+// -----------------------------------------------------------------------
+// Contraction is the pullback from a swing high to the following swing low
+type Contraction struct {
+	HighIndex int
+	LowIndex  int
+	High      float64
+	Low       float64
+	// Depth of the pullback in percent of the high
+	Depth float64
+	// Volume is the average volume from the high to the low
+	Volume float64
+}
+
+type VCP struct {
+	Contractions []Contraction
+	// Pivot is the buy point at the high of the last contraction
+	Pivot      float64
+	PivotIndex int
+	// Tightness is the depth of the last contraction in percent
+	Tightness float64
+	// BreakoutIndex is the first close above the pivot or -1
+	BreakoutIndex int
+}
+
+func (v VCP) Count() int {
+	return len(v.Contractions)
+}
+
+func (v VCP) Start() int {
+	return v.Contractions[0].HighIndex
+}
+
+func (v VCP) End() int {
+	return v.Contractions[len(v.Contractions)-1].LowIndex
+}
+
+type VCPConfig struct {
+	// Threshold in percent of the zigzag that finds the swings
+	Threshold       float64
+	MinContractions int
+	// MaxDepth is the maximum depth of the first contraction and MaxTightness
+	// the maximum depth of the last contraction in percent
+	MaxDepth     float64
+	MaxTightness float64
+	// every contraction needs less volume than the one before
+	RequireVolume bool
+	// MaxHighIncrease in percent a contraction high may exceed the first high
+	MaxHighIncrease float64
+}
+
+func DefaultVCPConfig() VCPConfig {
+	return VCPConfig{
+		Threshold:       3.0,
+		MinContractions: 2,
+		MaxDepth:        35.0,
+		MaxTightness:    10.0,
+		RequireVolume:   true,
+		MaxHighIncrease: 3.0,
+	}
+}
+
+// FindContractions returns the pullbacks between the swing highs and the following
+// swing lows. A pullback to the unconfirmed last low can still go deeper and is skipped.
+func FindContractions(prices *Matrix, threshold float64) []Contraction {
+	ret := make([]Contraction, 0)
+	sps := prices.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, threshold))
+	for i := 1; i < len(sps); i++ {
+		h := sps[i-1]
+		l := sps[i]
+		if h.BaseType != High || l.BaseType != Low || h.Value == 0.0 || l.Unconfirmed {
+			continue
+		}
+		vol := 0.0
+		for j := h.Index; j <= l.Index; j++ {
+			vol += prices.DataRows[j].Get(VOLUME)
+		}
+		ret = append(ret, Contraction{
+			HighIndex: h.Index,
+			LowIndex:  l.Index,
+			High:      h.Value,
+			Low:       l.Value,
+			Depth:     (h.Value - l.Value) / h.Value * 100.0,
+			Volume:    vol / float64(l.Index-h.Index+1),
+		})
+	}
+	return ret
+}
+
+func (cfg VCPConfig) follows(prev, cur, first Contraction) bool {
+	if cur.Depth >= prev.Depth {
+		return false
+	}
+	if cfg.RequireVolume && cur.Volume >= prev.Volume {
+		return false
+	}
+	return cur.High <= first.High*(1.0+cfg.MaxHighIncrease/100.0)
+}
+
+// FindVCPs returns the volatility contraction patterns. Every pattern is the
+// longest chain of successive contractions with shrinking depth and volume.
+func FindVCPs(prices *Matrix, cfg VCPConfig) []VCP {
+	ret := make([]VCP, 0)
+	cs := FindContractions(prices, cfg.Threshold)
+	used := -1
+	for i := 0; i < len(cs); i++ {
+		if i <= used {
+			continue
+		}
+		end := i
+		for end+1 < len(cs) && cfg.follows(cs[end], cs[end+1], cs[i]) {
+			end++
+		}
+		// drop the deep contractions at the start until the first one fits
+		start := i
+		for start < end && cs[start].Depth > cfg.MaxDepth {
+			start++
+		}
+		chain := cs[start : end+1]
+		last := chain[len(chain)-1]
+		if len(chain) < cfg.MinContractions || chain[0].Depth > cfg.MaxDepth || last.Depth > cfg.MaxTightness {
+			continue
+		}
+		used = end
+		v := VCP{
+			Contractions:  append([]Contraction(nil), chain...),
+			Pivot:         last.High,
+			PivotIndex:    last.HighIndex,
+			Tightness:     last.Depth,
+			BreakoutIndex: -1,
+		}
+		for j := last.LowIndex + 1; j < prices.CandleRows(); j++ {
+			if prices.DataRows[j].Close() > v.Pivot {
+				v.BreakoutIndex = j
+				break
+			}
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+type VCPScreenConfig struct {
+	VCP VCPConfig
+	// MinScore is the minimum MinerviniScore (0..1) on the last candle
+	MinScore float64
+	// Recent is the maximum number of bars since the end of the last contraction
+	Recent int
+	// MaxExtension is the maximum distance of the last close above the pivot in percent
+	MaxExtension float64
+}
+
+func DefaultVCPScreenConfig() VCPScreenConfig {
+	return VCPScreenConfig{
+		VCP:          DefaultVCPConfig(),
+		MinScore:     0.75,
+		Recent:       20,
+		MaxExtension: 5.0,
+	}
+}
+
+type VCPScreenResult struct {
+	// VCP is the most recent pattern or nil
+	VCP       *VCP
+	Minervini float64
+	Passed    bool
+}
+
+// ScreenVCP combines the trend template of MinerviniScore with the most recent
+// VCP. The screen passes if the trend template is met and the pattern ended
+// recently without running away from the pivot.
+func ScreenVCP(prices *Matrix, cfg VCPScreenConfig) VCPScreenResult {
+	ret := VCPScreenResult{}
+	rows := prices.CandleRows()
+	if rows == 0 {
+		return ret
+	}
+	ms := MinerviniScore(prices)
+	ret.Minervini = prices.DataRows[rows-1].Get(ms)
+	prices.RemoveColumn()
+	vcps := FindVCPs(prices, cfg.VCP)
+	if len(vcps) == 0 {
+		return ret
+	}
+	v := vcps[len(vcps)-1]
+	ret.VCP = &v
+	last := prices.DataRows[rows-1].Close()
+	ret.Passed = ret.Minervini >= cfg.MinScore &&
+		rows-1-v.End() <= cfg.Recent &&
+		last <= v.Pivot*(1.0+cfg.MaxExtension/100.0)
+	return ret
+}
@@ -0,0 +1,62 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindVCPs(t *testing.T) {
+	pivots := []float64{50, 60, 55, 65, 60, 70, 65, 75, 70, 80, 75, 85, 80, 90, 85, 95, 90, 100, 80, 98, 88, 97, 93, 97, 99}
+	mat := candleMatrix(closeBars(pathCloses(pivots, 12), 0.5))
+	// the volume dries up with every contraction
+	for i := range mat.DataRows {
+		v := 150.0
+		switch i / 12 {
+		case 17:
+			v = 300.0
+		case 19:
+			v = 200.0
+		case 21:
+			v = 100.0
+		}
+		mat.DataRows[i].Set(VOLUME, v)
+	}
+	cols := mat.Cols
+	vcps := FindVCPs(mat, DefaultVCPConfig())
+	assert.Equal(t, cols, mat.Cols)
+	assert.Equal(t, 1, len(vcps))
+	v := vcps[0]
+	assert.Equal(t, 3, v.Count())
+	assert.Equal(t, 97.5, v.Pivot)
+	assert.Equal(t, 21*12, v.PivotIndex)
+	assert.True(t, v.Tightness < 6.0)
+	assert.NotEqual(t, -1, v.BreakoutIndex)
+
+	cfg := DefaultVCPConfig()
+	cfg.MinContractions = 4
+	assert.Equal(t, 0, len(FindVCPs(mat, cfg)))
+
+	res := ScreenVCP(mat, DefaultVCPScreenConfig())
+	assert.Equal(t, cols, mat.Cols)
+	assert.NotZero(t, res.VCP)
+	assert.True(t, res.Minervini > 0.0)
+
+	// the last pullback is still running and is no contraction yet
+	forming := candleMatrix(closeBars(pathCloses(pivots[:23], 12), 0.5))
+	cs := FindContractions(forming, 3.0)
+	assert.Equal(t, 19*12, cs[len(cs)-1].HighIndex)
+}
+
+func TestFindContractionPhases(t *testing.T) {
+	mat := candleMatrix(closeBars(pathCloses([]float64{100, 110, 100, 110, 110.2, 110.1, 110.3}, 10), 0.5))
+	phases := FindContractionPhases(mat, 0.1)
+	found := false
+	for _, p := range phases {
+		if p.StartIndex <= 45 && p.EndIndex >= 45 {
+			found = true
+		}
+	}
+	// the flat part at the end is a contraction
+	assert.True(t, found)
+}
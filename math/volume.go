@@ -11,7 +11,7 @@ func VO(m *Matrix, fast, slow int) int {
 	ret := m.AddColumn()
 	emaSlow := EMA(m, slow, VOLUME)
 	emaFast := EMA(m, fast, VOLUME)
-	for i := 0; i < m.Rows; i++ {
+	for i := 0; i < m.CandleRows(); i++ {
 		if m.DataRows[i].Get(emaSlow) != 0.0 {
 			vo := (m.DataRows[i].Get(emaFast) - m.DataRows[i].Get(emaSlow)) / m.DataRows[i].Get(emaSlow) * 100.0
 			m.DataRows[i].Set(ret, vo)
@@ -53,7 +53,7 @@ func DeltaVolume(m *Matrix) int {
 	// 0 = Buy Volume Perentage 1 = Sell Volume Percentage
 	buy := m.AddNamedColumn("BuyVolume")
 	sell := m.AddNamedColumn("SellVolume")
-	for i := range m.Rows {
+	for i := range m.CandleRows() {
 		c := m.DataRows[i]
 		bv := c.Get(5) * (c.Close() - c.Low()) / (c.High() - c.Low())
 		bvp := bv / c.Get(5) * 100.0
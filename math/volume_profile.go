@@ -0,0 +1,243 @@
+package math
+
+import (
+	"math"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Volume profile
+//
+// -----------------------------------------------------------------------
+type VolumeProfileConfig struct {
+	// BinSize is the price range of one bin. If it is 0 the range is split into Bins bins.
+	BinSize float64
+	Bins    int
+	// TickSize rounds the bins to the tick size of the instrument (0 = no rounding)
+	TickSize float64
+	// ValueArea is the part of the total volume around the POC (0.7 = 70%)
+	ValueArea float64
+}
+
+func DefaultVolumeProfileConfig() VolumeProfileConfig {
+	return VolumeProfileConfig{
+		Bins:      50,
+		ValueArea: 0.7,
+	}
+}
+
+type VolumeBin struct {
+	Low    float64
+	High   float64
+	Volume float64
+}
+
+func (vb VolumeBin) Mid() float64 {
+	return (vb.Low + vb.High) / 2.0
+}
+
+type VolumeProfile struct {
+	// rows from Start up to (not including) End
+	Start   int
+	End     int
+	Session string
+	Bins    []VolumeBin
+	Total   float64
+	// POC - point of control is the mid of the bin with the highest volume
+	POC      float64
+	POCIndex int
+	// value area high and low
+	VAH float64
+	VAL float64
+	// high and low volume nodes (mid of the bins)
+	HVN []float64
+	LVN []float64
+}
+
+// BuildVolumeProfile distributes the volume of every candle between start and
+// end evenly over the price range of the candle
+func BuildVolumeProfile(m *Matrix, start, end int, cfg VolumeProfileConfig) *VolumeProfile {
+	if end > m.CandleRows() {
+		end = m.CandleRows()
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= end {
+		return nil
+	}
+	ret := &VolumeProfile{
+		Start:    start,
+		End:      end,
+		POCIndex: -1,
+	}
+	hi, lo := m.FindHighestHighLowestLow(start, end-start)
+	binSize := cfg.BinSize
+	if cfg.TickSize > 0.0 {
+		lo = FloorNearest(lo, cfg.TickSize)
+		hi = CeilNearest(hi, cfg.TickSize)
+	}
+	if binSize <= 0.0 {
+		bins := cfg.Bins
+		if bins <= 0 {
+			bins = 50
+		}
+		binSize = (hi - lo) / float64(bins)
+	}
+	if cfg.TickSize > 0.0 {
+		binSize = math.Max(RoundNearest(binSize, cfg.TickSize), cfg.TickSize)
+	}
+	if binSize <= 0.0 {
+		binSize = 1.0
+	}
+	cnt := int(math.Ceil((hi - lo) / binSize))
+	if cnt < 1 {
+		cnt = 1
+	}
+	ret.Bins = make([]VolumeBin, cnt)
+	for i := range ret.Bins {
+		ret.Bins[i].Low = lo + float64(i)*binSize
+		ret.Bins[i].High = lo + float64(i+1)*binSize
+	}
+	binOf := func(price float64) int {
+		idx := int((price - lo) / binSize)
+		if idx >= cnt {
+			idx = cnt - 1
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		return idx
+	}
+	for i := start; i < end; i++ {
+		p := &m.DataRows[i]
+		v := p.Get(VOLUME)
+		ret.Total += v
+		rng := p.High() - p.Low()
+		if rng <= 0.0 {
+			ret.Bins[binOf(p.Close())].Volume += v
+			continue
+		}
+		for j := binOf(p.Low()); j <= binOf(p.High()); j++ {
+			b := &ret.Bins[j]
+			overlap := math.Min(b.High, p.High()) - math.Max(b.Low, p.Low())
+			if overlap > 0.0 {
+				b.Volume += v * overlap / rng
+			}
+		}
+	}
+	ret.findPOC()
+	ret.findValueArea(cfg.ValueArea)
+	ret.findNodes()
+	return ret
+}
+
+func (vp *VolumeProfile) findPOC() {
+	max := -1.0
+	for i, b := range vp.Bins {
+		if b.Volume > max {
+			max = b.Volume
+			vp.POCIndex = i
+		}
+	}
+	vp.POC = vp.Bins[vp.POCIndex].Mid()
+}
+
+// findValueArea starts at the POC and adds the bigger neighbour bin until the
+// value area contains the requested part of the volume
+func (vp *VolumeProfile) findValueArea(part float64) {
+	if part <= 0.0 || part > 1.0 {
+		part = 0.7
+	}
+	lower := vp.POCIndex
+	upper := vp.POCIndex
+	sum := vp.Bins[vp.POCIndex].Volume
+	for sum < vp.Total*part && (lower > 0 || upper < len(vp.Bins)-1) {
+		up := -1.0
+		down := -1.0
+		if upper < len(vp.Bins)-1 {
+			up = vp.Bins[upper+1].Volume
+		}
+		if lower > 0 {
+			down = vp.Bins[lower-1].Volume
+		}
+		if up >= down {
+			upper++
+			sum += up
+		} else {
+			lower--
+			sum += down
+		}
+	}
+	vp.VAH = vp.Bins[upper].High
+	vp.VAL = vp.Bins[lower].Low
+}
+
+// findNodes marks local maxima above the average bin volume as HVN and local
+// minima below the average as LVN
+func (vp *VolumeProfile) findNodes() {
+	vp.HVN = make([]float64, 0)
+	vp.LVN = make([]float64, 0)
+	n := len(vp.Bins)
+	if n < 3 {
+		return
+	}
+	avg := vp.Total / float64(n)
+	for i := 1; i < n-1; i++ {
+		v := vp.Bins[i].Volume
+		if v > vp.Bins[i-1].Volume && v >= vp.Bins[i+1].Volume && v > avg {
+			vp.HVN = append(vp.HVN, vp.Bins[i].Mid())
+		}
+		if v < vp.Bins[i-1].Volume && v <= vp.Bins[i+1].Volume && v < avg {
+			vp.LVN = append(vp.LVN, vp.Bins[i].Mid())
+		}
+	}
+}
+
+// InValueArea checks if the price is between VAL and VAH
+func (vp *VolumeProfile) InValueArea(price float64) bool {
+	return price >= vp.VAL && price <= vp.VAH
+}
+
+// SessionVolumeProfiles builds one profile for every session
+func SessionVolumeProfiles(m *Matrix, s Session, cfg VolumeProfileConfig) []*VolumeProfile {
+	ret := make([]*VolumeProfile, 0)
+	ids := SessionIndices(m, s)
+	start := -1
+	for i := 0; i <= m.CandleRows(); i++ {
+		if i < m.CandleRows() && start != -1 && ids[i] == ids[start] {
+			continue
+		}
+		if start != -1 {
+			vp := BuildVolumeProfile(m, start, i, cfg)
+			vp.Session = s.SessionDate(m.DataRows[start].Key)
+			ret = append(ret, vp)
+			start = -1
+		}
+		if i < m.CandleRows() && ids[i] != -1 {
+			start = i
+		}
+	}
+	return ret
+}
+
+// DistanceToPOC adds the distance of the close to the POC in percent. Every
+// profile is used from its first row up to the first row of the next profile.
+func DistanceToPOC(m *Matrix, profiles []*VolumeProfile) int {
+	// 0 = distance to POC in percent
+	ret := m.AddNamedColumn("POCDist")
+	for i, vp := range profiles {
+		if vp == nil || vp.POC == 0.0 {
+			continue
+		}
+		end := m.CandleRows()
+		if i+1 < len(profiles) && profiles[i+1] != nil {
+			end = profiles[i+1].Start
+		}
+		for j := vp.Start; j < end; j++ {
+			p := &m.DataRows[j]
+			p.Set(ret, (p.Close()-vp.POC)/vp.POC*100.0)
+		}
+	}
+	return ret
+}
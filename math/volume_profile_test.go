@@ -0,0 +1,31 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestVolumeProfile(t *testing.T) {
+	mat := NewCandleMatrix()
+	add := func(key string, h, l, c, v float64) {
+		mat.AddRow(key).Set(OPEN, c).Set(HIGH, h).Set(LOW, l).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, v)
+	}
+	add("2024-01-02 00:00", 11, 10, 10.5, 100)
+	add("2024-01-03 00:00", 12, 11, 11.5, 400)
+	add("2024-01-04 00:00", 13, 12, 12.5, 100)
+	add("2024-01-05 00:00", 14, 13, 13.5, 50)
+	add("2024-01-08 00:00", 15, 14, 14.5, 300)
+	cfg := DefaultVolumeProfileConfig()
+	cfg.BinSize = 1.0
+	vp := BuildVolumeProfile(mat, 0, mat.Rows, cfg)
+	assert.Equal(t, 5, len(vp.Bins))
+	assert.Equal(t, 950.0, vp.Total)
+	assert.Equal(t, 11.5, vp.POC)
+	assert.Equal(t, 10.0, vp.VAL)
+	assert.Equal(t, 15.0, vp.VAH)
+	assert.Equal(t, []float64{11.5}, vp.HVN)
+	assert.Equal(t, []float64{13.5}, vp.LVN)
+	dist := DistanceToPOC(mat, []*VolumeProfile{vp})
+	assert.Equal(t, 0.0, mat.DataRows[1].Get(dist))
+}
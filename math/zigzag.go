@@ -0,0 +1,195 @@
+package math
+
+// -----------------------------------------------------------------------
+//
+//	ZigZag
+//
+// -----------------------------------------------------------------------
+type ZigZagMode int
+
+const (
+	// the threshold is a percentage of the last pivot
+	ZIGZAG_PERCENT ZigZagMode = iota
+	// the threshold is a price difference
+	ZIGZAG_ABSOLUTE
+	// the threshold is a multiple of the ATR
+	ZIGZAG_ATR
+)
+
+type ZigZagConfig struct {
+	Mode      ZigZagMode
+	Threshold float64
+	ATRPeriod int
+}
+
+func NewZigZagConfig(mode ZigZagMode, threshold float64) ZigZagConfig {
+	return ZigZagConfig{
+		Mode:      mode,
+		Threshold: threshold,
+		ATRPeriod: 14,
+	}
+}
+
+// FindZigZag returns the pivots where the price reversed by more than the
+// threshold. A pivot is confirmed on the row where the reversal crosses the
+// threshold. The last pivot is the extreme of the current leg and is marked
+// as unconfirmed.
+func (m *Matrix) FindZigZag(cfg ZigZagConfig) SwingPoints {
+	var ret SwingPoints
+	total := m.CandleRows()
+	if total == 0 {
+		return ret
+	}
+	atr := -1
+	if cfg.Mode == ZIGZAG_ATR {
+		atr = ATR(m, cfg.ATRPeriod)
+		defer m.RemoveColumn()
+	}
+	threshold := func(i int, ref float64) float64 {
+		switch cfg.Mode {
+		case ZIGZAG_ABSOLUTE:
+			return cfg.Threshold
+		case ZIGZAG_ATR:
+			return m.DataRows[i].Get(atr) * cfg.Threshold
+		}
+		return ref * cfg.Threshold / 100.0
+	}
+	pivot := func(idx int, bt SwingPointType, value float64, confirmed int) {
+		ret = append(ret, SwingPoint{
+			Timestamp:      m.DataRows[idx].Key,
+			BaseType:       bt,
+			Type:           bt,
+			Value:          value,
+			Price:          m.DataRows[idx].Close(),
+			Index:          idx,
+			ConfirmedIndex: confirmed,
+		})
+	}
+	dir := 0
+	hi, lo := m.DataRows[0].High(), m.DataRows[0].Low()
+	hiIdx, loIdx := 0, 0
+	for i := 1; i < total; i++ {
+		c := &m.DataRows[i]
+		switch dir {
+		case 0:
+			if c.High() > hi {
+				hi, hiIdx = c.High(), i
+			}
+			if c.Low() < lo {
+				lo, loIdx = c.Low(), i
+			}
+			if hiIdx > loIdx && hi-lo >= threshold(i, lo) {
+				pivot(loIdx, Low, lo, i)
+				dir = 1
+			} else if loIdx > hiIdx && hi-lo >= threshold(i, hi) {
+				pivot(hiIdx, High, hi, i)
+				dir = -1
+			}
+		case 1:
+			if c.High() > hi {
+				hi, hiIdx = c.High(), i
+			} else if hi-c.Low() >= threshold(i, hi) {
+				pivot(hiIdx, High, hi, i)
+				dir = -1
+				lo, loIdx = c.Low(), i
+			}
+		case -1:
+			if c.Low() < lo {
+				lo, loIdx = c.Low(), i
+			} else if c.High()-lo >= threshold(i, lo) {
+				pivot(loIdx, Low, lo, i)
+				dir = 1
+				hi, hiIdx = c.High(), i
+			}
+		}
+	}
+	if dir == 1 {
+		pivot(hiIdx, High, hi, -1)
+		ret[len(ret)-1].Unconfirmed = true
+	} else if dir == -1 {
+		pivot(loIdx, Low, lo, -1)
+		ret[len(ret)-1].Unconfirmed = true
+	}
+	labelSwingPoints(ret)
+	for i := range ret {
+		c := &ret[i]
+		for j := c.Index + 1; j < total; j++ {
+			if (c.BaseType == High && m.DataRows[j].High() > c.Value) || (c.BaseType == Low && m.DataRows[j].Low() < c.Value) {
+				c.Broken = true
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// labelSwingPoints sets the HH/LH/HL/LL type, the trend and the delta
+// compared to the previous pivot of the same base type
+func labelSwingPoints(sps SwingPoints) {
+	var lastHigh, lastLow *SwingPoint
+	for i := range sps {
+		c := &sps[i]
+		if i > 0 {
+			c.Delta = c.Value - sps[i-1].Value
+		}
+		if c.BaseType == High {
+			if lastHigh != nil {
+				if c.Value > lastHigh.Value {
+					c.Type = HigherHigh
+					c.Trend = 1
+				} else {
+					c.Type = LowerHigh
+					c.Trend = -1
+				}
+			}
+			lastHigh = c
+		} else {
+			if lastLow != nil {
+				if c.Value < lastLow.Value {
+					c.Type = LowerLow
+					c.Trend = -1
+				} else {
+					c.Type = HigherLow
+					c.Trend = 1
+				}
+			}
+			lastLow = c
+		}
+	}
+}
+
+// ZigZag draws the lines between the pivots of FindZigZag. The rows after the
+// last confirmed pivot can still change with the next candles.
+func ZigZag(m *Matrix, cfg ZigZagConfig) int {
+	// 0 = ZigZag line 1 = Type of the pivot (SwingPointType) 2 = confirmed (type of the pivot confirmed on this row)
+	// 3 = repaint (1 = after the last confirmed pivot)
+	ret := m.AddNamedColumn("ZigZag")
+	typeIdx := m.AddNamedColumn("ZZType")
+	confIdx := m.AddNamedColumn("ZZConfirmed")
+	repaintIdx := m.AddNamedColumn("ZZRepaint")
+	sps := m.FindZigZag(cfg)
+	lastConfirmed := -1
+	for i, sp := range sps {
+		r := &m.DataRows[sp.Index]
+		r.Set(ret, sp.Value)
+		r.Set(typeIdx, float64(sp.Type))
+		if !sp.Unconfirmed {
+			m.DataRows[sp.ConfirmedIndex].Set(confIdx, float64(sp.BaseType))
+			lastConfirmed = sp.Index
+		}
+		if i > 0 {
+			prev := sps[i-1]
+			steps := float64(sp.Index - prev.Index)
+			for j := prev.Index + 1; j < sp.Index; j++ {
+				f := float64(j-prev.Index) / steps
+				m.DataRows[j].Set(ret, prev.Value+(sp.Value-prev.Value)*f)
+			}
+		}
+	}
+	if len(sps) > 0 {
+		for i := lastConfirmed + 1; i < m.CandleRows(); i++ {
+			m.DataRows[i].Set(repaintIdx, 1.0)
+		}
+	}
+	return ret
+}
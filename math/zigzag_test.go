@@ -0,0 +1,38 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestZigZag(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closes := []float64{100, 102, 105, 110, 108, 104, 101, 103, 107, 112, 115, 111, 106, 105, 108}
+	for i, c := range closes {
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(HIGH, c).Set(LOW, c).Set(CLOSE, c).Set(ADJ_CLOSE, c)
+	}
+	sps := mat.FindZigZag(NewZigZagConfig(ZIGZAG_PERCENT, 5.0))
+	assert.Equal(t, 5, len(sps))
+	assert.Equal(t, []int{0, 3, 6, 10, 13}, []int{sps[0].Index, sps[1].Index, sps[2].Index, sps[3].Index, sps[4].Index})
+	assert.Equal(t, HigherLow, sps[2].Type)
+	assert.Equal(t, HigherHigh, sps[3].Type)
+	assert.True(t, sps[4].Unconfirmed)
+	assert.False(t, sps[3].Unconfirmed)
+	// a bigger threshold removes the small legs
+	assert.Equal(t, 2, len(mat.FindZigZag(NewZigZagConfig(ZIGZAG_ABSOLUTE, 12.0))))
+	zz := ZigZag(mat, NewZigZagConfig(ZIGZAG_PERCENT, 5.0))
+	assert.Equal(t, 110.0, mat.DataRows[3].Get(zz))
+	assert.Equal(t, 108.0, mat.DataRows[8].Get(zz))
+	// the pivots are confirmed when the reversal crosses the threshold
+	assert.Equal(t, []int{2, 5, 8, 12, -1}, []int{sps[0].ConfirmedIndex, sps[1].ConfirmedIndex, sps[2].ConfirmedIndex, sps[3].ConfirmedIndex, sps[4].ConfirmedIndex})
+	assert.Equal(t, 0.0, mat.DataRows[10].Get(zz+2))
+	assert.Equal(t, 1.0, mat.DataRows[12].Get(zz+2))
+	assert.Equal(t, -1.0, mat.DataRows[8].Get(zz+2))
+	// the leg after the last confirmed pivot can repaint
+	for i := 0; i < mat.Rows; i++ {
+		assert.Equal(t, i > 10, mat.DataRows[i].Get(zz+3) == 1.0, "row %d", i)
+	}
+}
@@ -0,0 +1,268 @@
+package math
+
+import (
+	"math"
+	"sort"
+)
+
+// -----------------------------------------------------------------------
+//
+//	Support and resistance zones
+//
+// -----------------------------------------------------------------------
+type ZoneSource int
+
+const (
+	ZONE_SWING ZoneSource = 1 << iota
+	ZONE_GAP
+	ZONE_INSIDE_BAR
+	ZONE_FIBONACCI
+	ZONE_SUPPORT_RESISTANCE
+	ZONE_KMEANS
+)
+
+const (
+	ZONE_SUPPORT    = 1
+	ZONE_RESISTANCE = -1
+)
+
+type ZoneConfig struct {
+	Sources ZoneSource
+	// Width is the half width of a zone around a level in multiples of the ATR
+	Width     float64
+	ATRPeriod int
+	// thresholds of FindMajorLevels and FindMajorGaps
+	MajorThreshold float64
+	GapThreshold   float64
+	// lookback of FindFibonacciLevels and FindSupportResistance
+	FibonacciLookback int
+	SRLookback        int
+	// number of clusters and iterations of KMeans. ZONE_KMEANS is not part of
+	// the default sources.
+	Clusters   int
+	Iterations int
+}
+
+func DefaultZoneConfig() ZoneConfig {
+	return ZoneConfig{
+		Sources:           ZONE_SWING | ZONE_GAP | ZONE_INSIDE_BAR | ZONE_FIBONACCI | ZONE_SUPPORT_RESISTANCE,
+		Width:             0.25,
+		ATRPeriod:         14,
+		MajorThreshold:    1.0,
+		GapThreshold:      2.0,
+		FibonacciLookback: 100,
+		SRLookback:        20,
+		Clusters:          5,
+		Iterations:        20,
+	}
+}
+
+type Zone struct {
+	Upper   float64
+	Lower   float64
+	Sources ZoneSource
+	// Touches counts how often the price entered the zone
+	Touches        int
+	LastTouch      string
+	LastTouchIndex int
+	Strength       float64
+	// Type is ZONE_SUPPORT if the last close is above the zone and ZONE_RESISTANCE if below
+	Type int
+	// Broken is set when a close crossed the zone. Flipped is set when the
+	// broken zone was retested from the other side and held.
+	Broken  bool
+	Flipped bool
+}
+
+func (z Zone) Mid() float64 {
+	return (z.Upper + z.Lower) / 2.0
+}
+
+func (z Zone) Contains(price float64) bool {
+	return price >= z.Lower && price <= z.Upper
+}
+
+// Distance returns 0 if the price is inside the zone otherwise the distance
+// to the nearest bound
+func (z Zone) Distance(price float64) float64 {
+	if price > z.Upper {
+		return price - z.Upper
+	}
+	if price < z.Lower {
+		return z.Lower - price
+	}
+	return 0.0
+}
+
+func (z Zone) SourceCount() int {
+	cnt := 0
+	for s := ZONE_SWING; s <= ZONE_KMEANS; s <<= 1 {
+		if z.Sources&s != 0 {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// collectZones converts the levels of all enabled sources into raw zones
+func collectZones(prices *Matrix, cfg ZoneConfig, width float64) ([]Zone, error) {
+	ret := make([]Zone, 0)
+	addLevel := func(v float64, src ZoneSource) {
+		if v > 0.0 {
+			ret = append(ret, Zone{Upper: v + width, Lower: v - width, Sources: src})
+		}
+	}
+	addLevels := func(ml *MajorLevels, src ZoneSource) {
+		for _, l := range ml.Levels {
+			addLevel(l.Value, src)
+		}
+	}
+	if cfg.Sources&ZONE_SWING != 0 {
+		addLevels(FindMajorLevels(prices, cfg.MajorThreshold), ZONE_SWING)
+	}
+	if cfg.Sources&ZONE_GAP != 0 {
+		addLevels(FindMajorGaps(prices, cfg.GapThreshold), ZONE_GAP)
+	}
+	if cfg.Sources&ZONE_INSIDE_BAR != 0 {
+		addLevels(FindInsideBarMajorLevels(prices, cfg.MajorThreshold), ZONE_INSIDE_BAR)
+	}
+	if cfg.Sources&ZONE_FIBONACCI != 0 && prices.Rows >= cfg.FibonacciLookback {
+		addLevels(FindFibonacciLevels(prices, cfg.FibonacciLookback), ZONE_FIBONACCI)
+	}
+	if cfg.Sources&ZONE_SUPPORT_RESISTANCE != 0 {
+		for _, v := range prices.FindSupportResistance(cfg.SRLookback, 0.0) {
+			addLevel(v, ZONE_SUPPORT_RESISTANCE)
+		}
+	}
+	if cfg.Sources&ZONE_KMEANS != 0 && cfg.Clusters > 0 && prices.Rows > 0 {
+		centroids, _, err := KMeans(prices, cfg.Clusters, cfg.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range centroids {
+			addLevel(c, ZONE_KMEANS)
+		}
+	}
+	return ret, nil
+}
+
+// mergeZones joins overlapping zones
+func mergeZones(zones []Zone) []Zone {
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Lower < zones[j].Lower
+	})
+	ret := make([]Zone, 0)
+	for _, z := range zones {
+		if len(ret) > 0 && z.Lower <= ret[len(ret)-1].Upper {
+			last := &ret[len(ret)-1]
+			last.Upper = math.Max(last.Upper, z.Upper)
+			last.Sources |= z.Sources
+			continue
+		}
+		ret = append(ret, z)
+	}
+	return ret
+}
+
+// evaluate walks through the candles and updates touches and the state
+func (z *Zone) evaluate(prices *Matrix) {
+	z.LastTouchIndex = -1
+	inside := false
+	side := 0
+	for i := 0; i < prices.CandleRows(); i++ {
+		c := &prices.DataRows[i]
+		touching := c.Low() <= z.Upper && c.High() >= z.Lower
+		if touching && !inside {
+			z.Touches++
+			z.LastTouch = c.Key
+			z.LastTouchIndex = i
+			// a retest of a broken zone which holds on the new side
+			if z.Broken && side != 0 && z.closeSide(c.Close()) == side {
+				z.Flipped = true
+			}
+		}
+		inside = touching
+		cs := z.closeSide(c.Close())
+		if cs != 0 {
+			if side != 0 && cs != side {
+				z.Broken = true
+				z.Flipped = false
+			}
+			side = cs
+		}
+	}
+	z.Type = side
+}
+
+// closeSide returns 1 if the price is above, -1 if below and 0 if inside
+func (z *Zone) closeSide(price float64) int {
+	if price > z.Upper {
+		return ZONE_SUPPORT
+	}
+	if price < z.Lower {
+		return ZONE_RESISTANCE
+	}
+	return 0
+}
+
+// FindZones merges the levels of the different level finders into zones. The
+// strength combines the touches, the number of sources confirming the zone
+// and how recent the last touch was.
+func FindZones(prices *Matrix, cfg ZoneConfig) ([]Zone, error) {
+	if prices.CandleRows() < 2 {
+		return make([]Zone, 0), nil
+	}
+	ai := ATR(prices, cfg.ATRPeriod)
+	width := prices.DataRows[prices.CandleRows()-1].Get(ai) * cfg.Width
+	prices.RemoveColumn()
+	zones, err := collectZones(prices, cfg, width)
+	if err != nil {
+		return nil, err
+	}
+	ret := mergeZones(zones)
+	last := prices.CandleRows() - 1
+	for i := range ret {
+		z := &ret[i]
+		z.evaluate(prices)
+		recency := 0.0
+		if z.LastTouchIndex != -1 {
+			recency = 1.0 / (1.0 + float64(last-z.LastTouchIndex)/100.0)
+		}
+		z.Strength = (float64(z.Touches) + 2.0*float64(z.SourceCount())) * recency
+	}
+	return ret, nil
+}
+
+// NearestZone returns the index of the zone closest to the price or -1
+func NearestZone(zones []Zone, price float64) int {
+	ret := -1
+	min := math.MaxFloat64
+	for i, z := range zones {
+		if d := z.Distance(price); d < min {
+			min = d
+			ret = i
+		}
+	}
+	return ret
+}
+
+// DistanceToZone adds the distance of the close to the nearest zone in percent.
+// Positive values are above the zone, negative below and 0 inside.
+func DistanceToZone(prices *Matrix, zones []Zone) int {
+	// 0 = distance in percent
+	ret := prices.AddNamedColumn("ZoneDist")
+	for i := 0; i < prices.CandleRows(); i++ {
+		c := &prices.DataRows[i]
+		idx := NearestZone(zones, c.Close())
+		if idx == -1 || c.Close() == 0.0 {
+			continue
+		}
+		z := zones[idx]
+		d := z.Distance(c.Close()) / c.Close() * 100.0
+		if c.Close() < z.Lower {
+			d = -d
+		}
+		c.Set(ret, d)
+	}
+	return ret
+}
@@ -0,0 +1,57 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestFindZones(t *testing.T) {
+	mat := NewCandleMatrix()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// range between 100 and 110 then a break above 110 and a retest
+	closes := []float64{100, 103, 107, 110, 106, 102, 100, 104, 108, 110, 107, 103, 100, 105, 109, 114, 118, 121, 116, 112, 111, 115, 119, 123, 126}
+	open := closes[0]
+	for i, c := range closes {
+		mat.AddRow(day.AddDate(0, 0, i).Format(KEY_FORMAT)).Set(OPEN, open).Set(HIGH, max(open, c)+1).Set(LOW, min(open, c)-1).Set(CLOSE, c).Set(ADJ_CLOSE, c).Set(VOLUME, 100)
+		open = c
+	}
+	cols := mat.Cols
+	cfg := DefaultZoneConfig()
+	cfg.FibonacciLookback = 20
+	zones, err := FindZones(mat, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, cols, mat.Cols)
+	idx := NearestZone(zones, 110.0)
+	assert.NotEqual(t, -1, idx)
+	z := zones[idx]
+	assert.True(t, z.Contains(110.0))
+	assert.True(t, z.Broken)
+	assert.True(t, z.Flipped)
+	assert.Equal(t, ZONE_SUPPORT, z.Type)
+	dist := DistanceToZone(mat, zones)
+	assert.True(t, mat.DataRows[mat.Rows-1].Get(dist) >= 0.0)
+	FindMajorGaps(mat, 2.0)
+	assert.Equal(t, cols+1, mat.Cols)
+	// more clusters than rows
+	cfg.Sources |= ZONE_KMEANS
+	cfg.Clusters = 30
+	_, err = FindZones(mat, cfg)
+	assert.Equal(t, ErrNotEnoughRows, err)
+}
+
+func TestFindZonesGap(t *testing.T) {
+	closes := []float64{100, 100, 100, 100, 100, 100, 100, 100, 104, 104, 104, 104, 104, 104, 104, 104}
+	mat := candleMatrix(closeBars(closes, 0.5))
+	cfg := DefaultZoneConfig()
+	cfg.Sources = ZONE_GAP
+	zones, err := FindZones(mat, cfg)
+	assert.NoError(t, err)
+	// the previous close and the open of the gap are the levels
+	assert.Equal(t, 2, len(zones))
+	for i, price := range []float64{100.0, 104.0} {
+		assert.True(t, zones[i].Contains(price))
+		assert.Equal(t, ZONE_GAP, zones[i].Sources)
+	}
+}